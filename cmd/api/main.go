@@ -27,7 +27,7 @@ func main() {
 	serverAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 
 	// Create server
-	server := http.NewServer(serverAddr, cfg.Data.Dir)
+	server := http.NewServer(serverAddr, cfg)
 
 	// Start server in a goroutine
 	go func() {
@@ -0,0 +1,25 @@
+// Command renderstub serves prepared pages over the rendering service
+// protocol, standing in for a browser-backed renderer in local runs and CI
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/tedjuang/go-scrapy/internal/fetcher"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9222", "Address to listen on")
+	dir := flag.String("dir", "./test/render", "Directory of rendered pages, as <host>/<path>/index.html")
+	flag.Parse()
+
+	mux := http.NewServeMux()
+	mux.Handle("/render", &fetcher.StubRenderer{Dir: *dir})
+
+	log.Printf("Stub renderer serving %s on http://%s/render", *dir, *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		log.Fatalf("Stub renderer failed: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/scraper"
+	"github.com/tedjuang/go-scrapy/internal/storage"
+)
+
+// backfill implements the backfill subcommand: re-run extraction over the
+// archived snapshots of stored products and fill fields that are empty
+func backfill(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	id := fs.String("id", "", "ID of the product to backfill (all products if empty)")
+	configFile := fs.String("config", "", "Config file to take the archive settings from (e.g., configs/dev/config.json)")
+	dataDir := fs.String("data", "./data", "Directory to store data")
+	retention := fs.Int("retention", 0, "Prune snapshots not seen for this many days first (0 keeps all)")
+	fs.Parse(args)
+
+	cfg := loadConfig(*configFile)
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "data":
+			cfg.Data.Dir = *dataDir
+		case "retention":
+			cfg.Scraping.Archive.RetentionDays = *retention
+		}
+	})
+	if cfg.Data.Dir == "" {
+		cfg.Data.Dir = *dataDir
+	}
+	cfg.Scraping.Archive.Enabled = true
+
+	store, err := storage.NewJSONFileStorage(filepath.Join(cfg.Data.Dir, "products.json"))
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	factory := scraper.NewScraperFactoryWithConfig(cfg)
+	defer factory.Close()
+	archive := factory.Archive()
+	if archive == nil {
+		log.Fatalf("Failed to open the snapshot archive")
+	}
+
+	var products []*models.Product
+	if *id != "" {
+		product, err := store.GetByID(*id)
+		if err != nil {
+			log.Fatalf("Failed to get product: %v", err)
+		}
+		if product == nil {
+			log.Fatalf("Product not found: %s", *id)
+		}
+		products = append(products, product)
+	} else if products, err = store.GetAll(); err != nil {
+		log.Fatalf("Failed to get products: %v", err)
+	}
+
+	updated := 0
+	for _, p := range products {
+		s, _ := factory.GetScraper(p.Website)
+		extractor, ok := s.(scraper.Extractor)
+		if !ok {
+			log.Printf("Warning: Website %s can't re-extract snapshots, skipping %s", p.Website, p.ID)
+			continue
+		}
+
+		result, err := scraper.Backfill(extractor, archive, p)
+		if err != nil {
+			log.Printf("Warning: Failed to backfill %s: %v", p.ID, err)
+			continue
+		}
+		if result.Missing > 0 {
+			log.Printf("Warning: %d snapshots of %s are no longer archived", result.Missing, p.ID)
+		}
+		if len(result.Filled) == 0 {
+			continue
+		}
+
+		if err := store.Save(p); err != nil {
+			log.Fatalf("Failed to save product: %v", err)
+		}
+		updated++
+		fmt.Printf("Backfilled %s from %d snapshots: %v\n", p.ID, result.Snapshots, result.Filled)
+	}
+
+	fmt.Printf("\nBackfilled %d of %d products\n", updated, len(products))
+}
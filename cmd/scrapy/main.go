@@ -1,47 +1,112 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/tedjuang/go-scrapy/internal/block"
+	"github.com/tedjuang/go-scrapy/internal/config"
+	"github.com/tedjuang/go-scrapy/internal/currency"
+	"github.com/tedjuang/go-scrapy/internal/health"
+	"github.com/tedjuang/go-scrapy/internal/media"
 	"github.com/tedjuang/go-scrapy/internal/models"
 	"github.com/tedjuang/go-scrapy/internal/scraper"
 	"github.com/tedjuang/go-scrapy/internal/storage"
 )
 
 func main() {
+	// Subcommands take their own flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "crawl-shop":
+			crawlShop(os.Args[2:])
+			return
+		case "ranking":
+			scrapeRanking(os.Args[2:])
+			return
+		case "sitemap":
+			discoverSitemap(os.Args[2:])
+			return
+		case "backfill":
+			backfill(os.Args[2:])
+			return
+		}
+	}
+
 	// Define command line flags
 	url := flag.String("url", "", "URL of the product to track")
 	search := flag.String("search", "", "Search for products with this keyword")
 	website := flag.String("website", "rakuten", "Website to scrape (e.g., rakuten)")
+	variant := flag.String("variant", "", "SKU of the product variant to track (with -url)")
 	maxResults := flag.Int("max", 10, "Maximum number of search results")
-	dataDir := flag.String("data", "./data", "Directory to store data")
+	scrape := addScrapeFlags(flag.CommandLine, true)
+	targetCurrency := flag.String("currency", "", "Also display prices converted to this currency (e.g., USD)")
+	sortOrder := flag.String("sort", "", "Sort search results by price (price or -price)")
+	ratesFile := flag.String("rates", "", "Static exchange-rate table (default the config's, or configs/dev/exchange_rates.json next to the binary or in the working directory)")
+	ratesURL := flag.String("rates-url", "", "Exchange-rate service URL (overrides -rates)")
 
 	// Parse command line flags
 	flag.Parse()
+	cfg := scrape.config()
+	dataDir := cfg.Data.Dir
 
 	// Create data directory if it doesn't exist
-	if err := os.MkdirAll(*dataDir, 0755); err != nil {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		log.Fatalf("Failed to create data directory: %v", err)
 	}
 
 	// Create a storage instance
-	store, err := storage.NewJSONFileStorage(filepath.Join(*dataDir, "products.json"))
+	store, err := storage.NewJSONFileStorage(filepath.Join(dataDir, "products.json"))
 	if err != nil {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 
+	quarantine, err := storage.NewJSONQuarantineStorage(filepath.Join(dataDir, "quarantine.json"))
+	if err != nil {
+		log.Fatalf("Failed to initialize quarantine storage: %v", err)
+	}
+
+	if *sortOrder != "" && *sortOrder != "price" && *sortOrder != "-price" {
+		log.Fatalf("Invalid sort order: %s", *sortOrder)
+	}
+
+	// Create a currency converter; daily rate snapshots are kept with the data
+	var converter *currency.Converter
+	if *targetCurrency != "" {
+		if *ratesFile == "" && *ratesURL == "" {
+			*ratesFile, *ratesURL = cfg.Currency.RatesFile, cfg.Currency.RatesURL
+		}
+		if *ratesFile == "" {
+			*ratesFile = defaultRatesFile()
+		}
+		provider, err := currency.NewProvider(*ratesFile, *ratesURL)
+		if err != nil {
+			log.Fatalf("Failed to load exchange rates: %v", err)
+		}
+		rates, err := currency.NewSnapshotStore(filepath.Join(dataDir, "exchange_rates.json"))
+		if err != nil {
+			log.Fatalf("Failed to initialize exchange rate storage: %v", err)
+		}
+		converter = currency.NewConverter(provider, rates)
+	}
+
 	// Create a scraper factory
-	factory := scraper.NewScraperFactory()
+	factory := scraper.NewScraperFactoryWithConfig(cfg)
+	gate := newGate(cfg, store, quarantine)
+	defer factory.Close()
+	defer report(factory)
 
 	// Get the appropriate scraper
 	s, exists := factory.GetScraper(*website)
 	if !exists {
-		log.Fatalf("No scraper found for website: %s", *website)
+		fatalf(factory, "No scraper found for website: %s", *website)
 	}
 
 	// Process command based on flags
@@ -50,15 +115,25 @@ func main() {
 		fmt.Printf("Scraping product from URL: %s\n", *url)
 		product, err := s.ScrapeProduct(*url)
 		if err != nil {
-			log.Fatalf("Failed to scrape product: %v", err)
+			fatalf(factory, "Failed to scrape product: %v", err)
+		}
+
+		if *variant != "" {
+			if err := product.TrackVariant(*variant); err != nil {
+				fatalf(factory, "Failed to track variant: %v", err)
+			}
 		}
 
 		// Print product details
-		printProduct(product)
+		printProduct(product, converter, *targetCurrency)
 
-		// Save to storage
-		if err := store.Save(product); err != nil {
-			log.Fatalf("Failed to save product: %v", err)
+		// Save to storage, extending the stored history, unless quarantined
+		err = saveScraped(gate, health.PageProduct, product, factory.Images())
+		if errors.Is(err, health.ErrQuarantined) {
+			fatalf(factory, "Product not saved, %v (see quarantine.json)", err)
+		}
+		if err != nil {
+			fatalf(factory, "Failed to save product: %v", err)
 		}
 		fmt.Println("Product saved successfully!")
 	} else if *search != "" {
@@ -66,34 +141,64 @@ func main() {
 		fmt.Printf("Searching for '%s' on %s (max: %d results)\n", *search, *website, *maxResults)
 		products, err := s.ScrapeSearch(*search, *maxResults)
 		if err != nil {
-			log.Fatalf("Failed to search for products: %v", err)
+			fatalf(factory, "Failed to search for products: %v", err)
+		}
+
+		if *sortOrder != "" {
+			sortByPrice(products, converter, *targetCurrency, *sortOrder == "-price")
 		}
 
 		// Print search results
 		fmt.Printf("Found %d products:\n", len(products))
 		for i, p := range products {
 			fmt.Printf("\n--- Product %d ---\n", i+1)
-			printProduct(p)
+			printProduct(p, converter, *targetCurrency)
 
 			// Save to storage
-			if err := store.Save(p); err != nil {
+			if err := saveScraped(gate, health.PageListing, p, nil); err != nil {
 				log.Printf("Warning: Failed to save product %s: %v", p.ID, err)
 			}
 		}
-		fmt.Println("\nAll products saved successfully!")
+		fmt.Println("\nProducts saved!")
 	} else {
 		// If no URL or search provided, show usage information
 		flag.Usage()
+		factory.Close()
 		os.Exit(1)
 	}
 }
 
-// printProduct displays product information
-func printProduct(p *models.Product) {
+// printProduct displays product information, with the price converted to
+// target when a converter is given
+func printProduct(p *models.Product, converter *currency.Converter, target string) {
 	fmt.Printf("ID: %s\n", p.ID)
 	fmt.Printf("Name: %s\n", p.Name)
 	fmt.Printf("URL: %s\n", p.URL)
-	fmt.Printf("Price: %.2f %s\n", p.CurrentPrice, p.Currency)
+	fmt.Printf("Price: %s\n", p.CurrentPrice)
+	if p.Availability != models.AvailabilityUnknown {
+		fmt.Printf("Availability: %s\n", p.Availability)
+	}
+	if p.ShippingFee != nil {
+		fmt.Printf("Shipping: %s\n", p.ShippingFee)
+	}
+	if p.Points > 0 {
+		fmt.Printf("Points: %d\n", p.Points)
+	}
+	fmt.Printf("Effective Price: %s\n", p.EffectivePrice())
+	for _, v := range p.Variants {
+		tracked := ""
+		if v.SKU == p.TrackedVariant {
+			tracked = " (tracked)"
+		}
+		fmt.Printf("Variant %s: %v %s %s%s\n", v.SKU, v.Attributes, v.Price, v.Availability, tracked)
+	}
+	if converter != nil && target != "" {
+		if converted, err := converter.ConvertProduct(p, target); err != nil {
+			fmt.Printf("Converted Price: unavailable (%v)\n", err)
+		} else {
+			fmt.Printf("Converted Price: %s\n", converted)
+		}
+	}
 	fmt.Printf("Image URL: %s\n", p.ImageURL)
 	if len(p.Description) > 100 {
 		fmt.Printf("Description: %s...\n", p.Description[:100])
@@ -102,3 +207,195 @@ func printProduct(p *models.Product) {
 	}
 	fmt.Printf("Last Updated: %s\n", p.LastUpdated.Format(time.RFC1123))
 }
+
+// scrapeFlags are the flags shared by the commands that scrape. They
+// apply on top of the config file given with -config, if any.
+type scrapeFlags struct {
+	fs         *flag.FlagSet
+	configFile *string
+	dataDir    *string
+	useCache   *bool
+	useArchive *bool
+	useSession *bool
+	useImages  *bool // nil for commands that don't download images
+	proxies    *string
+}
+
+// addScrapeFlags defines the shared flags on fs, with -images if images is
+// set
+func addScrapeFlags(fs *flag.FlagSet, images bool) *scrapeFlags {
+	f := &scrapeFlags{
+		fs:         fs,
+		configFile: fs.String("config", "", "Config file to take the scraping settings from (e.g., configs/dev/config.json)"),
+		dataDir:    fs.String("data", "./data", "Directory to store data"),
+		useCache:   fs.Bool("cache", false, "Cache fetched pages under the data directory and revalidate them"),
+		useArchive: fs.Bool("archive", false, "Archive the HTML of scraped product pages under the data directory"),
+		useSession: fs.Bool("session", false, "Keep cookies across runs under the data directory, warming up new sessions"),
+		proxies:    fs.String("proxies", "", "Comma-separated proxies to fetch through (e.g., http://10.0.0.1:3128,socks5://10.0.0.2:1080)"),
+	}
+	if images {
+		f.useImages = fs.Bool("images", false, "Download product images with thumbnails under the data directory")
+	}
+	return f
+}
+
+// config returns the configuration of the command: the config file if
+// given, with the flags set on the command line applied on top
+func (f *scrapeFlags) config() *config.Config {
+	cfg := loadConfig(*f.configFile)
+
+	set := make(map[string]bool)
+	f.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+
+	if set["data"] || cfg.Data.Dir == "" {
+		cfg.Data.Dir = *f.dataDir
+	}
+	if set["cache"] {
+		cfg.Scraping.Cache.Enabled = *f.useCache
+	}
+	if set["archive"] {
+		cfg.Scraping.Archive.Enabled = *f.useArchive
+	}
+	if set["session"] {
+		cfg.Scraping.Session.Enabled = *f.useSession
+	}
+	if set["images"] {
+		cfg.Scraping.Images.Enabled = *f.useImages
+	}
+	if set["proxies"] {
+		cfg.Scraping.Proxies.URLs = nil
+		if *f.proxies != "" {
+			cfg.Scraping.Proxies.URLs = strings.Split(*f.proxies, ",")
+		}
+	}
+	return cfg
+}
+
+// loadConfig loads the config file at path, or returns an empty
+// configuration if path is empty
+func loadConfig(path string) *config.Config {
+	if path == "" {
+		return &config.Config{}
+	}
+	cfg, err := config.LoadConfigFile(path)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	return cfg
+}
+
+// newGate validates scraped products with the rules of cfg before saving
+// them to store, holding suspicious ones in quarantine. Extraction health
+// isn't monitored: it lives in memory and would be lost with the run.
+func newGate(cfg *config.Config, store *storage.JSONFileStorage, quarantine *storage.JSONQuarantineStorage) *health.Gate {
+	return &health.Gate{
+		Store:      store,
+		Quarantine: quarantine,
+		Rules:      health.NewRules(cfg.Scraping.Validation.MaxPriceRatio),
+	}
+}
+
+// report lists the URLs the crawl policy refused to fetch, the cache
+// counters, the circuits paused by block pages and the proxy counters
+func report(factory *scraper.ScraperFactory) {
+	if skipped := factory.Policy().Skipped(); len(skipped) > 0 {
+		fmt.Printf("\nSkipped %d URLs due to crawl policy:\n", len(skipped))
+		for _, s := range skipped {
+			fmt.Printf("  %s (%s)\n", s.URL, s.Reason)
+		}
+	}
+
+	if cache := factory.Cache(); cache != nil {
+		stats := cache.Stats()
+		fmt.Printf("\nCache: %d hits, %d revalidated, %d misses\n", stats.Hits, stats.Revalidations, stats.Misses)
+	}
+
+	for _, c := range factory.Breaker().States() {
+		if c.State != block.StateClosed {
+			fmt.Printf("\nCircuit %s is %s until %s after %d block pages (last: %s)\n",
+				c.Domain, c.State, c.OpenUntil.Format(time.RFC1123), c.Blocks, c.LastReason)
+		}
+	}
+
+	if pool := factory.Proxies(); pool != nil {
+		fmt.Println("\nProxies:")
+		for _, s := range pool.Stats() {
+			fmt.Printf("  %s: %d requests, %d failures, %d blocks, healthy: %t\n", s.URL, s.Requests, s.Failures, s.Blocks, s.Healthy)
+		}
+	}
+}
+
+// fatalf reports on the crawl and closes the factory before exiting like
+// log.Fatalf, which skips deferred calls. The report explains failures such
+// as URLs refused by robots.txt or domains paused by block pages.
+func fatalf(factory *scraper.ScraperFactory, format string, args ...any) {
+	report(factory)
+	factory.Close()
+	log.Fatalf(format, args...)
+}
+
+// saveScraped saves a product scraped from a page of the given type through
+// gate, returning an error wrapping health.ErrQuarantined if it was held in
+// quarantine. The product's image is downloaded first if images is set,
+// which only product pages should do.
+func saveScraped(gate *health.Gate, page string, product *models.Product, images *media.Downloader) error {
+	var prepare func(*models.Product)
+	if images != nil {
+		prepare = func(p *models.Product) {
+			if err := images.Download(p); err != nil {
+				log.Printf("Warning: Failed to download image of %s: %v", p.ID, err)
+			}
+		}
+	}
+
+	issues, err := gate.Save(page, product, prepare)
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		return health.QuarantineError(issues)
+	}
+	return nil
+}
+
+// defaultRatesFile returns the rate table of the dev config next to the
+// binary, or else in the working directory
+func defaultRatesFile() string {
+	path := filepath.Join("configs", "dev", "exchange_rates.json")
+	if exe, err := os.Executable(); err == nil {
+		if beside := filepath.Join(filepath.Dir(exe), path); fileExists(beside) {
+			return beside
+		}
+	}
+	return path
+}
+
+// fileExists reports whether path names an existing file
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// sortByPrice orders products by price, converted to target when given so
+// that prices in different currencies compare correctly
+func sortByPrice(products []*models.Product, converter *currency.Converter, target string, desc bool) {
+	prices := make(map[string]models.Money, len(products))
+	for _, p := range products {
+		prices[p.ID] = p.CurrentPrice
+		if target != "" {
+			if converted, err := converter.ConvertProduct(p, target); err == nil {
+				prices[p.ID] = converted
+			} else {
+				log.Printf("Warning: Failed to convert price of %s: %v", p.ID, err)
+			}
+		}
+	}
+
+	sort.SliceStable(products, func(i, j int) bool {
+		a, b := prices[products[i].ID], prices[products[j].ID]
+		if desc {
+			a, b = b, a
+		}
+		return models.LessPrice(a, b)
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/tedjuang/go-scrapy/internal/health"
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/scraper"
+	"github.com/tedjuang/go-scrapy/internal/storage"
+)
+
+// scrapeRanking implements the ranking subcommand: scrape a best-seller
+// ranking or genre listing, record ranks and show movement
+func scrapeRanking(args []string) {
+	fs := flag.NewFlagSet("ranking", flag.ExitOnError)
+	genre := fs.String("genre", "", "Genre ID (empty for the all-genre ranking)")
+	period := fs.String("period", "daily", "Ranking period: realtime, daily, weekly or monthly")
+	listing := fs.Bool("listing", false, "Scrape the genre listing instead of the best-seller ranking")
+	website := fs.String("website", "rakuten", "Website to scrape (e.g., rakuten)")
+	limit := fs.Int("limit", 30, "Maximum number of ranked products")
+	scrape := addScrapeFlags(fs, false)
+	fs.Parse(args)
+	cfg := scrape.config()
+	dataDir := cfg.Data.Dir
+
+	if *listing && *genre == "" {
+		log.Fatal("A genre is required with -listing")
+	}
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		log.Fatalf("Failed to create data directory: %v", err)
+	}
+
+	store, err := storage.NewJSONFileStorage(filepath.Join(dataDir, "products.json"))
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	quarantine, err := storage.NewJSONQuarantineStorage(filepath.Join(dataDir, "quarantine.json"))
+	if err != nil {
+		log.Fatalf("Failed to initialize quarantine storage: %v", err)
+	}
+
+	factory := scraper.NewScraperFactoryWithConfig(cfg)
+	defer factory.Close()
+	defer report(factory)
+	gate := newGate(cfg, store, quarantine)
+
+	s, exists := factory.GetScraper(*website)
+	if !exists {
+		fatalf(factory, "No scraper found for website: %s", *website)
+	}
+	rs, ok := s.(scraper.RankingScraper)
+	if !ok {
+		fatalf(factory, "Rankings are not supported for website: %s", *website)
+	}
+
+	var source string
+	var products []*models.Product
+	if *listing {
+		source = models.GenreSource(*genre)
+		products, err = rs.ScrapeGenre(*genre, *limit)
+	} else {
+		source = models.RankingSource(*period, *genre)
+		products, err = rs.ScrapeRanking(*period, *genre, *limit)
+	}
+	if err != nil {
+		fatalf(factory, "Failed to scrape ranking: %v", err)
+	}
+
+	for _, p := range products {
+		if err := saveScraped(gate, health.PageListing, p, nil); err != nil {
+			log.Printf("Warning: Failed to save product %s: %v", p.ID, err)
+		}
+	}
+
+	fmt.Printf("Ranking %s (%d products):\n", source, len(products))
+	for _, m := range models.LatestRanking(products, source) {
+		movement := m.Status
+		switch {
+		case m.Status != models.MovementCompared:
+		case m.Change > 0:
+			movement = fmt.Sprintf("↑%d", m.Change)
+		case m.Change < 0:
+			movement = fmt.Sprintf("↓%d", -m.Change)
+		default:
+			movement = "="
+		}
+		fmt.Printf("%3d  %-5s %s\n", m.Rank, movement, m.Name)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/tedjuang/go-scrapy/internal/health"
+	"github.com/tedjuang/go-scrapy/internal/scraper"
+	"github.com/tedjuang/go-scrapy/internal/storage"
+)
+
+// crawlShop implements the crawl-shop subcommand: enumerate a shop's catalog
+// and save its products
+func crawlShop(args []string) {
+	fs := flag.NewFlagSet("crawl-shop", flag.ExitOnError)
+	shopCode := fs.String("shop", "", "Code of the shop to crawl (e.g., book)")
+	website := fs.String("website", "rakuten", "Website to crawl (e.g., rakuten)")
+	limit := fs.Int("limit", 100, "Maximum number of products to collect")
+	scrape := addScrapeFlags(fs, false)
+	fs.Parse(args)
+	cfg := scrape.config()
+	dataDir := cfg.Data.Dir
+
+	if *shopCode == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		log.Fatalf("Failed to create data directory: %v", err)
+	}
+
+	store, err := storage.NewJSONFileStorage(filepath.Join(dataDir, "products.json"))
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	quarantine, err := storage.NewJSONQuarantineStorage(filepath.Join(dataDir, "quarantine.json"))
+	if err != nil {
+		log.Fatalf("Failed to initialize quarantine storage: %v", err)
+	}
+	shops, err := storage.NewJSONShopStorage(filepath.Join(dataDir, "shops.json"))
+	if err != nil {
+		log.Fatalf("Failed to initialize shop storage: %v", err)
+	}
+
+	factory := scraper.NewScraperFactoryWithConfig(cfg)
+	defer factory.Close()
+	defer report(factory)
+	gate := newGate(cfg, store, quarantine)
+
+	s, exists := factory.GetScraper(*website)
+	if !exists {
+		fatalf(factory, "No scraper found for website: %s", *website)
+	}
+	crawler, ok := s.(scraper.ShopCrawler)
+	if !ok {
+		fatalf(factory, "Shop crawling is not supported for website: %s", *website)
+	}
+
+	fmt.Printf("Crawling shop '%s' on %s (limit: %d products)\n", *shopCode, *website, *limit)
+	shop, products, err := crawler.CrawlShop(*shopCode, *limit)
+	if err != nil {
+		fatalf(factory, "Failed to crawl shop: %v", err)
+	}
+
+	fmt.Printf("Shop: %s (%s)\n", shop.Name, shop.URL)
+	if shop.Rating > 0 {
+		fmt.Printf("Rating: %.2f (%d reviews)\n", shop.Rating, shop.ReviewCount)
+	}
+	fmt.Printf("Found %d products:\n", len(products))
+	for _, p := range products {
+		fmt.Printf("  %s  %s  %s\n", p.ID, p.CurrentPrice, p.Name)
+
+		if err := saveScraped(gate, health.PageListing, p, nil); err != nil {
+			log.Printf("Warning: Failed to save product %s: %v", p.ID, err)
+		}
+	}
+
+	if err := shops.Save(shop); err != nil {
+		fatalf(factory, "Failed to save shop: %v", err)
+	}
+	fmt.Println("\nShop and products saved successfully!")
+}
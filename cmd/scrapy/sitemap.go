@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/health"
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/scraper"
+	"github.com/tedjuang/go-scrapy/internal/sitemap"
+	"github.com/tedjuang/go-scrapy/internal/storage"
+)
+
+// discoverSitemap implements the sitemap subcommand: discover product URLs
+// from a site's sitemaps and batch-scrape them
+func discoverSitemap(args []string) {
+	fs := flag.NewFlagSet("sitemap", flag.ExitOnError)
+	website := fs.String("website", "rakuten", "Website whose sitemaps to read (e.g., rakuten)")
+	since := fs.String("since", "", "Only pages modified on or after this date (YYYY-MM-DD)")
+	limit := fs.Int("limit", 100, "Maximum number of product URLs")
+	list := fs.Bool("list", false, "Only list the discovered URLs, don't scrape them")
+	scrape := addScrapeFlags(fs, true)
+	fs.Parse(args)
+	cfg := scrape.config()
+	dataDir := cfg.Data.Dir
+
+	var sinceTime time.Time
+	if *since != "" {
+		var err error
+		if sinceTime, err = time.Parse("2006-01-02", *since); err != nil {
+			log.Fatalf("Invalid -since date: %v", err)
+		}
+	}
+
+	factory := scraper.NewScraperFactoryWithConfig(cfg)
+	defer factory.Close()
+	defer report(factory)
+	s, exists := factory.GetScraper(*website)
+	if !exists {
+		fatalf(factory, "No scraper found for website: %s", *website)
+	}
+	site, _ := factory.GetSiteConfig(*website)
+	if !site.Sitemap.Enabled() {
+		fatalf(factory, "No sitemaps configured for website: %s", *website)
+	}
+
+	fmt.Printf("Reading sitemaps for %s (limit: %d)\n", *website, *limit)
+	entries, err := factory.SitemapCrawler().Discover(site.Sitemap.RobotsURLs, site.Sitemap.SitemapURLs, sitemap.Options{
+		Since: sinceTime,
+		Match: site.Sitemap.IsProduct,
+		Limit: *limit,
+	})
+	if err != nil {
+		fatalf(factory, "Failed to read sitemaps: %v", err)
+	}
+
+	fmt.Printf("Discovered %d product URLs\n", len(entries))
+	urls := make([]string, len(entries))
+	for i, e := range entries {
+		urls[i] = e.Loc
+		if *list {
+			fmt.Println(e.Loc)
+		}
+	}
+	if *list {
+		return
+	}
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		fatalf(factory, "Failed to create data directory: %v", err)
+	}
+	store, err := storage.NewJSONFileStorage(filepath.Join(dataDir, "products.json"))
+	if err != nil {
+		fatalf(factory, "Failed to initialize storage: %v", err)
+	}
+	quarantine, err := storage.NewJSONQuarantineStorage(filepath.Join(dataDir, "quarantine.json"))
+	if err != nil {
+		fatalf(factory, "Failed to initialize quarantine storage: %v", err)
+	}
+	gate := newGate(cfg, store, quarantine)
+
+	result := scraper.ScrapeBatch(s, urls, func(p *models.Product) error {
+		fmt.Printf("Scraped %s: %s %s\n", p.ID, p.CurrentPrice, p.Name)
+		return saveScraped(gate, health.PageProduct, p, factory.Images())
+	})
+
+	for _, f := range result.Failures {
+		log.Printf("Warning: Failed to scrape %s: %s", f.URL, f.Error)
+	}
+	fmt.Printf("\nScraped %d of %d products\n", result.Scraped, result.Requested)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/health"
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/scraper"
+	"github.com/tedjuang/go-scrapy/internal/sitemap"
+)
+
+// defaultDiscoverLimit caps sitemap discovery when no limit is given
+const defaultDiscoverLimit = 100
+
+// BatchScrapeRequest represents a request to scrape several product URLs
+type BatchScrapeRequest struct {
+	Website string   `json:"website" binding:"required" example:"rakuten"`
+	URLs    []string `json:"urls" binding:"required,min=1"`
+}
+
+// DiscoverProductsRequest represents a request to discover product URLs
+// from a site's sitemaps
+type DiscoverProductsRequest struct {
+	Website string    `json:"website" binding:"required" example:"rakuten"`
+	Since   time.Time `json:"since,omitempty"`     // only pages modified since, by sitemap lastmod
+	Limit   int       `json:"limit" example:"100"` // maximum number of product URLs
+	DryRun  bool      `json:"dry_run,omitempty"`   // list the URLs without scraping them
+}
+
+// DiscoverResult is the result of a finished discovery job
+type DiscoverResult struct {
+	Discovered []sitemap.Entry      `json:"discovered"`
+	Batch      *scraper.BatchResult `json:"batch,omitempty"`
+}
+
+// BatchScrapeProducts starts a background scrape of several product URLs
+// @Summary Batch scrape products
+// @Description Start a background job that scrapes and saves each URL. Poll the returned job for the result.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param request body BatchScrapeRequest true "Batch Scrape Request"
+// @Success 202 {object} JobResponse "Batch scrape job"
+// @Failure 400 {object} JobResponse "Invalid request"
+// @Failure 404 {object} JobResponse "Scraper not found"
+// @Router /api/v1/products/scrape/batch [post]
+func (h *ProductHandler) BatchScrapeProducts(c *gin.Context) {
+	var req BatchScrapeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, JobResponse{
+			Error: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	s, exists := h.factory.GetScraper(req.Website)
+	if !exists {
+		c.JSON(http.StatusNotFound, JobResponse{
+			Error: "Scraper not found for website: " + req.Website,
+		})
+		return
+	}
+
+	job := h.jobs.Submit("batch_scrape", func() (any, error) {
+		result := h.scrapeBatch(s, req.URLs)
+		return &result, nil
+	})
+
+	c.JSON(http.StatusAccepted, JobResponse{
+		Job: &job,
+	})
+}
+
+// DiscoverProducts starts a background sitemap discovery, followed by a
+// batch scrape of the discovered product URLs
+// @Summary Discover products from sitemaps
+// @Description Start a background job that reads the site's sitemaps (robots.txt Sitemap: lines, sitemap indexes, gzip sitemaps), keeps product URLs modified since the given time and scrapes them unless dry_run is set
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param request body DiscoverProductsRequest true "Discover Products Request"
+// @Success 202 {object} JobResponse "Discovery job"
+// @Failure 400 {object} JobResponse "Invalid request or no sitemaps configured"
+// @Failure 404 {object} JobResponse "Scraper not found"
+// @Router /api/v1/products/discover [post]
+func (h *ProductHandler) DiscoverProducts(c *gin.Context) {
+	var req DiscoverProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, JobResponse{
+			Error: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = defaultDiscoverLimit
+	}
+
+	s, exists := h.factory.GetScraper(req.Website)
+	if !exists {
+		c.JSON(http.StatusNotFound, JobResponse{
+			Error: "Scraper not found for website: " + req.Website,
+		})
+		return
+	}
+	site, _ := h.factory.GetSiteConfig(req.Website)
+	if !site.Sitemap.Enabled() {
+		c.JSON(http.StatusBadRequest, JobResponse{
+			Error: "No sitemaps configured for website: " + req.Website,
+		})
+		return
+	}
+
+	job := h.jobs.Submit("sitemap_discover", func() (any, error) {
+		entries, err := h.factory.SitemapCrawler().Discover(site.Sitemap.RobotsURLs, site.Sitemap.SitemapURLs, sitemap.Options{
+			Since: req.Since,
+			Match: site.Sitemap.IsProduct,
+			Limit: req.Limit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sitemap discovery failed: %w", err)
+		}
+
+		result := &DiscoverResult{Discovered: entries}
+		if !req.DryRun {
+			urls := make([]string, len(entries))
+			for i, e := range entries {
+				urls[i] = e.Loc
+			}
+			batch := h.scrapeBatch(s, urls)
+			result.Batch = &batch
+		}
+		return result, nil
+	})
+
+	c.JSON(http.StatusAccepted, JobResponse{
+		Job: &job,
+	})
+}
+
+// scrapeBatch scrapes urls and saves the products, extending stored history.
+// Quarantined products are reported as failures.
+func (h *ProductHandler) scrapeBatch(s scraper.Scraper, urls []string) scraper.BatchResult {
+	return scraper.ScrapeBatch(s, urls, func(p *models.Product) error {
+		issues, err := h.saveScraped(health.PageProduct, p)
+		if err != nil {
+			return err
+		}
+		if len(issues) > 0 {
+			return health.QuarantineError(issues)
+		}
+		return nil
+	})
+}
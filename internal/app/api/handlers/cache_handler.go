@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/httpcache"
+)
+
+// CacheStatsResponse represents the HTTP cache counters
+type CacheStatsResponse struct {
+	Enabled bool             `json:"enabled"`
+	Stats   *httpcache.Stats `json:"stats,omitempty"`
+}
+
+// GetCacheStats returns the HTTP cache counters
+// @Summary Get HTTP cache statistics
+// @Description Get the hit, revalidation and miss counters of the scrapers' HTTP cache since the server started
+// @Tags cache
+// @Produce json
+// @Success 200 {object} CacheStatsResponse "Cache statistics"
+// @Router /api/v1/cache/stats [get]
+func (h *ProductHandler) GetCacheStats(c *gin.Context) {
+	cache := h.factory.Cache()
+	if cache == nil {
+		c.JSON(http.StatusOK, CacheStatsResponse{})
+		return
+	}
+
+	stats := cache.Stats()
+	c.JSON(http.StatusOK, CacheStatsResponse{
+		Enabled: true,
+		Stats:   &stats,
+	})
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/block"
+)
+
+// CircuitsResponse represents the circuit breaker state of every blocked domain
+type CircuitsResponse struct {
+	Circuits []block.State `json:"circuits"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// GetCircuits returns the circuit breaker state of every domain
+// @Summary Get circuit breaker states
+// @Description Get the circuit of every domain that served a block page since the server started: closed, open (requests paused until open_until) or half-open
+// @Tags scrapers
+// @Produce json
+// @Success 200 {object} CircuitsResponse "Circuit states"
+// @Router /api/v1/circuits [get]
+func (h *ProductHandler) GetCircuits(c *gin.Context) {
+	c.JSON(http.StatusOK, CircuitsResponse{
+		Circuits: h.factory.Breaker().States(),
+	})
+}
+
+// ResetCircuit closes the circuit of a domain
+// @Summary Reset a circuit
+// @Description Close the circuit of a domain so its requests resume at once, e.g. after solving a captcha or changing proxies
+// @Tags scrapers
+// @Produce json
+// @Param domain path string true "Domain, e.g. rakuten.co.jp"
+// @Success 200 {object} CircuitsResponse "Circuit states after the reset"
+// @Failure 404 {object} CircuitsResponse "No circuit for the domain"
+// @Router /api/v1/circuits/{domain}/reset [post]
+func (h *ProductHandler) ResetCircuit(c *gin.Context) {
+	breaker := h.factory.Breaker()
+	if !breaker.Reset(c.Param("domain")) {
+		c.JSON(http.StatusNotFound, CircuitsResponse{
+			Error: "No circuit for domain: " + c.Param("domain"),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, CircuitsResponse{
+		Circuits: breaker.States(),
+	})
+}
+
+// scrapeErrorStatus returns the status of a failed scrape: 503 with a
+// Retry-After header when the site blocked the scraper, 500 otherwise
+func scrapeErrorStatus(c *gin.Context, err error) int {
+	var blocked *block.BlockedError
+	if !errors.As(err, &blocked) {
+		return http.StatusInternalServerError
+	}
+	if !blocked.Until.IsZero() {
+		seconds := math.Ceil(time.Until(blocked.Until).Seconds())
+		c.Header("Retry-After", strconv.Itoa(max(int(seconds), 1)))
+	}
+	return http.StatusServiceUnavailable
+}
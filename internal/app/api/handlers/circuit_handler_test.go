@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/block"
+)
+
+func TestResetCircuit(t *testing.T) {
+	h := newTestHandler(t)
+	r := gin.New()
+	r.GET("/circuits", h.GetCircuits)
+	r.POST("/circuits/:domain/reset", h.ResetCircuit)
+
+	breaker := h.factory.Breaker()
+	for i := 0; i < block.DefaultThreshold; i++ {
+		breaker.RecordBlock("rakuten.co.jp", "status 429")
+	}
+
+	get := func(method, path string) (int, CircuitsResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		var resp CircuitsResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	if _, resp := get(http.MethodGet, "/circuits"); len(resp.Circuits) != 1 || resp.Circuits[0].State != block.StateOpen {
+		t.Fatalf("Expected the open circuit, got %+v", resp)
+	}
+	if code, _ := get(http.MethodPost, "/circuits/example.com/reset"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown domain, got %d", code)
+	}
+	if code, resp := get(http.MethodPost, "/circuits/rakuten.co.jp/reset"); code != http.StatusOK || resp.Circuits[0].State != block.StateClosed {
+		t.Errorf("Expected the circuit closed, got %d: %+v", code, resp)
+	}
+}
+
+func TestScrapeErrorStatus(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if code := scrapeErrorStatus(c, errors.New("timeout")); code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for other errors, got %d", code)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	err := &block.BlockedError{URL: "https://item.rakuten.co.jp/", Reason: "circuit open", Until: time.Now().Add(90 * time.Second)}
+	if code := scrapeErrorStatus(c, err); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 when blocked, got %d", code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "90" {
+		t.Errorf("Expected Retry-After 90, got %q", got)
+	}
+}
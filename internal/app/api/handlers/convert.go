@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// convertPrices converts each product's current price to the target
+// currency, keyed by product ID. Products that can't be converted are left
+// out of the prices and their errors returned, keyed by product ID.
+func (h *ProductHandler) convertPrices(products []*models.Product, target string) (map[string]models.Money, map[string]string) {
+	converted := make(map[string]models.Money, len(products))
+	var failed map[string]string
+	for _, p := range products {
+		price, err := h.converter.ConvertProduct(p, target)
+		if err != nil {
+			if failed == nil {
+				failed = make(map[string]string)
+			}
+			failed[p.ID] = err.Error()
+			continue
+		}
+		converted[p.ID] = price
+	}
+	return converted, failed
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/config"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestGetAllProductsConversionErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	ratesFile := filepath.Join(dir, "rates.json")
+	if err := os.WriteFile(ratesFile, []byte(`[{"date": "2000-01-01", "base": "JPY", "rates": {"USD": 0.005}}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Data.Dir = dir
+	cfg.Currency.RatesFile = ratesFile
+	h, err := NewProductHandler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.storage.Save(models.NewProduct("yen", "Yen", "https://example.com/yen", "rakuten", models.NewMoney(2000, "JPY")))
+	h.storage.Save(models.NewProduct("pound", "Pound", "https://example.com/pound", "rakuten", models.NewMoney(500, "GBP")))
+
+	r := gin.New()
+	r.GET("/api/v1/products", h.GetAllProducts)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/products?currency=USD&sort=price", nil))
+
+	var resp ProductsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Count != 2 {
+		t.Fatalf("Expected both products, got %d: %s", w.Code, w.Body)
+	}
+	if resp.ConvertedPrices["yen"] != models.NewMoney(1000, "USD") {
+		t.Errorf("Expected 10.00 USD, got %s", resp.ConvertedPrices["yen"])
+	}
+	if _, ok := resp.ConvertedPrices["pound"]; ok || resp.ConversionErrors["pound"] == "" {
+		t.Errorf("Expected the GBP product flagged, got %v %v", resp.ConvertedPrices, resp.ConversionErrors)
+	}
+}
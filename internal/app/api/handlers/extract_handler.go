@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/scraper"
+	"github.com/tedjuang/go-scrapy/internal/snapshot"
+)
+
+// Page types of an extraction request
+const (
+	PageTypeProduct = "product"
+	PageTypeSearch  = "search"
+)
+
+// ExtractRequest represents a request to run a scraper's extraction over a
+// page given as raw HTML or as an archived snapshot
+type ExtractRequest struct {
+	HTML       string `json:"html,omitempty"`
+	SnapshotID string `json:"snapshot_id,omitempty"`
+	URL        string `json:"url,omitempty" example:"https://item.rakuten.co.jp/book/14583459/"` // required with html; defaults to the snapshot's URL
+	PageType   string `json:"page_type,omitempty" example:"product"`                             // product (default) or search
+}
+
+// ExtractResponse represents the products extracted from a page
+type ExtractResponse struct {
+	URL        string                       `json:"url,omitempty"`
+	PageType   string                       `json:"page_type,omitempty"`
+	Products   []*models.Product            `json:"products"`
+	Provenance map[string]models.Provenance `json:"provenance,omitempty"` // keyed by product ID
+	Count      int                          `json:"count"`
+	Error      string                       `json:"error,omitempty"`
+}
+
+// ExtractPage runs a scraper's extraction over a given page
+// @Summary Extract products from a page
+// @Description Run a website's extraction over raw HTML or an archived snapshot and return the products it yields with the provenance of each field. Nothing is fetched or saved.
+// @Tags scrapers
+// @Accept json
+// @Produce json
+// @Param website path string true "Website whose scraper extracts the page"
+// @Param request body ExtractRequest true "Extract Request"
+// @Success 200 {object} ExtractResponse "Extracted products"
+// @Failure 400 {object} ExtractResponse "Invalid request or website can't extract pages"
+// @Failure 404 {object} ExtractResponse "Scraper or snapshot not found"
+// @Failure 500 {object} ExtractResponse "Server error"
+// @Router /api/v1/scrapers/{website}/extract [post]
+func (h *ProductHandler) ExtractPage(c *gin.Context) {
+	var req ExtractRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ExtractResponse{
+			Error: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	if (req.HTML == "") == (req.SnapshotID == "") {
+		c.JSON(http.StatusBadRequest, ExtractResponse{
+			Error: "Invalid request: exactly one of html and snapshot_id is required",
+		})
+		return
+	}
+	if req.PageType == "" {
+		req.PageType = PageTypeProduct
+	}
+	if req.PageType != PageTypeProduct && req.PageType != PageTypeSearch {
+		c.JSON(http.StatusBadRequest, ExtractResponse{
+			Error: "Invalid page type: " + req.PageType,
+		})
+		return
+	}
+
+	website := c.Param("website")
+	s, exists := h.factory.GetScraper(website)
+	if !exists {
+		c.JSON(http.StatusNotFound, ExtractResponse{
+			Error: "Scraper not found for website: " + website,
+		})
+		return
+	}
+	extractor, ok := s.(scraper.Extractor)
+	if !ok {
+		c.JSON(http.StatusBadRequest, ExtractResponse{
+			Error: "Website can't extract pages: " + website,
+		})
+		return
+	}
+
+	html := []byte(req.HTML)
+	if req.SnapshotID != "" {
+		archive := h.factory.Archive()
+		if archive == nil {
+			c.JSON(http.StatusBadRequest, ExtractResponse{
+				Error: "Snapshot archive is disabled",
+			})
+			return
+		}
+
+		meta, archived, err := archive.Load(req.SnapshotID)
+		if errors.Is(err, snapshot.ErrNotFound) {
+			c.JSON(http.StatusNotFound, ExtractResponse{
+				Error: "Snapshot not found",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ExtractResponse{
+				Error: "Failed to load snapshot: " + err.Error(),
+			})
+			return
+		}
+		html = archived
+		if req.URL == "" {
+			req.URL = meta.URL
+		}
+	}
+
+	// Product IDs and relative links are derived from the page's URL
+	if req.URL == "" {
+		c.JSON(http.StatusBadRequest, ExtractResponse{
+			Error: "Invalid request: url is required with html",
+		})
+		return
+	}
+
+	// A page yielding nothing is a valid answer: the selectors don't match it
+	products := []*models.Product{}
+	if req.PageType == PageTypeSearch {
+		found, err := extractor.ExtractSearch(req.URL, html)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ExtractResponse{
+				Error: "Failed to extract page: " + err.Error(),
+			})
+			return
+		}
+		products = append(products, found...)
+	} else if product, err := extractor.ExtractProduct(req.URL, html); err == nil {
+		products = append(products, product)
+	}
+
+	provenance := make(map[string]models.Provenance, len(products))
+	for _, p := range products {
+		provenance[p.ID] = p.Provenance
+	}
+
+	c.JSON(http.StatusOK, ExtractResponse{
+		URL:        req.URL,
+		PageType:   req.PageType,
+		Products:   products,
+		Provenance: provenance,
+		Count:      len(products),
+	})
+}
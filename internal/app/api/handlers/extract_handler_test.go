@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/config"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+const extractTestPage = `<html><body>
+<h1 itemprop="name">Nintendo Switch 有機ELモデル</h1>
+<span itemprop="price" content="37980">37,980円</span>
+</body></html>`
+
+// newTestHandler creates a handler storing its data in a temporary directory
+func newTestHandler(t *testing.T) *ProductHandler {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{}
+	cfg.Data.Dir = t.TempDir()
+	h, err := NewProductHandler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func newExtractRouter(t *testing.T) (*gin.Engine, *ProductHandler) {
+	h := newTestHandler(t)
+	r := gin.New()
+	r.POST("/scrapers/:website/extract", h.ExtractPage)
+	return r, h
+}
+
+func postExtract(r *gin.Engine, website string, req ExtractRequest) (int, ExtractResponse) {
+	body, _ := json.Marshal(req)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/scrapers/"+website+"/extract", strings.NewReader(string(body))))
+
+	var resp ExtractResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestExtractPage(t *testing.T) {
+	r, h := newExtractRouter(t)
+
+	code, resp := postExtract(r, "rakuten", ExtractRequest{
+		HTML: extractTestPage,
+		URL:  "https://item.rakuten.co.jp/shop/switch-oled/",
+	})
+	if code != http.StatusOK || resp.Count != 1 {
+		t.Fatalf("Expected one product, got %d: %+v", code, resp)
+	}
+	if p := resp.Products[0]; p.ID != "switch-oled" || p.CurrentPrice != models.NewMoney(37980, "JPY") {
+		t.Errorf("Unexpected product %s at %s", p.ID, p.CurrentPrice)
+	}
+	if source := resp.Provenance["switch-oled"]["price"]; source.Rule != "[itemprop='price']@content" {
+		t.Errorf("Unexpected price provenance %+v", source)
+	}
+
+	// Extraction must not save anything
+	if products, _ := h.storage.GetAll(); len(products) != 0 {
+		t.Errorf("Expected nothing saved, got %d products", len(products))
+	}
+}
+
+func TestExtractPageErrors(t *testing.T) {
+	r, _ := newExtractRouter(t)
+
+	tests := []struct {
+		name    string
+		website string
+		req     ExtractRequest
+		code    int
+	}{
+		{"no page", "rakuten", ExtractRequest{URL: "https://item.rakuten.co.jp/shop/a/"}, http.StatusBadRequest},
+		{"html and snapshot", "rakuten", ExtractRequest{HTML: extractTestPage, SnapshotID: "abc"}, http.StatusBadRequest},
+		{"html without url", "rakuten", ExtractRequest{HTML: extractTestPage}, http.StatusBadRequest},
+		{"bad page type", "rakuten", ExtractRequest{HTML: extractTestPage, URL: "https://item.rakuten.co.jp/shop/a/", PageType: "cart"}, http.StatusBadRequest},
+		{"archive disabled", "rakuten", ExtractRequest{SnapshotID: "abc"}, http.StatusBadRequest},
+		{"unknown website", "amazon", ExtractRequest{HTML: extractTestPage, URL: "https://amazon.co.jp/dp/1"}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := postExtract(r, tt.website, tt.req)
+			if code != tt.code || resp.Error == "" {
+				t.Errorf("Expected %d with an error, got %d: %+v", tt.code, code, resp)
+			}
+		})
+	}
+}
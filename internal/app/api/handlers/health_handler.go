@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/health"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// ScraperHealthResponse represents the extraction health of every site
+type ScraperHealthResponse struct {
+	Sites []health.PageHealth `json:"sites"`
+}
+
+// QuarantineResponse represents the quarantined products
+type QuarantineResponse struct {
+	Products []*models.QuarantinedProduct `json:"products"`
+	Count    int                          `json:"count"`
+	Error    string                       `json:"error,omitempty"`
+}
+
+// GetScraperHealth returns per-field extraction metrics of every site
+// @Summary Get scraper health
+// @Description Get per-site, per-page-type extraction success rates of each product field since the server started, with ok/degrading/failing statuses. The counts are kept in memory only and reset when the server restarts.
+// @Tags scrapers
+// @Produce json
+// @Success 200 {object} ScraperHealthResponse "Scraper health"
+// @Router /api/v1/scrapers/health [get]
+func (h *ProductHandler) GetScraperHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, ScraperHealthResponse{
+		Sites: h.monitor.Report(),
+	})
+}
+
+// GetQuarantine returns the scraped products held back by validation
+// @Summary Get quarantined products
+// @Description Get scraped products that failed validation and were not saved, most recent first
+// @Tags scrapers
+// @Produce json
+// @Success 200 {object} QuarantineResponse "Quarantined products"
+// @Failure 500 {object} QuarantineResponse "Server error"
+// @Router /api/v1/quarantine [get]
+func (h *ProductHandler) GetQuarantine(c *gin.Context) {
+	products, err := h.quarantine.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, QuarantineResponse{
+			Error: "Failed to get quarantined products: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, QuarantineResponse{
+		Products: products,
+		Count:    len(products),
+	})
+}
+
+// ApproveQuarantined saves a quarantined product despite its issues
+// @Summary Approve a quarantined product
+// @Description Save a quarantined product as scraped, extending the stored history, and remove it from quarantine
+// @Tags scrapers
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {object} ProductResponse "Saved product"
+// @Failure 404 {object} ProductResponse "Product not quarantined"
+// @Failure 500 {object} ProductResponse "Server error"
+// @Router /api/v1/quarantine/{id}/approve [post]
+func (h *ProductHandler) ApproveQuarantined(c *gin.Context) {
+	entry, err := h.quarantine.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ProductResponse{
+			Error: "Failed to get quarantined product: " + err.Error(),
+		})
+		return
+	}
+	if entry == nil {
+		c.JSON(http.StatusNotFound, ProductResponse{
+			Error: "Product not quarantined",
+		})
+		return
+	}
+
+	product := entry.Product
+	h.mergeStoredHistory(product)
+	if err := h.storage.Save(product); err != nil {
+		c.JSON(http.StatusInternalServerError, ProductResponse{
+			Error: "Failed to save product: " + err.Error(),
+		})
+		return
+	}
+	h.recordShop(product)
+
+	if err := h.quarantine.Delete(product.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, ProductResponse{
+			Error: "Failed to remove product from quarantine: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ProductResponse{
+		Product: product,
+	})
+}
+
+// RejectQuarantined discards a quarantined product
+// @Summary Reject a quarantined product
+// @Description Discard a quarantined scrape, keeping the stored product unchanged
+// @Tags scrapers
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 204 "Discarded"
+// @Failure 404 {object} QuarantineResponse "Product not quarantined"
+// @Router /api/v1/quarantine/{id} [delete]
+func (h *ProductHandler) RejectQuarantined(c *gin.Context) {
+	if err := h.quarantine.Delete(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, QuarantineResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// ProductHistoryResponse represents the time series recorded for a product
+type ProductHistoryResponse struct {
+	ProductID           string                      `json:"product_id"`
+	PriceHistory        []models.PricePoint         `json:"price_history"`
+	PriceStats          *models.PriceStats          `json:"price_stats,omitempty"`
+	EffectivePriceStats *models.PriceStats          `json:"effective_price_stats,omitempty"`
+	AvailabilityChanges []models.AvailabilityChange `json:"availability_changes,omitempty"`
+	ReviewHistory       []models.ReviewPoint        `json:"review_history"`
+	ReviewVelocity      float64                     `json:"review_velocity"` // new reviews per day over the last 30 days
+	Error               string                      `json:"error,omitempty"`
+}
+
+// GetProductHistory returns the price and review history of a product
+// @Summary Get product history
+// @Description Get the price and review time series of a product with summary statistics
+// @Tags products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param currency query string false "Convert the price history to this currency (ISO 4217)"
+// @Success 200 {object} ProductHistoryResponse "Product history"
+// @Failure 400 {object} ProductHistoryResponse "Invalid request"
+// @Failure 404 {object} ProductHistoryResponse "Product not found"
+// @Failure 500 {object} ProductHistoryResponse "Server error"
+// @Router /api/v1/products/{id}/history [get]
+func (h *ProductHandler) GetProductHistory(c *gin.Context) {
+	id := c.Param("id")
+
+	product, err := h.storage.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ProductHistoryResponse{
+			Error: "Failed to get product: " + err.Error(),
+		})
+		return
+	}
+
+	if product == nil {
+		c.JSON(http.StatusNotFound, ProductHistoryResponse{
+			Error: "Product not found",
+		})
+		return
+	}
+
+	// Work on a copy so conversion doesn't touch the stored product
+	history := *product
+	if target := c.Query("currency"); target != "" {
+		history.PriceHistory, err = h.converter.ConvertHistory(product.PriceHistory, target)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ProductHistoryResponse{
+				Error: "Failed to convert prices: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	resp := ProductHistoryResponse{
+		ProductID:           product.ID,
+		PriceHistory:        history.PriceHistory,
+		AvailabilityChanges: history.AvailabilityChanges(),
+		ReviewHistory:       history.ReviewHistory,
+		ReviewVelocity:      history.ReviewVelocity(reviewVelocityWindow),
+	}
+
+	// Stats are omitted when the history mixes currencies
+	if stats, err := history.PriceStats(); err == nil {
+		resp.PriceStats = &stats
+	}
+	if stats, err := history.EffectivePriceStats(); err == nil {
+		resp.EffectivePriceStats = &stats
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
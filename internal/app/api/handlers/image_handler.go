@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/media"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// ImageResponse is a version of a product's image with the paths serving it
+type ImageResponse struct {
+	models.ImageVersion
+	FilePath      string `json:"file_path" example:"/api/v1/products/14583459/images/3f5a..."`
+	ThumbnailPath string `json:"thumbnail_path" example:"/api/v1/products/14583459/images/3f5a...?thumbnail=true"`
+}
+
+// ImagesResponse represents the downloaded images of a product
+type ImagesResponse struct {
+	ProductID string          `json:"product_id,omitempty"`
+	Images    []ImageResponse `json:"images"`
+	Changes   int             `json:"changes"` // versions showing a different picture than the one before
+	Error     string          `json:"error,omitempty"`
+}
+
+// GetProductImages lists the downloaded versions of a product's image
+// @Summary Get a product's images
+// @Description Get the downloaded versions of a product's listing image, oldest first, with their perceptual hashes and whether each shows a different picture than the one before
+// @Tags products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {object} ImagesResponse "Image versions"
+// @Failure 404 {object} ImagesResponse "Product not found"
+// @Failure 500 {object} ImagesResponse "Server error"
+// @Router /api/v1/products/{id}/images [get]
+func (h *ProductHandler) GetProductImages(c *gin.Context) {
+	product, err := h.storage.GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ImagesResponse{
+			Error: "Failed to get product: " + err.Error(),
+		})
+		return
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, ImagesResponse{
+			Error: "Product not found",
+		})
+		return
+	}
+
+	resp := ImagesResponse{
+		ProductID: product.ID,
+		Images:    make([]ImageResponse, len(product.Images)),
+		Changes:   len(product.ImageChanges()),
+	}
+	for i, v := range product.Images {
+		path := "/api/v1/products/" + product.ID + "/images/" + v.ID
+		resp.Images[i] = ImageResponse{ImageVersion: v, FilePath: path, ThumbnailPath: path + "?thumbnail=true"}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetProductImage serves a downloaded image of a product
+// @Summary Get a product image
+// @Description Get a downloaded version of a product's image by its ID, or its JPEG thumbnail with ?thumbnail=true
+// @Tags products
+// @Produce image/jpeg
+// @Produce image/png
+// @Produce image/gif
+// @Param id path string true "Product ID"
+// @Param image path string true "Image ID"
+// @Param thumbnail query bool false "Serve the thumbnail instead of the image"
+// @Success 200 {file} binary "Image"
+// @Failure 404 {object} ImagesResponse "Image not found or image downloads disabled"
+// @Failure 500 {object} ImagesResponse "Server error"
+// @Router /api/v1/products/{id}/images/{image} [get]
+func (h *ProductHandler) GetProductImage(c *gin.Context) {
+	images := h.factory.Images()
+	if images == nil {
+		c.JSON(http.StatusNotFound, ImagesResponse{
+			Error: "Image downloads are disabled",
+		})
+		return
+	}
+
+	product, err := h.storage.GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ImagesResponse{
+			Error: "Failed to get product: " + err.Error(),
+		})
+		return
+	}
+	// Only images of the product are served
+	if product == nil || product.Image(c.Param("image")) == nil {
+		c.JSON(http.StatusNotFound, ImagesResponse{
+			Error: "Image not found",
+		})
+		return
+	}
+
+	load := images.Store().Load
+	if c.Query("thumbnail") == "true" {
+		load = images.Store().LoadThumbnail
+	}
+	meta, data, err := load(c.Param("image"))
+	if errors.Is(err, media.ErrNotFound) {
+		c.JSON(http.StatusNotFound, ImagesResponse{
+			Error: "Image not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ImagesResponse{
+			Error: "Failed to load image: " + err.Error(),
+		})
+		return
+	}
+
+	contentType := meta.ContentType
+	if c.Query("thumbnail") == "true" {
+		contentType = "image/jpeg"
+	}
+	// Images never change under their ID
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Data(http.StatusOK, contentType, data)
+}
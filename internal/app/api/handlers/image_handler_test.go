@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/config"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestGetProductImages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Data.Dir = t.TempDir()
+	cfg.Scraping.IgnoreRobotsTxt = true
+	cfg.Scraping.Images.Enabled = true
+	h, err := NewProductHandler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	picture := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	for x := 0; x < 300; x++ {
+		for y := 0; y < 200; y++ {
+			picture.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	var data bytes.Buffer
+	png.Encode(&data, picture)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data.Bytes())
+	}))
+	defer server.Close()
+
+	product := models.NewProduct("item-1", "Item", "https://item.rakuten.co.jp/shop/item-1/", "rakuten", models.NewMoney(1000, "JPY"))
+	product.ImageURL = server.URL + "/item.png"
+	if err := h.factory.Images().Download(product); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.storage.Save(product); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/api/v1/products/:id/images", h.GetProductImages)
+	r.GET("/api/v1/products/:id/images/:image", h.GetProductImage)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/v1/products/item-1/images")
+	var resp ImagesResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Images) != 1 || resp.Images[0].Width != 300 {
+		t.Fatalf("Expected one image, got %d: %+v", w.Code, resp)
+	}
+
+	if w := get(resp.Images[0].FilePath); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || !bytes.Equal(w.Body.Bytes(), data.Bytes()) {
+		t.Errorf("Expected the PNG, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w := get(resp.Images[0].ThumbnailPath); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("Expected the JPEG thumbnail, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w := get("/api/v1/products/item-2/images"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown product, got %d", w.Code)
+	}
+	// Images of other products aren't served under this one
+	if w := get("/api/v1/products/item-1/images/" + strings.Repeat("0", 64)); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown image, got %d", w.Code)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/matching"
+)
+
+// ItemOffersResponse represents the offers for an item across websites
+type ItemOffersResponse struct {
+	Item   *matching.Item   `json:"item,omitempty"`
+	Offers []matching.Offer `json:"offers"`
+	Count  int              `json:"count"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// GetItemOffers returns the cheapest current offers for an item
+// @Summary Get offers for an item
+// @Description List the cheapest current offer per website for an item, matched by JAN/EAN/ISBN or title similarity
+// @Tags items
+// @Produce json
+// @Param id path string true "Item ID, GTIN or the ID of any product in the item"
+// @Param currency query string false "Compare offers converted to this currency (ISO 4217)"
+// @Success 200 {object} ItemOffersResponse "Item offers"
+// @Failure 404 {object} ItemOffersResponse "Item not found"
+// @Failure 500 {object} ItemOffersResponse "Server error"
+// @Router /api/v1/items/{id}/offers [get]
+func (h *ProductHandler) GetItemOffers(c *gin.Context) {
+	id := c.Param("id")
+
+	products, err := h.storage.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ItemOffersResponse{
+			Error: "Failed to get products: " + err.Error(),
+		})
+		return
+	}
+
+	item := h.matcher.FindItem(products, id)
+	if item == nil {
+		c.JSON(http.StatusNotFound, ItemOffersResponse{
+			Error: "Item not found",
+		})
+		return
+	}
+
+	offers := item.Offers()
+
+	// Compare offers in one currency using the rate valid at each offer's update
+	if target := c.Query("currency"); target != "" {
+		for i := range offers {
+			price, err := h.converter.Convert(offers[i].Price, target, offers[i].LastUpdated)
+			if err != nil {
+				offers[i].ConversionError = err.Error()
+				continue
+			}
+			offers[i].ConvertedPrice = &price
+		}
+		matching.SortOffers(offers)
+	}
+
+	c.JSON(http.StatusOK, ItemOffersResponse{
+		Item:   item,
+		Offers: offers,
+		Count:  len(offers),
+	})
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/config"
+	"github.com/tedjuang/go-scrapy/internal/health"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestGetItemOffersAfterListingSave(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Data.Dir = t.TempDir()
+	h, err := NewProductHandler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const jan = "4902370548495"
+	page := models.NewProduct("shop:switch", "Nintendo Switch 有機ELモデル ホワイト", "https://item.rakuten.co.jp/shop/switch/", "rakuten", models.NewMoney(37980, "JPY"))
+	page.SetIdentifier(jan)
+	other := models.NewProduct("B098RKWHHZ", "ゲーム機本体 OLED", "https://www.example.com/dp/B098RKWHHZ", "example", models.NewMoney(38500, "JPY"))
+	other.SetIdentifier(jan)
+	for _, p := range []*models.Product{page, other} {
+		if issues, err := h.saveScraped(health.PageProduct, p); err != nil || len(issues) > 0 {
+			t.Fatalf("Failed to save %s: %v %v", p.ID, issues, err)
+		}
+	}
+
+	// The product shows up in search results, which carry no JAN
+	listing := models.NewProduct("shop:switch", "Nintendo Switch 有機ELモデル ホワイト", "https://item.rakuten.co.jp/shop/switch/", "rakuten", models.NewMoney(36980, "JPY"))
+	if issues, err := h.saveScraped(health.PageListing, listing); err != nil || len(issues) > 0 {
+		t.Fatalf("Failed to save the listing result: %v %v", issues, err)
+	}
+
+	r := gin.New()
+	r.GET("/api/v1/items/:id/offers", h.GetItemOffers)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/items/"+jan+"/offers", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the item found by its JAN, got %d: %s", w.Code, w.Body)
+	}
+	var resp ItemOffersResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != 2 || resp.Offers[0].Price != models.NewMoney(36980, "JPY") {
+		t.Errorf("Expected both sites' offers, the listing's price first, got %+v", resp.Offers)
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/crawlpolicy"
+)
+
+// SkippedURLsResponse represents the URLs refused by the crawl policy
+type SkippedURLsResponse struct {
+	Skipped []crawlpolicy.Skip `json:"skipped"`
+	Count   int                `json:"count"`
+}
+
+// GetSkippedURLs returns the URLs the crawl policy refused to fetch
+// @Summary Get URLs skipped by the crawl policy
+// @Description List the most recent URLs not fetched because robots.txt disallowed them or could not be read
+// @Tags crawl-policy
+// @Produce json
+// @Success 200 {object} SkippedURLsResponse "Skipped URLs"
+// @Router /api/v1/crawl-policy/skipped [get]
+func (h *ProductHandler) GetSkippedURLs(c *gin.Context) {
+	skipped := h.factory.Policy().Skipped()
+	c.JSON(http.StatusOK, SkippedURLsResponse{
+		Skipped: skipped,
+		Count:   len(skipped),
+	})
+}
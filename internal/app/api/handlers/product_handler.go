@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"path/filepath"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/config"
+	"github.com/tedjuang/go-scrapy/internal/currency"
+	"github.com/tedjuang/go-scrapy/internal/health"
+	"github.com/tedjuang/go-scrapy/internal/jobs"
+	"github.com/tedjuang/go-scrapy/internal/matching"
 	"github.com/tedjuang/go-scrapy/internal/models"
 	"github.com/tedjuang/go-scrapy/internal/scraper"
 	"github.com/tedjuang/go-scrapy/internal/storage"
@@ -12,31 +18,82 @@ import (
 
 // ProductHandler handles requests related to products
 type ProductHandler struct {
-	factory *scraper.ScraperFactory
-	storage *storage.JSONFileStorage
+	factory    *scraper.ScraperFactory
+	storage    *storage.JSONFileStorage
+	shops      *storage.JSONShopStorage
+	quarantine *storage.JSONQuarantineStorage
+	matcher    *matching.Matcher
+	converter  *currency.Converter
+	jobs       *jobs.Manager
+	monitor    *health.Monitor
+	gate       *health.Gate
 }
 
 // NewProductHandler creates a new product handler
-func NewProductHandler(dataDir string) (*ProductHandler, error) {
+func NewProductHandler(cfg *config.Config) (*ProductHandler, error) {
+	dataDir := cfg.Data.Dir
+
 	// Create storage
 	store, err := storage.NewJSONFileStorage(filepath.Join(dataDir, "products.json"))
 	if err != nil {
 		return nil, err
 	}
 
-	// Create scraper factory
-	factory := scraper.NewScraperFactory()
+	shops, err := storage.NewJSONShopStorage(filepath.Join(dataDir, "shops.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Suspicious scrapes are held here instead of overwriting stored products
+	quarantine, err := storage.NewJSONQuarantineStorage(filepath.Join(dataDir, "quarantine.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Create currency converter; daily rate snapshots are kept with the data
+	provider, err := currency.NewProvider(cfg.Currency.RatesFile, cfg.Currency.RatesURL)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := currency.NewSnapshotStore(filepath.Join(dataDir, "exchange_rates.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Create scraper factory; its scrapers share the configured crawl policy
+	factory := scraper.NewScraperFactoryWithConfig(cfg)
+
+	// Health metrics are kept in memory and start over with the server
+	monitor := health.NewMonitor(cfg.Scraping.Validation.HealthWindow)
 
 	return &ProductHandler{
-		factory: factory,
-		storage: store,
+		factory:    factory,
+		storage:    store,
+		shops:      shops,
+		quarantine: quarantine,
+		matcher:    matching.NewMatcher(),
+		converter:  currency.NewConverter(provider, rates),
+		jobs:       jobs.NewManager(),
+		monitor:    monitor,
+		gate: &health.Gate{
+			Store:      store,
+			Quarantine: quarantine,
+			Rules:      health.NewRules(cfg.Scraping.Validation.MaxPriceRatio),
+			Monitor:    monitor,
+		},
 	}, nil
 }
 
+// Close stops the background work of the handler's scrapers
+func (h *ProductHandler) Close() {
+	h.factory.Close()
+}
+
 // ScrapeProductRequest represents a request to scrape a product
 type ScrapeProductRequest struct {
 	URL     string `json:"url" binding:"required" example:"https://item.rakuten.co.jp/book/14583459/"`
 	Website string `json:"website" binding:"required" example:"rakuten"`
+	Variant string `json:"variant,omitempty" example:"10000001"` // SKU to track, if the product has variants
 }
 
 // SearchProductsRequest represents a request to search for products
@@ -48,15 +105,23 @@ type SearchProductsRequest struct {
 
 // ProductResponse represents the response for a product
 type ProductResponse struct {
-	Product *models.Product `json:"product"`
-	Error   string          `json:"error,omitempty"`
+	Product          *models.Product          `json:"product"`
+	ConvertedPrice   *models.Money            `json:"converted_price,omitempty"`
+	ConvertedHistory []models.PricePoint      `json:"converted_price_history,omitempty"`
+	Quarantined      bool                     `json:"quarantined,omitempty"` // the scrape failed validation and wasn't saved
+	Issues           []models.ValidationIssue `json:"issues,omitempty"`
+	Provenance       models.Provenance        `json:"provenance,omitempty"` // with ?debug=true
+	Error            string                   `json:"error,omitempty"`
 }
 
 // ProductsResponse represents the response for multiple products
 type ProductsResponse struct {
-	Products []*models.Product `json:"products"`
-	Count    int               `json:"count"`
-	Error    string            `json:"error,omitempty"`
+	Products         []*models.Product            `json:"products"`
+	ConvertedPrices  map[string]models.Money      `json:"converted_prices,omitempty"`  // keyed by product ID
+	ConversionErrors map[string]string            `json:"conversion_errors,omitempty"` // keyed by product ID, for prices that couldn't be converted
+	Provenance       map[string]models.Provenance `json:"provenance,omitempty"`        // keyed by product ID, with ?debug=true
+	Count            int                          `json:"count"`
+	Error            string                       `json:"error,omitempty"`
 }
 
 // ScrapeProduct scrapes a product from a given URL
@@ -66,10 +131,12 @@ type ProductsResponse struct {
 // @Accept json
 // @Produce json
 // @Param request body ScrapeProductRequest true "Scrape Product Request"
+// @Param debug query bool false "Include the provenance of each extracted field"
 // @Success 200 {object} ProductResponse "Product information"
 // @Failure 400 {object} ProductResponse "Invalid request"
 // @Failure 404 {object} ProductResponse "Scraper not found"
 // @Failure 500 {object} ProductResponse "Server error"
+// @Failure 503 {object} ProductResponse "Blocked by the site or its circuit is open"
 // @Router /api/v1/products/scrape [post]
 func (h *ProductHandler) ScrapeProduct(c *gin.Context) {
 	var req ScrapeProductRequest
@@ -92,23 +159,39 @@ func (h *ProductHandler) ScrapeProduct(c *gin.Context) {
 	// Scrape the product
 	product, err := s.ScrapeProduct(req.URL)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ProductResponse{
+		c.JSON(scrapeErrorStatus(c, err), ProductResponse{
 			Error: "Failed to scrape product: " + err.Error(),
 		})
 		return
 	}
 
-	// Save to storage
-	if err := h.storage.Save(product); err != nil {
+	if req.Variant != "" {
+		if err := product.TrackVariant(req.Variant); err != nil {
+			c.JSON(http.StatusBadRequest, ProductResponse{
+				Error: "Invalid variant: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	// Save to storage, extending the stored history, unless quarantined
+	issues, err := h.saveScraped(health.PageProduct, product)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ProductResponse{
 			Error: "Failed to save product: " + err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, ProductResponse{
-		Product: product,
-	})
+	resp := ProductResponse{
+		Product:     product,
+		Quarantined: len(issues) > 0,
+		Issues:      issues,
+	}
+	if c.Query("debug") == "true" {
+		resp.Provenance = product.Provenance
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // SearchProducts searches for products
@@ -118,10 +201,12 @@ func (h *ProductHandler) ScrapeProduct(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body SearchProductsRequest true "Search Products Request"
+// @Param debug query bool false "Include the provenance of each product's fields"
 // @Success 200 {object} ProductsResponse "Search results"
 // @Failure 400 {object} ProductsResponse "Invalid request"
 // @Failure 404 {object} ProductsResponse "Scraper not found"
 // @Failure 500 {object} ProductsResponse "Server error"
+// @Failure 503 {object} ProductsResponse "Blocked by the site or its circuit is open"
 // @Router /api/v1/products/search [post]
 func (h *ProductHandler) SearchProducts(c *gin.Context) {
 	var req SearchProductsRequest
@@ -149,7 +234,7 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 	// Search for products
 	products, err := s.ScrapeSearch(req.Keyword, req.MaxResults)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ProductsResponse{
+		c.JSON(scrapeErrorStatus(c, err), ProductsResponse{
 			Error: "Failed to search for products: " + err.Error(),
 		})
 		return
@@ -157,27 +242,81 @@ func (h *ProductHandler) SearchProducts(c *gin.Context) {
 
 	// Save products to storage
 	for _, p := range products {
-		if err := h.storage.Save(p); err != nil {
-			// Just log the error but continue
-			// TODO: Add proper logging
+		if _, err := h.saveScraped(health.PageListing, p); err != nil {
+			log.Printf("Warning: failed to save product %s: %v", p.ID, err)
 		}
 	}
 
-	c.JSON(http.StatusOK, ProductsResponse{
+	resp := ProductsResponse{
 		Products: products,
 		Count:    len(products),
-	})
+	}
+	if c.Query("debug") == "true" {
+		resp.Provenance = make(map[string]models.Provenance, len(products))
+		for _, p := range products {
+			resp.Provenance[p.ID] = p.Provenance
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// saveScraped validates a freshly scraped product against the stored copy
+// and saves it with the stored history, or quarantines it if it fails
+// validation, returning the issues found. Every result is counted in the
+// health monitor under its page type. With image downloads enabled, the
+// image of a product page is downloaded before saving.
+func (h *ProductHandler) saveScraped(page string, product *models.Product) ([]models.ValidationIssue, error) {
+	// Listings show other image sizes, which would look like changes
+	var prepare func(*models.Product)
+	if images := h.factory.Images(); images != nil && page == health.PageProduct {
+		prepare = func(p *models.Product) {
+			if err := images.Download(p); err != nil {
+				log.Printf("Warning: image of product %s not downloaded: %v", p.ID, err)
+			}
+		}
+	}
+
+	issues, err := h.gate.Save(page, product, prepare)
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) > 0 {
+		log.Printf("Warning: quarantined product %s: %v", product.ID, health.QuarantineError(issues))
+		return issues, nil
+	}
+	h.recordShop(product)
+	return nil, nil
+}
+
+// mergeStoredHistory carries the stored price history over to a freshly
+// scraped product
+func (h *ProductHandler) mergeStoredHistory(product *models.Product) {
+	if existing, err := h.storage.GetByID(product.ID); err == nil && existing != nil {
+		product.MergeHistory(existing)
+	}
 }
 
 // GetAllProducts returns all products
 // @Summary Get all products
-// @Description Get all stored products
+// @Description Get all stored products, optionally with prices converted to a target currency and sorted
 // @Tags products
 // @Produce json
+// @Param currency query string false "Target currency for converted prices (ISO 4217)"
+// @Param sort query string false "Sort key: price, rating, reviews or review_velocity; prefix with - for descending"
 // @Success 200 {object} ProductsResponse "All products"
+// @Failure 400 {object} ProductsResponse "Invalid request"
 // @Failure 500 {object} ProductsResponse "Server error"
 // @Router /api/v1/products [get]
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
+	target := c.Query("currency")
+	order := c.Query("sort")
+	if order != "" && !validSortOrder(order) {
+		c.JSON(http.StatusBadRequest, ProductsResponse{
+			Error: "Invalid sort order: " + order,
+		})
+		return
+	}
+
 	products, err := h.storage.GetAll()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ProductsResponse{
@@ -186,9 +325,21 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 		return
 	}
 
+	var converted map[string]models.Money
+	var conversionErrors map[string]string
+	if target != "" {
+		converted, conversionErrors = h.convertPrices(products, target)
+	}
+
+	if order != "" {
+		sortProducts(products, converted, order)
+	}
+
 	c.JSON(http.StatusOK, ProductsResponse{
-		Products: products,
-		Count:    len(products),
+		Products:         products,
+		ConvertedPrices:  converted,
+		ConversionErrors: conversionErrors,
+		Count:            len(products),
 	})
 }
 
@@ -198,7 +349,9 @@ func (h *ProductHandler) GetAllProducts(c *gin.Context) {
 // @Tags products
 // @Produce json
 // @Param id path string true "Product ID"
+// @Param currency query string false "Target currency for converted price and history (ISO 4217)"
 // @Success 200 {object} ProductResponse "Product information"
+// @Failure 400 {object} ProductResponse "Invalid request"
 // @Failure 404 {object} ProductResponse "Product not found"
 // @Failure 500 {object} ProductResponse "Server error"
 // @Router /api/v1/products/{id} [get]
@@ -220,7 +373,24 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, ProductResponse{
+	resp := ProductResponse{
 		Product: product,
-	})
+	}
+
+	// Convert each price point with the rate valid at its timestamp
+	if target := c.Query("currency"); target != "" {
+		price, err := h.converter.ConvertProduct(product, target)
+		if err == nil {
+			resp.ConvertedPrice = &price
+			resp.ConvertedHistory, err = h.converter.ConvertHistory(product.PriceHistory, target)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ProductResponse{
+				Error: "Failed to convert prices: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
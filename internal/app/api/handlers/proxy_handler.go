@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/proxy"
+)
+
+// ProxyStatsResponse represents the counters of the proxy pool
+type ProxyStatsResponse struct {
+	Enabled bool          `json:"enabled"`
+	Proxies []proxy.Stats `json:"proxies,omitempty"`
+}
+
+// GetProxyStats returns the counters of every proxy
+// @Summary Get proxy statistics
+// @Description Get the health, request, failure, block and ejection counters of each proxy since the server started, with requests per site
+// @Tags proxies
+// @Produce json
+// @Success 200 {object} ProxyStatsResponse "Proxy statistics"
+// @Router /api/v1/proxies [get]
+func (h *ProductHandler) GetProxyStats(c *gin.Context) {
+	pool := h.factory.Proxies()
+	if pool == nil {
+		c.JSON(http.StatusOK, ProxyStatsResponse{})
+		return
+	}
+
+	c.JSON(http.StatusOK, ProxyStatsResponse{
+		Enabled: true,
+		Proxies: pool.Stats(),
+	})
+}
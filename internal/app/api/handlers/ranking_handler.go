@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/health"
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/scraper"
+)
+
+// ScrapeRankingRequest represents a request to scrape a ranked list
+type ScrapeRankingRequest struct {
+	Website string `json:"website" binding:"required" example:"rakuten"`
+	Type    string `json:"type" example:"ranking"` // "ranking" (default) or "genre"
+	Period  string `json:"period" example:"daily"` // ranking period: realtime, daily, weekly or monthly
+	Genre   string `json:"genre" example:"100227"` // genre ID; required for genre listings
+	Limit   int    `json:"limit" example:"30"`
+}
+
+// RankingResponse represents a ranked list with rank movement
+type RankingResponse struct {
+	Source  string                `json:"source"`
+	Entries []models.RankMovement `json:"entries"`
+	Count   int                   `json:"count"`
+	Error   string                `json:"error,omitempty"`
+}
+
+// ProductRanksResponse represents the rank history of a product
+type ProductRanksResponse struct {
+	ProductID   string                `json:"product_id"`
+	RankHistory []models.RankSnapshot `json:"rank_history"`
+	Movements   []models.RankMovement `json:"movements"` // latest movement per list
+	Error       string                `json:"error,omitempty"`
+}
+
+// ScrapeRanking scrapes a best-seller ranking or genre listing
+// @Summary Scrape a ranking
+// @Description Scrape a best-seller ranking or genre listing, record each product's rank and save the products
+// @Tags rankings
+// @Accept json
+// @Produce json
+// @Param request body ScrapeRankingRequest true "Scrape Ranking Request"
+// @Success 200 {object} RankingResponse "Ranking with movement since the previous scrape"
+// @Failure 400 {object} RankingResponse "Invalid request"
+// @Failure 404 {object} RankingResponse "Scraper not found or cannot scrape rankings"
+// @Failure 500 {object} RankingResponse "Server error"
+// @Failure 503 {object} RankingResponse "Blocked by the site or its circuit is open"
+// @Router /api/v1/rankings/scrape [post]
+func (h *ProductHandler) ScrapeRanking(c *gin.Context) {
+	var req ScrapeRankingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, RankingResponse{
+			Error: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = 30
+	}
+	if req.Period == "" {
+		req.Period = "daily"
+	}
+
+	s, exists := h.factory.GetScraper(req.Website)
+	if !exists {
+		c.JSON(http.StatusNotFound, RankingResponse{
+			Error: "Scraper not found for website: " + req.Website,
+		})
+		return
+	}
+	rs, ok := s.(scraper.RankingScraper)
+	if !ok {
+		c.JSON(http.StatusNotFound, RankingResponse{
+			Error: "Rankings are not supported for website: " + req.Website,
+		})
+		return
+	}
+
+	var source string
+	var products []*models.Product
+	var err error
+	switch req.Type {
+	case "", models.RankSourceRanking:
+		source = models.RankingSource(req.Period, req.Genre)
+		products, err = rs.ScrapeRanking(req.Period, req.Genre, req.Limit)
+	case models.RankSourceGenre:
+		if req.Genre == "" {
+			c.JSON(http.StatusBadRequest, RankingResponse{
+				Error: "Invalid request: genre is required for genre listings",
+			})
+			return
+		}
+		source = models.GenreSource(req.Genre)
+		products, err = rs.ScrapeGenre(req.Genre, req.Limit)
+	default:
+		c.JSON(http.StatusBadRequest, RankingResponse{
+			Error: "Invalid ranking type: " + req.Type,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(scrapeErrorStatus(c, err), RankingResponse{
+			Error: "Failed to scrape ranking: " + err.Error(),
+		})
+		return
+	}
+
+	for _, p := range products {
+		if _, err := h.saveScraped(health.PageListing, p); err != nil {
+			log.Printf("Warning: failed to save product %s: %v", p.ID, err)
+		}
+	}
+
+	entries := models.LatestRanking(products, source)
+	c.JSON(http.StatusOK, RankingResponse{
+		Source:  source,
+		Entries: entries,
+		Count:   len(entries),
+	})
+}
+
+// GetRanking returns the latest stored state of a ranked list
+// @Summary Get a ranking
+// @Description Get the products of the latest scrape of a ranked list, ordered by rank, with movement since the previous scrape
+// @Tags rankings
+// @Produce json
+// @Param source query string true "Ranked list, e.g. ranking/daily/100227 or genre/100227"
+// @Success 200 {object} RankingResponse "Ranking"
+// @Failure 400 {object} RankingResponse "Invalid request"
+// @Failure 500 {object} RankingResponse "Server error"
+// @Router /api/v1/rankings [get]
+func (h *ProductHandler) GetRanking(c *gin.Context) {
+	source := c.Query("source")
+	if source == "" {
+		c.JSON(http.StatusBadRequest, RankingResponse{
+			Error: "Invalid request: source is required",
+		})
+		return
+	}
+
+	products, err := h.storage.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, RankingResponse{
+			Error: "Failed to get products: " + err.Error(),
+		})
+		return
+	}
+
+	entries := models.LatestRanking(products, source)
+	c.JSON(http.StatusOK, RankingResponse{
+		Source:  source,
+		Entries: entries,
+		Count:   len(entries),
+	})
+}
+
+// GetProductRanks returns the rank history of a product
+// @Summary Get product rank history
+// @Description Get the rank snapshots of a product, optionally for one ranked list, with the latest movement per list
+// @Tags products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param source query string false "Only this ranked list"
+// @Success 200 {object} ProductRanksResponse "Rank history"
+// @Failure 404 {object} ProductRanksResponse "Product not found"
+// @Failure 500 {object} ProductRanksResponse "Server error"
+// @Router /api/v1/products/{id}/ranks [get]
+func (h *ProductHandler) GetProductRanks(c *gin.Context) {
+	product, err := h.storage.GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ProductRanksResponse{
+			Error: "Failed to get product: " + err.Error(),
+		})
+		return
+	}
+
+	if product == nil {
+		c.JSON(http.StatusNotFound, ProductRanksResponse{
+			Error: "Product not found",
+		})
+		return
+	}
+
+	resp := ProductRanksResponse{
+		ProductID:   product.ID,
+		RankHistory: make([]models.RankSnapshot, 0),
+		Movements:   make([]models.RankMovement, 0),
+	}
+
+	only := c.Query("source")
+	seen := make(map[string]bool)
+	for _, rs := range product.RankHistory {
+		if only != "" && rs.Source != only {
+			continue
+		}
+		resp.RankHistory = append(resp.RankHistory, rs)
+		if !seen[rs.Source] {
+			seen[rs.Source] = true
+			if m, ok := product.RankMovement(rs.Source); ok {
+				resp.Movements = append(resp.Movements, m)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
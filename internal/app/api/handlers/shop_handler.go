@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/health"
+	"github.com/tedjuang/go-scrapy/internal/jobs"
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/scraper"
+)
+
+// defaultShopCrawlLimit caps a shop crawl when no limit is given
+const defaultShopCrawlLimit = 100
+
+// CrawlShopRequest represents a request to crawl a shop's catalog
+type CrawlShopRequest struct {
+	Website string `json:"website" example:"rakuten"` // defaults to rakuten
+	Limit   int    `json:"limit" example:"100"`
+}
+
+// ShopResponse represents the response for a shop
+type ShopResponse struct {
+	Shop  *models.Shop `json:"shop"`
+	Error string       `json:"error,omitempty"`
+}
+
+// ShopsResponse represents the response for multiple shops
+type ShopsResponse struct {
+	Shops []*models.Shop `json:"shops"`
+	Count int            `json:"count"`
+	Error string         `json:"error,omitempty"`
+}
+
+// JobResponse represents the response for a background job
+type JobResponse struct {
+	Job   *jobs.Job `json:"job"`
+	Error string    `json:"error,omitempty"`
+}
+
+// ShopCrawlResult is the result of a finished shop crawl job
+type ShopCrawlResult struct {
+	Shop  *models.Shop `json:"shop"`
+	Count int          `json:"count"` // products found and saved
+}
+
+// GetAllShops returns all known shops
+// @Summary Get all shops
+// @Description Get all shops seen while scraping or crawling
+// @Tags shops
+// @Produce json
+// @Success 200 {object} ShopsResponse "All shops"
+// @Failure 500 {object} ShopsResponse "Server error"
+// @Router /api/v1/shops [get]
+func (h *ProductHandler) GetAllShops(c *gin.Context) {
+	shops, err := h.shops.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ShopsResponse{
+			Error: "Failed to get shops: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ShopsResponse{
+		Shops: shops,
+		Count: len(shops),
+	})
+}
+
+// GetShop returns a shop by code
+// @Summary Get shop by code
+// @Description Get a shop by its code on a website
+// @Tags shops
+// @Produce json
+// @Param code path string true "Shop code"
+// @Param website query string false "Website (default rakuten)"
+// @Success 200 {object} ShopResponse "Shop information"
+// @Failure 404 {object} ShopResponse "Shop not found"
+// @Failure 500 {object} ShopResponse "Server error"
+// @Router /api/v1/shops/{code} [get]
+func (h *ProductHandler) GetShop(c *gin.Context) {
+	shop, err := h.shops.Get(c.DefaultQuery("website", "rakuten"), c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ShopResponse{
+			Error: "Failed to get shop: " + err.Error(),
+		})
+		return
+	}
+
+	if shop == nil {
+		c.JSON(http.StatusNotFound, ShopResponse{
+			Error: "Shop not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ShopResponse{
+		Shop: shop,
+	})
+}
+
+// GetShopProducts returns the stored products of a shop
+// @Summary Get shop products
+// @Description Get the stored products sold by a shop
+// @Tags shops
+// @Produce json
+// @Param code path string true "Shop code"
+// @Param website query string false "Website (default rakuten)"
+// @Success 200 {object} ProductsResponse "Shop products"
+// @Failure 500 {object} ProductsResponse "Server error"
+// @Router /api/v1/shops/{code}/products [get]
+func (h *ProductHandler) GetShopProducts(c *gin.Context) {
+	website := c.DefaultQuery("website", "rakuten")
+	code := c.Param("code")
+
+	products, err := h.storage.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ProductsResponse{
+			Error: "Failed to get products: " + err.Error(),
+		})
+		return
+	}
+
+	shopProducts := make([]*models.Product, 0)
+	for _, p := range products {
+		if p.Website == website && p.ShopCode == code {
+			shopProducts = append(shopProducts, p)
+		}
+	}
+
+	c.JSON(http.StatusOK, ProductsResponse{
+		Products: shopProducts,
+		Count:    len(shopProducts),
+	})
+}
+
+// CrawlShop starts a background crawl of a shop's catalog
+// @Summary Crawl a shop
+// @Description Start a background job that enumerates a shop's catalog and saves its products. Poll the returned job for the result.
+// @Tags shops
+// @Accept json
+// @Produce json
+// @Param code path string true "Shop code"
+// @Param request body CrawlShopRequest false "Crawl Shop Request"
+// @Success 202 {object} JobResponse "Crawl job"
+// @Failure 400 {object} JobResponse "Invalid request"
+// @Failure 404 {object} JobResponse "Scraper not found or cannot crawl shops"
+// @Router /api/v1/shops/{code}/crawl [post]
+func (h *ProductHandler) CrawlShop(c *gin.Context) {
+	var req CrawlShopRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, JobResponse{
+				Error: "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if req.Website == "" {
+		req.Website = "rakuten"
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultShopCrawlLimit
+	}
+	code := c.Param("code")
+
+	s, exists := h.factory.GetScraper(req.Website)
+	if !exists {
+		c.JSON(http.StatusNotFound, JobResponse{
+			Error: "Scraper not found for website: " + req.Website,
+		})
+		return
+	}
+	crawler, ok := s.(scraper.ShopCrawler)
+	if !ok {
+		c.JSON(http.StatusNotFound, JobResponse{
+			Error: "Shop crawling is not supported for website: " + req.Website,
+		})
+		return
+	}
+
+	job := h.jobs.Submit("shop_crawl", func() (any, error) {
+		return h.crawlShop(crawler, code, req.Limit)
+	})
+
+	c.JSON(http.StatusAccepted, JobResponse{
+		Job: &job,
+	})
+}
+
+// GetJob returns the status of a background job
+// @Summary Get job status
+// @Description Get the status and, once finished, the result of a background job
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} JobResponse "Job status"
+// @Failure 404 {object} JobResponse "Job not found"
+// @Router /api/v1/jobs/{id} [get]
+func (h *ProductHandler) GetJob(c *gin.Context) {
+	job, ok := h.jobs.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, JobResponse{
+			Error: "Job not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, JobResponse{
+		Job: &job,
+	})
+}
+
+// crawlShop runs a shop crawl and saves the shop and its products
+func (h *ProductHandler) crawlShop(crawler scraper.ShopCrawler, code string, limit int) (*ShopCrawlResult, error) {
+	shop, products, err := crawler.CrawlShop(code, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range products {
+		if _, err := h.saveScraped(health.PageListing, p); err != nil {
+			log.Printf("Warning: failed to save product %s: %v", p.ID, err)
+		}
+	}
+
+	if err := h.shops.Save(shop); err != nil {
+		return nil, fmt.Errorf("failed to save shop: %w", err)
+	}
+
+	return &ShopCrawlResult{
+		Shop:  shop,
+		Count: len(products),
+	}, nil
+}
+
+// recordShop stores a minimal entry for the shop selling product, unless the
+// shop is already known
+func (h *ProductHandler) recordShop(product *models.Product) {
+	if product.ShopCode == "" {
+		return
+	}
+	if existing, err := h.shops.Get(product.Website, product.ShopCode); err != nil || existing != nil {
+		return
+	}
+	shop := models.NewShop(product.ShopCode, "", "", product.Website)
+	if err := h.shops.Save(shop); err != nil {
+		log.Printf("Warning: failed to save shop %s: %v", shop.Code, err)
+	}
+}
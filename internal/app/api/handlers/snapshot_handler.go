@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/scraper"
+	"github.com/tedjuang/go-scrapy/internal/snapshot"
+)
+
+// SnapshotResponse represents the metadata of an archived page
+type SnapshotResponse struct {
+	Snapshot *snapshot.Meta `json:"snapshot,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// BackfillResponse represents the result of re-extracting a product's snapshots
+type BackfillResponse struct {
+	Result *scraper.BackfillResult `json:"result,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+}
+
+// GetSnapshot returns an archived page
+// @Summary Get an archived page
+// @Description Get the raw HTML of an archived page by snapshot ID as plain text, or its metadata with ?meta=true
+// @Tags snapshots
+// @Produce plain
+// @Produce json
+// @Param id path string true "Snapshot ID"
+// @Param meta query bool false "Return the metadata instead of the HTML"
+// @Success 200 {object} SnapshotResponse "Snapshot metadata"
+// @Failure 404 {object} SnapshotResponse "Snapshot not found or archive disabled"
+// @Failure 500 {object} SnapshotResponse "Server error"
+// @Router /api/v1/snapshots/{id} [get]
+func (h *ProductHandler) GetSnapshot(c *gin.Context) {
+	archive := h.factory.Archive()
+	if archive == nil {
+		c.JSON(http.StatusNotFound, SnapshotResponse{
+			Error: "Snapshot archive is disabled",
+		})
+		return
+	}
+
+	meta, html, err := archive.Load(c.Param("id"))
+	if errors.Is(err, snapshot.ErrNotFound) {
+		c.JSON(http.StatusNotFound, SnapshotResponse{
+			Error: "Snapshot not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, SnapshotResponse{
+			Error: "Failed to load snapshot: " + err.Error(),
+		})
+		return
+	}
+
+	if c.Query("meta") == "true" {
+		c.JSON(http.StatusOK, SnapshotResponse{Snapshot: meta})
+		return
+	}
+
+	// Archived pages are third-party HTML and must never run as ours
+	c.Header("X-Snapshot-URL", meta.URL)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "sandbox")
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", html)
+}
+
+// BackfillProduct re-extracts a product from its archived snapshots
+// @Summary Backfill a product from snapshots
+// @Description Re-run extraction over the archived pages of a product's price points and fill fields that are empty, without fetching anything
+// @Tags products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Success 200 {object} BackfillResponse "Backfill result"
+// @Failure 400 {object} BackfillResponse "Archive disabled or website can't re-extract"
+// @Failure 404 {object} BackfillResponse "Product not found"
+// @Failure 500 {object} BackfillResponse "Server error"
+// @Router /api/v1/products/{id}/backfill [post]
+func (h *ProductHandler) BackfillProduct(c *gin.Context) {
+	archive := h.factory.Archive()
+	if archive == nil {
+		c.JSON(http.StatusBadRequest, BackfillResponse{
+			Error: "Snapshot archive is disabled",
+		})
+		return
+	}
+
+	product, err := h.storage.GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, BackfillResponse{
+			Error: "Failed to get product: " + err.Error(),
+		})
+		return
+	}
+	if product == nil {
+		c.JSON(http.StatusNotFound, BackfillResponse{
+			Error: "Product not found",
+		})
+		return
+	}
+
+	s, _ := h.factory.GetScraper(product.Website)
+	extractor, ok := s.(scraper.Extractor)
+	if !ok {
+		c.JSON(http.StatusBadRequest, BackfillResponse{
+			Error: "Website can't re-extract snapshots: " + product.Website,
+		})
+		return
+	}
+
+	result, err := scraper.Backfill(extractor, archive, product)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, BackfillResponse{
+			Error: "Failed to backfill product: " + err.Error(),
+		})
+		return
+	}
+
+	if len(result.Filled) > 0 {
+		if err := h.storage.Save(product); err != nil {
+			c.JSON(http.StatusInternalServerError, BackfillResponse{
+				Error: "Failed to save product: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, BackfillResponse{Result: &result})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tedjuang/go-scrapy/internal/config"
+)
+
+func TestGetSnapshotServesPlainText(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Data.Dir = t.TempDir()
+	cfg.Scraping.Archive.Enabled = true
+	h, err := NewProductHandler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := "<html><script>alert(document.cookie)</script></html>"
+	id, err := h.factory.Archive().Save("https://item.rakuten.co.jp/shop/item/", "rakuten", []byte(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/api/v1/snapshots/:id", h.GetSnapshot)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/snapshots/"+id, nil))
+
+	if w.Code != http.StatusOK || w.Body.String() != html {
+		t.Fatalf("Expected the archived HTML, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Expected archived HTML served as plain text, got %q", ct)
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" || w.Header().Get("Content-Security-Policy") != "sandbox" {
+		t.Errorf("Expected nosniff and sandbox headers, got %v", w.Header())
+	}
+}
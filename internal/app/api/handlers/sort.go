@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// reviewVelocityWindow is the period over which review velocity is measured
+const reviewVelocityWindow = 30 * 24 * time.Hour
+
+// productLess compares two products for a sort key. converted holds prices
+// converted to a target currency, keyed by product ID, and may be nil.
+type productLess func(a, b *models.Product, converted map[string]models.Money) bool
+
+// productOrders lists the sort keys accepted by list endpoints. Prefix a key
+// with "-" for descending order.
+var productOrders = map[string]productLess{
+	"price": func(a, b *models.Product, converted map[string]models.Money) bool {
+		return models.LessPrice(priceOf(a, converted), priceOf(b, converted))
+	},
+	"rating": func(a, b *models.Product, _ map[string]models.Money) bool {
+		return a.Rating < b.Rating
+	},
+	"reviews": func(a, b *models.Product, _ map[string]models.Money) bool {
+		return a.ReviewCount < b.ReviewCount
+	},
+	"review_velocity": func(a, b *models.Product, _ map[string]models.Money) bool {
+		return a.ReviewVelocity(reviewVelocityWindow) < b.ReviewVelocity(reviewVelocityWindow)
+	},
+}
+
+// validSortOrder reports whether order is a known sort key
+func validSortOrder(order string) bool {
+	_, ok := productOrders[strings.TrimPrefix(order, "-")]
+	return ok
+}
+
+// sortProducts orders products by the given sort key
+func sortProducts(products []*models.Product, converted map[string]models.Money, order string) {
+	less := productOrders[strings.TrimPrefix(order, "-")]
+	desc := strings.HasPrefix(order, "-")
+
+	sort.SliceStable(products, func(i, j int) bool {
+		if desc {
+			return less(products[j], products[i], converted)
+		}
+		return less(products[i], products[j], converted)
+	})
+}
+
+// priceOf returns a product's converted price if available, otherwise its own price
+func priceOf(p *models.Product, converted map[string]models.Money) models.Money {
+	if m, ok := converted[p.ID]; ok {
+		return m
+	}
+	return p.CurrentPrice
+}
@@ -6,19 +6,21 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/tedjuang/go-scrapy/internal/app/api/handlers"
 	"github.com/tedjuang/go-scrapy/internal/app/api/middlewares"
+	"github.com/tedjuang/go-scrapy/internal/config"
 )
 
-// SetupRouter sets up the router with all API routes
-func SetupRouter(dataDir string) (*gin.Engine, error) {
+// SetupRouter sets up the router with all API routes. The returned function
+// stops the handlers' background work once the server is shut down.
+func SetupRouter(cfg *config.Config) (*gin.Engine, func(), error) {
 	r := gin.Default()
 
 	// Add middleware
 	r.Use(middlewares.Logger())
 
 	// Create product handler
-	handler, err := handlers.NewProductHandler(dataDir)
+	handler, err := handlers.NewProductHandler(cfg)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// API v1 routes
@@ -28,8 +30,63 @@ func SetupRouter(dataDir string) (*gin.Engine, error) {
 		{
 			products.GET("", handler.GetAllProducts)
 			products.GET("/:id", handler.GetProduct)
+			products.GET("/:id/history", handler.GetProductHistory)
+			products.GET("/:id/ranks", handler.GetProductRanks)
+			products.GET("/:id/images", handler.GetProductImages)
+			products.GET("/:id/images/:image", handler.GetProductImage)
 			products.POST("/scrape", handler.ScrapeProduct)
 			products.POST("/search", handler.SearchProducts)
+			products.POST("/scrape/batch", handler.BatchScrapeProducts)
+			products.POST("/discover", handler.DiscoverProducts)
+			products.POST("/:id/backfill", handler.BackfillProduct)
+		}
+
+		items := v1.Group("/items")
+		{
+			items.GET("/:id/offers", handler.GetItemOffers)
+		}
+
+		shops := v1.Group("/shops")
+		{
+			shops.GET("", handler.GetAllShops)
+			shops.GET("/:code", handler.GetShop)
+			shops.GET("/:code/products", handler.GetShopProducts)
+			shops.POST("/:code/crawl", handler.CrawlShop)
+		}
+
+		rankings := v1.Group("/rankings")
+		{
+			rankings.GET("", handler.GetRanking)
+			rankings.POST("/scrape", handler.ScrapeRanking)
+		}
+
+		scrapers := v1.Group("/scrapers")
+		{
+			scrapers.GET("/health", handler.GetScraperHealth)
+			scrapers.POST("/:website/extract", handler.ExtractPage)
+		}
+
+		quarantine := v1.Group("/quarantine")
+		{
+			quarantine.GET("", handler.GetQuarantine)
+			quarantine.POST("/:id/approve", handler.ApproveQuarantined)
+			quarantine.DELETE("/:id", handler.RejectQuarantined)
+		}
+
+		v1.GET("/crawl-policy/skipped", handler.GetSkippedURLs)
+		v1.GET("/cache/stats", handler.GetCacheStats)
+		v1.GET("/proxies", handler.GetProxyStats)
+		v1.GET("/snapshots/:id", handler.GetSnapshot)
+
+		circuits := v1.Group("/circuits")
+		{
+			circuits.GET("", handler.GetCircuits)
+			circuits.POST("/:domain/reset", handler.ResetCircuit)
+		}
+
+		jobs := v1.Group("/jobs")
+		{
+			jobs.GET("/:id", handler.GetJob)
 		}
 	}
 
@@ -48,5 +105,5 @@ func SetupRouter(dataDir string) (*gin.Engine, error) {
 		c.Redirect(301, "/swagger/index.html")
 	})
 
-	return r, nil
+	return r, handler.Close, nil
 }
@@ -5,35 +5,43 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/tedjuang/go-scrapy/internal/app/api/routes"
+	"github.com/tedjuang/go-scrapy/internal/config"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	server  *http.Server
-	dataDir string
+	server *http.Server
+	cfg    *config.Config
+
+	mutex sync.Mutex
+	close func() // stops the handlers' background work, once started
 }
 
 // NewServer creates a new HTTP server
-func NewServer(addr, dataDir string) *Server {
+func NewServer(addr string, cfg *config.Config) *Server {
 	return &Server{
 		server: &http.Server{
 			Addr: addr,
 		},
-		dataDir: dataDir,
+		cfg: cfg,
 	}
 }
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
-	router, err := routes.SetupRouter(s.dataDir)
+	router, closeHandlers, err := routes.SetupRouter(s.cfg)
 	if err != nil {
 		return err
 	}
 
+	s.mutex.Lock()
 	s.server.Handler = router
+	s.close = closeHandlers
+	s.mutex.Unlock()
 
 	log.Printf("Starting server on %s\n", s.server.Addr)
 	return s.server.ListenAndServe()
@@ -42,7 +50,14 @@ func (s *Server) Start() error {
 // Stop stops the HTTP server
 func (s *Server) Stop(ctx context.Context) error {
 	log.Println("Shutting down server...")
-	return s.server.Shutdown(ctx)
+	err := s.server.Shutdown(ctx)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.close != nil {
+		s.close()
+	}
+	return err
 }
 
 // GracefulShutdown gracefully shuts down the server with a timeout
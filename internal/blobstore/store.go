@@ -0,0 +1,187 @@
+// Package blobstore is the content-addressed file store behind the snapshot
+// archive and the image store. Files are named after the SHA-256 of their
+// content, so identical downloads share one file, and each has a JSON
+// metadata file recording when it was first and last seen.
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Seen spans the downloads that returned a file. It is embedded in the
+// metadata of the stores using the package.
+type Seen struct {
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// See records a download at now
+func (s *Seen) See(now time.Time) {
+	if s.FirstSeen.IsZero() {
+		s.FirstSeen = now
+	}
+	s.LastSeen = now
+}
+
+// seen returns the span itself, for pruning
+func (s *Seen) seen() *Seen {
+	return s
+}
+
+// Meta is the metadata of a stored file: a struct embedding Seen
+type Meta interface {
+	See(now time.Time)
+	seen() *Seen
+}
+
+// Store keeps files under a directory as <id[:2]>/<id><suffix>, with a
+// <id>.json metadata file next to each
+type Store struct {
+	dir      string
+	kind     string // names the files in errors, e.g. "snapshot"
+	notFound error
+	mutex    sync.Mutex
+}
+
+// New creates a store under dir whose lookups of unknown files fail with
+// notFound. kind names the files in errors.
+func New(dir, kind string, notFound error) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s directory: %w", kind, err)
+	}
+	return &Store{dir: dir, kind: kind, notFound: notFound}, nil
+}
+
+// ID returns the content address of data
+func ID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidID reports whether id looks like a content address, so that IDs from
+// requests can't address files outside the store
+func ValidID(id string) bool {
+	if len(id) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// Path returns the file of id with the given suffix, e.g. ".html.gz"
+func (s *Store) Path(id, suffix string) string {
+	return filepath.Join(s.dir, id[:2], id+suffix)
+}
+
+// Save records a download of the file id at now. The stored metadata is read
+// into meta; for a new file, create fills meta and writes the file instead,
+// with the directory of Path in place. Either way meta is saved seen at now.
+func (s *Store) Save(id string, now time.Time, meta Meta, create func() error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.readMeta(id, meta)
+	if errors.Is(err, s.notFound) {
+		if err := os.MkdirAll(filepath.Dir(s.Path(id, "")), 0755); err != nil {
+			return fmt.Errorf("failed to create %s directory: %w", s.kind, err)
+		}
+		if err := create(); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	meta.See(now)
+	return s.writeMeta(id, meta)
+}
+
+// ReadMeta reads the metadata of id into meta, failing with the store's
+// not-found error for unknown or invalid IDs
+func (s *Store) ReadMeta(id string, meta Meta) error {
+	if !ValidID(id) {
+		return s.notFound
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.readMeta(id, meta)
+}
+
+// ReadFile reads the file of id with the given suffix
+func (s *Store) ReadFile(id, suffix string) ([]byte, error) {
+	data, err := os.ReadFile(s.Path(id, suffix))
+	if os.IsNotExist(err) {
+		return nil, s.notFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s %s: %w", s.kind, id, err)
+	}
+	return data, nil
+}
+
+// Prune deletes the files last seen before cutoff, with each of the given
+// suffixes and their metadata, and returns how many were removed. newMeta
+// returns an empty metadata value to read them into.
+func (s *Store) Prune(cutoff time.Time, newMeta func() Meta, suffixes ...string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	metas, err := filepath.Glob(filepath.Join(s.dir, "*", "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range metas {
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		meta := newMeta()
+		if err := s.readMeta(id, meta); err != nil || !meta.seen().LastSeen.Before(cutoff) {
+			continue
+		}
+		for _, suffix := range suffixes {
+			if err := os.Remove(s.Path(id, suffix)); err != nil && !os.IsNotExist(err) {
+				return removed, fmt.Errorf("failed to remove %s %s: %w", s.kind, id, err)
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s %s: %w", s.kind, id, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (s *Store) readMeta(id string, meta Meta) error {
+	data, err := os.ReadFile(s.Path(id, ".json"))
+	if os.IsNotExist(err) {
+		return s.notFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s metadata: %w", s.kind, err)
+	}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return fmt.Errorf("failed to parse %s metadata: %w", s.kind, err)
+	}
+	return nil
+}
+
+func (s *Store) writeMeta(id string, meta Meta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s metadata: %w", s.kind, err)
+	}
+	if err := os.WriteFile(s.Path(id, ".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s metadata: %w", s.kind, err)
+	}
+	return nil
+}
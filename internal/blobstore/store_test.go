@@ -0,0 +1,62 @@
+package blobstore
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+var errTestNotFound = errors.New("blob not found")
+
+// testMeta is the metadata of a test blob
+type testMeta struct {
+	Name string `json:"name"`
+	Seen
+}
+
+func TestSaveAndPrune(t *testing.T) {
+	store, err := New(t.TempDir(), "blob", errTestNotFound)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	save := func(data string, now time.Time) (*testMeta, int) {
+		t.Helper()
+		id, created := ID([]byte(data)), 0
+		meta := &testMeta{}
+		err := store.Save(id, now, meta, func() error {
+			created++
+			meta.Name = data
+			return os.WriteFile(store.Path(id, ".txt"), []byte(data), 0644)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return meta, created
+	}
+
+	// A second download of the same content only extends LastSeen
+	if _, created := save("a", day); created != 1 {
+		t.Errorf("Expected the file created, got %d", created)
+	}
+	meta, created := save("a", day.AddDate(0, 0, 1))
+	if created != 0 || meta.Name != "a" || !meta.FirstSeen.Equal(day) || !meta.LastSeen.Equal(day.AddDate(0, 0, 1)) {
+		t.Errorf("Expected the stored file seen again, got %+v (%d created)", meta, created)
+	}
+	save("b", day.AddDate(0, 0, 3))
+
+	n, err := store.Prune(day.AddDate(0, 0, 2), func() Meta { return &testMeta{} }, ".txt")
+	if err != nil || n != 1 {
+		t.Fatalf("Expected one file pruned, got %d, %v", n, err)
+	}
+	if err := store.ReadMeta(ID([]byte("a")), &testMeta{}); !errors.Is(err, errTestNotFound) {
+		t.Errorf("Expected the pruned file gone, got %v", err)
+	}
+	if data, err := store.ReadFile(ID([]byte("b")), ".txt"); err != nil || string(data) != "b" {
+		t.Errorf("Expected the recent file kept, got %q, %v", data, err)
+	}
+	if err := store.ReadMeta("../../etc/passwd", &testMeta{}); !errors.Is(err, errTestNotFound) {
+		t.Errorf("Expected an invalid ID refused, got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package block
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+var testDetector = Detector{
+	Statuses:    DefaultStatuses,
+	Markers:     []string{"g-recaptcha", "アクセスが集中"},
+	MinBodySize: 100,
+}
+
+func TestDetect(t *testing.T) {
+	page := "<html><body>" + strings.Repeat("商品 ", 50) + "</body></html>"
+
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		blocked     bool
+	}{
+		{"normal page", 200, "text/html", page, false},
+		{"not found", 404, "text/html", page, false},
+		{"rate limited", 429, "text/html", page, true},
+		{"captcha", 200, "text/html", strings.Replace(page, "商品", `<div class="G-RECAPTCHA">`, 1), true},
+		{"japanese marker", 200, "text/html", strings.Replace(page, "商品", "只今アクセスが集中しています", 1), true},
+		{"small page", 200, "text/html; charset=utf-8", "<html></html>", true},
+		{"small JSON", 200, "application/json", "{}", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, blocked := testDetector.Detect(tt.status, tt.contentType, []byte(tt.body))
+			if blocked != tt.blocked {
+				t.Errorf("Expected blocked=%t, got %t (%s)", tt.blocked, blocked, reason)
+			}
+			if blocked && reason == "" {
+				t.Error("Expected a reason for the block")
+			}
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	clock := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(Config{Threshold: 2, CoolDown: time.Minute, MaxCoolDown: 3 * time.Minute})
+	b.now = func() time.Time { return clock }
+
+	// A success in between resets the count
+	b.RecordBlock("shop.example", "status 403")
+	b.RecordSuccess("shop.example")
+	if until := b.RecordBlock("shop.example", "status 403"); !until.IsZero() {
+		t.Fatalf("Expected the circuit closed, open until %s", until)
+	}
+	until := b.RecordBlock("shop.example", "status 429")
+	if !until.Equal(clock.Add(time.Minute)) {
+		t.Fatalf("Expected the circuit open for a minute, got %s", until)
+	}
+	if _, open := b.Open("shop.example"); !open {
+		t.Error("Expected the circuit open")
+	}
+	if _, open := b.Open("other.example"); open {
+		t.Error("Expected other domains unaffected")
+	}
+
+	// Half-open after the cool-down: one block reopens for twice as long
+	clock = clock.Add(time.Minute)
+	if _, open := b.Open("shop.example"); open {
+		t.Error("Expected requests let through after the cool-down")
+	}
+	if s := b.States()[0]; s.State != StateHalfOpen {
+		t.Errorf("Expected half-open, got %s", s.State)
+	}
+	if until := b.RecordBlock("shop.example", "status 429"); !until.Equal(clock.Add(2 * time.Minute)) {
+		t.Errorf("Expected a doubled cool-down, open until %s", until)
+	}
+
+	// The cool-down is capped
+	clock = clock.Add(2 * time.Minute)
+	if until := b.RecordBlock("shop.example", "status 429"); !until.Equal(clock.Add(3 * time.Minute)) {
+		t.Errorf("Expected the capped cool-down, open until %s", until)
+	}
+
+	// A success while half-open closes the circuit and resets the backoff
+	clock = clock.Add(3 * time.Minute)
+	b.RecordSuccess("shop.example")
+	s := b.States()[0]
+	if s.State != StateClosed || s.Trips != 0 || s.Blocks != 5 || s.LastReason != "status 429" {
+		t.Errorf("Unexpected state after recovery: %+v", s)
+	}
+}
+
+func TestBreakerReset(t *testing.T) {
+	b := NewBreaker(Config{Threshold: 1})
+	b.RecordBlock("shop.example", "status 403")
+
+	if !b.Reset("shop.example") || b.Reset("other.example") {
+		t.Error("Expected only the known domain reset")
+	}
+	if _, open := b.Open("shop.example"); open {
+		t.Error("Expected the circuit closed after a reset")
+	}
+}
+
+// blockCounter counts the block pages reported to it by status
+type blockCounter map[int]int
+
+func (c blockCounter) RecordBlock(status int, header http.Header) {
+	c[status]++
+}
+
+func TestGuard(t *testing.T) {
+	status := http.StatusOK
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "<html><body>%s</body></html>", strings.Repeat("item ", 50))
+	}))
+	defer server.Close()
+
+	reported := blockCounter{}
+	guard := Guard{Domain: "shop.example", Detector: testDetector, Breaker: NewBreaker(Config{Threshold: 2}), Proxies: reported}
+	visit := func() error {
+		c := colly.NewCollector(colly.AllowURLRevisit())
+		w := guard.Attach(c)
+		c.Visit(server.URL)
+		return w.Err()
+	}
+
+	if err := visit(); err != nil {
+		t.Fatalf("Expected no block, got %v", err)
+	}
+
+	status = http.StatusTooManyRequests
+	err := visit()
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || !errors.Is(err, ErrBlocked) || blocked.Reason != "status 429" || !blocked.Until.IsZero() {
+		t.Fatalf("Expected a 429 block with the circuit closed, got %v", err)
+	}
+
+	// The second block opens the circuit and later requests aren't sent
+	if err := visit(); !errors.As(err, &blocked) || blocked.Until.IsZero() {
+		t.Fatalf("Expected the circuit opened, got %v", err)
+	}
+	sent := requests
+	if err := visit(); !errors.As(err, &blocked) || blocked.Reason != "circuit open" {
+		t.Errorf("Expected the circuit open, got %v", err)
+	}
+	if requests != sent {
+		t.Error("Expected no request while the circuit is open")
+	}
+	if len(reported) != 1 || reported[http.StatusTooManyRequests] != 2 {
+		t.Errorf("Expected both block pages reported to the proxies, got %v", reported)
+	}
+}
+
+// replayCache treats responses with a Replayed header as cache hits and
+// records the evicted URLs
+type replayCache struct {
+	evicted []string
+}
+
+func (c *replayCache) Replayed(header http.Header) bool {
+	return header.Get("Replayed") != ""
+}
+
+func (c *replayCache) Evict(u *url.URL) {
+	c.evicted = append(c.evicted, u.Path)
+}
+
+func TestGuardEvictsCachedBlocks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/replayed" {
+			w.Header().Set("Replayed", "1")
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><div class="g-recaptcha"></div></html>`)
+	}))
+	defer server.Close()
+
+	cache := &replayCache{}
+	reported := blockCounter{}
+	breaker := NewBreaker(Config{Threshold: 1})
+	guard := Guard{Domain: "shop.example", Detector: testDetector, Breaker: breaker, Proxies: reported, Cache: cache}
+	visit := func(path string) error {
+		c := colly.NewCollector(colly.AllowURLRevisit())
+		w := guard.Attach(c)
+		c.Visit(server.URL + path)
+		return w.Err()
+	}
+
+	// A replayed block page is evicted and reported but not counted again
+	if err := visit("/replayed"); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("Expected the cached block page reported, got %v", err)
+	}
+	if _, open := breaker.Open("shop.example"); open || len(reported) != 0 {
+		t.Errorf("Expected a cached block page not counted, reported %v", reported)
+	}
+
+	// A fetched one is evicted and counted
+	if err := visit("/fetched"); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("Expected the block page reported, got %v", err)
+	}
+	if _, open := breaker.Open("shop.example"); !open || reported[http.StatusOK] != 1 {
+		t.Errorf("Expected the block page counted, reported %v", reported)
+	}
+	if strings.Join(cache.evicted, ",") != "/replayed,/fetched" {
+		t.Errorf("Expected both pages evicted, got %v", cache.evicted)
+	}
+}
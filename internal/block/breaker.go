@@ -0,0 +1,201 @@
+package block
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Circuit states
+const (
+	StateClosed   = "closed"    // requests go through
+	StateOpen     = "open"      // requests are skipped until the cool-down ends
+	StateHalfOpen = "half-open" // the cool-down ended; the next response closes or reopens the circuit
+)
+
+// Defaults of a breaker
+const (
+	DefaultThreshold   = 3
+	DefaultCoolDown    = 10 * time.Minute
+	DefaultMaxCoolDown = 2 * time.Hour
+)
+
+// Config configures a Breaker
+type Config struct {
+	Threshold   int           // consecutive blocks opening a circuit; defaults to DefaultThreshold
+	CoolDown    time.Duration // first pause of a domain, doubled each time it reopens; defaults to DefaultCoolDown
+	MaxCoolDown time.Duration // longest pause; defaults to DefaultMaxCoolDown
+}
+
+// State is the circuit of one domain
+type State struct {
+	Domain      string     `json:"domain"`
+	State       string     `json:"state"`
+	Consecutive int        `json:"consecutive_blocks"`
+	Blocks      int64      `json:"blocks"` // since the process started
+	Trips       int        `json:"trips"`  // openings since the circuit last closed
+	OpenUntil   *time.Time `json:"open_until,omitempty"`
+	LastBlock   *time.Time `json:"last_block,omitempty"`
+	LastReason  string     `json:"last_reason,omitempty"`
+}
+
+// circuit is the breaker state of one domain
+type circuit struct {
+	consecutive int
+	blocks      int64
+	trips       int
+	openUntil   time.Time // zero when closed; in the past when half-open
+	lastBlock   time.Time
+	lastReason  string
+}
+
+// Breaker pauses domains after repeated blocks. It is safe for concurrent
+// use and is shared by all scrapers of a process.
+type Breaker struct {
+	cfg Config
+
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+
+	now func() time.Time
+}
+
+// NewBreaker creates a circuit breaker
+func NewBreaker(cfg Config) *Breaker {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = DefaultThreshold
+	}
+	if cfg.CoolDown <= 0 {
+		cfg.CoolDown = DefaultCoolDown
+	}
+	if cfg.MaxCoolDown < cfg.CoolDown {
+		cfg.MaxCoolDown = max(DefaultMaxCoolDown, cfg.CoolDown)
+	}
+	return &Breaker{
+		cfg:      cfg,
+		circuits: make(map[string]*circuit),
+		now:      time.Now,
+	}
+}
+
+// circuit returns the circuit of domain, creating it. The caller holds the
+// mutex.
+func (b *Breaker) circuit(domain string) *circuit {
+	c, ok := b.circuits[domain]
+	if !ok {
+		c = &circuit{}
+		b.circuits[domain] = c
+	}
+	return c
+}
+
+// Open reports whether the circuit of domain is open, and until when
+func (b *Breaker) Open(domain string) (time.Time, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c, ok := b.circuits[domain]
+	if ok && b.now().Before(c.openUntil) {
+		return c.openUntil, true
+	}
+	return time.Time{}, false
+}
+
+// RecordBlock counts a block page of domain and opens its circuit after too
+// many in a row, or at once while half-open. It returns when the circuit
+// closes again, or zero if it isn't open.
+func (b *Breaker) RecordBlock(domain, reason string) time.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+	c := b.circuit(domain)
+	c.blocks++
+	c.consecutive++
+	c.lastBlock = now
+	c.lastReason = reason
+
+	if now.Before(c.openUntil) {
+		// A response to a request sent before the circuit opened
+		return c.openUntil
+	}
+	halfOpen := !c.openUntil.IsZero()
+	if c.consecutive < b.cfg.Threshold && !halfOpen {
+		return time.Time{}
+	}
+
+	c.trips++
+	coolDown := b.cfg.CoolDown
+	for i := 1; i < c.trips && coolDown < b.cfg.MaxCoolDown; i++ {
+		coolDown *= 2
+	}
+	c.openUntil = now.Add(min(coolDown, b.cfg.MaxCoolDown))
+	c.consecutive = 0
+	return c.openUntil
+}
+
+// RecordSuccess counts a normal response of domain, closing its circuit if
+// half-open
+func (b *Breaker) RecordSuccess(domain string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c, ok := b.circuits[domain]
+	if !ok {
+		return
+	}
+	c.consecutive = 0
+	if !c.openUntil.IsZero() && !b.now().Before(c.openUntil) {
+		c.openUntil = time.Time{}
+		c.trips = 0
+	}
+}
+
+// Reset closes the circuit of domain, reporting whether it was known
+func (b *Breaker) Reset(domain string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	c, ok := b.circuits[domain]
+	if ok {
+		c.consecutive = 0
+		c.trips = 0
+		c.openUntil = time.Time{}
+	}
+	return ok
+}
+
+// States returns the circuit of every domain that has been blocked, sorted
+// by domain
+func (b *Breaker) States() []State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+	states := make([]State, 0, len(b.circuits))
+	for domain, c := range b.circuits {
+		s := State{
+			Domain:      domain,
+			State:       StateClosed,
+			Consecutive: c.consecutive,
+			Blocks:      c.blocks,
+			Trips:       c.trips,
+			LastReason:  c.lastReason,
+		}
+		if !c.openUntil.IsZero() {
+			s.State = StateHalfOpen
+			if now.Before(c.openUntil) {
+				s.State = StateOpen
+			}
+			until := c.openUntil
+			s.OpenUntil = &until
+		}
+		if !c.lastBlock.IsZero() {
+			last := c.lastBlock
+			s.LastBlock = &last
+		}
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Domain < states[j].Domain })
+	return states
+}
@@ -0,0 +1,169 @@
+// Package block recognizes block and captcha pages served instead of the
+// requested page and pauses a domain that keeps serving them, so scrapers
+// back off instead of hammering a site that has turned them away.
+package block
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// ErrBlocked is matched by every error reporting a block page or an open
+// circuit
+var ErrBlocked = errors.New("blocked by site")
+
+// BlockedError reports that a site served a block page, or that its circuit
+// is open and the request wasn't sent
+type BlockedError struct {
+	Domain string
+	URL    string
+	Reason string    // e.g. "status 429" or "circuit open"
+	Until  time.Time // when the domain's circuit lets requests through again, zero if it's closed
+}
+
+// Error describes the block
+func (e *BlockedError) Error() string {
+	msg := fmt.Sprintf("%v: %s (%s)", ErrBlocked, e.URL, e.Reason)
+	if !e.Until.IsZero() {
+		msg += fmt.Sprintf(", paused until %s", e.Until.Format(time.RFC3339))
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrBlocked) match
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// DefaultStatuses are the statuses sites answer blocked clients with
+var DefaultStatuses = []int{http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable}
+
+// Detector recognizes the block pages of a site
+type Detector struct {
+	Statuses    []int    // statuses meaning a block
+	Markers     []string // text found on block and captcha pages, matched case-insensitively
+	MinBodySize int      // successful HTML responses shorter than this are block pages; 0 disables
+}
+
+// Detect reports whether a response is a block page and why
+func (d Detector) Detect(status int, contentType string, body []byte) (string, bool) {
+	for _, s := range d.Statuses {
+		if status == s {
+			return fmt.Sprintf("status %d", status), true
+		}
+	}
+
+	lower := bytes.ToLower(body)
+	for _, marker := range d.Markers {
+		if bytes.Contains(lower, []byte(strings.ToLower(marker))) {
+			return fmt.Sprintf("block page marker %q", marker), true
+		}
+	}
+
+	if d.MinBodySize > 0 && status < http.StatusMultipleChoices && strings.Contains(contentType, "html") && len(body) < d.MinBodySize {
+		return fmt.Sprintf("body of %d bytes", len(body)), true
+	}
+	return "", false
+}
+
+// ProxyReporter counts block pages against the proxy that fetched them
+type ProxyReporter interface {
+	RecordBlock(status int, header http.Header)
+}
+
+// Cache is the response cache behind a site's collectors
+type Cache interface {
+	Replayed(header http.Header) bool // served without a request
+	Evict(u *url.URL)
+}
+
+// Guard applies a site's detector and circuit breaker to its collectors
+type Guard struct {
+	Domain   string // circuit key, e.g. "rakuten.co.jp"
+	Detector Detector
+	Breaker  *Breaker
+	Proxies  ProxyReporter // told of every block page if set
+	Cache    Cache         // block pages are evicted from it if set
+}
+
+// Watch collects the first block seen by a collector
+type Watch struct {
+	mutex sync.Mutex
+	err   *BlockedError
+}
+
+// Err returns the first block seen, as a *BlockedError, or nil
+func (w *Watch) Err() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.err == nil {
+		return nil
+	}
+	return w.err
+}
+
+// set records err unless a block was already seen
+func (w *Watch) set(err *BlockedError) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// Attach makes collector c skip requests while the domain's circuit is open
+// and check every response for a block page, counting blocks against the
+// circuit. Like the crawl policy, collectors created with Clone must be
+// attached again.
+func (g Guard) Attach(c *colly.Collector) *Watch {
+	w := &Watch{}
+
+	c.OnRequest(func(r *colly.Request) {
+		if until, open := g.Breaker.Open(g.Domain); open {
+			w.set(&BlockedError{Domain: g.Domain, URL: r.URL.String(), Reason: "circuit open", Until: until})
+			r.Abort()
+		}
+	})
+
+	check := func(r *colly.Response) {
+		reason, blocked := g.Detector.Detect(r.StatusCode, r.Headers.Get("Content-Type"), r.Body)
+		if !blocked {
+			g.Breaker.RecordSuccess(g.Domain)
+			return
+		}
+		// A block page replayed from the cache was counted when it was
+		// fetched; only drop it so the next visit reaches the site
+		if g.Cache != nil {
+			g.Cache.Evict(r.Request.URL)
+			if r.Headers != nil && g.Cache.Replayed(*r.Headers) {
+				until, _ := g.Breaker.Open(g.Domain)
+				w.set(&BlockedError{Domain: g.Domain, URL: r.Request.URL.String(), Reason: reason + " (cached)", Until: until})
+				return
+			}
+		}
+		until := g.Breaker.RecordBlock(g.Domain, reason)
+		if g.Proxies != nil && r.Headers != nil {
+			g.Proxies.RecordBlock(r.StatusCode, *r.Headers)
+		}
+		w.set(&BlockedError{Domain: g.Domain, URL: r.Request.URL.String(), Reason: reason, Until: until})
+	}
+
+	c.OnResponse(check)
+	c.OnError(func(r *colly.Response, err error) {
+		// Without a status the site wasn't reached; the proxies handle that
+		if r.StatusCode != 0 {
+			check(r)
+		}
+	})
+	return w
+}
@@ -20,25 +20,138 @@ type Config struct {
 	} `json:"data"`
 
 	Scraping struct {
-		UserAgent string `json:"userAgent"`
-		Timeout   int    `json:"timeout"`
-		Retries   int    `json:"retries"`
+		UserAgent         string                  `json:"userAgent"`
+		Timeout           int                     `json:"timeout"`
+		Retries           int                     `json:"retries"`
+		RequestsPerMinute int                     `json:"requestsPerMinute"` // global budget across sites, 0 for unlimited
+		IgnoreRobotsTxt   bool                    `json:"ignoreRobotsTxt"`
+		Sites             map[string]SiteScraping `json:"sites"` // keyed by website, e.g. "rakuten"
+		Cache             struct {
+			Enabled    bool   `json:"enabled"`
+			Dir        string `json:"dir"`        // defaults to <data dir>/cache
+			TTLSeconds int    `json:"ttlSeconds"` // default TTL for sites without their own
+		} `json:"cache"`
+		Archive struct {
+			Enabled       bool   `json:"enabled"`
+			Dir           string `json:"dir"`           // defaults to <data dir>/snapshots
+			RetentionDays int    `json:"retentionDays"` // snapshots unseen for longer are pruned, 0 keeps them
+		} `json:"archive"`
+		Images struct {
+			Enabled       bool   `json:"enabled"`
+			Dir           string `json:"dir"`           // defaults to <data dir>/images
+			ThumbnailSize int    `json:"thumbnailSize"` // longest side of thumbnails in pixels, default 200
+			MaxBytes      int64  `json:"maxBytes"`      // largest image downloaded, default 10 MiB
+		} `json:"images"`
+		Headers struct {
+			Profiles []HeaderProfile `json:"profiles"` // none sends userAgent with default Accept headers
+			Rotation string          `json:"rotation"` // fixed (default), round-robin or random
+		} `json:"headers"`
+		Proxies struct {
+			URLs                 []string `json:"urls"`                 // http, https or socks5 proxies; none fetches directly
+			Strategy             string   `json:"strategy"`             // round-robin (default) or lru
+			MaxFailures          int      `json:"maxFailures"`          // consecutive failures or blocks before a proxy is ejected
+			EjectSeconds         int      `json:"ejectSeconds"`         // how long an ejected proxy sits out
+			CheckURL             string   `json:"checkURL"`             // fetched through each proxy by health checks
+			CheckIntervalSeconds int      `json:"checkIntervalSeconds"` // 0 disables periodic health checks
+		} `json:"proxies"`
+		Render struct {
+			URL            string `json:"url"`            // rendering service of sites with the render fetcher, e.g. http://localhost:9222/render
+			TimeoutSeconds int    `json:"timeoutSeconds"` // per page
+			WaitMs         int    `json:"waitMs"`         // how long scripts may run after a page loaded
+		} `json:"render"`
+		// Product lookups through the API lack what only pages provide:
+		// variants, JAN/ISBN, the cleaned description and attributes,
+		// snapshots and JSON requests. Saved products keep the stored ones.
+		RakutenAPI struct {
+			ApplicationID string `json:"applicationId"` // Rakuten Web Service application ID; empty scrapes Rakuten's pages only
+			AffiliateID   string `json:"affiliateId"`   // optional
+			Endpoint      string `json:"endpoint"`      // defaults to the Ichiba Item Search API
+			IntervalMs    int    `json:"intervalMs"`    // between API requests, defaulting to a second
+		} `json:"rakutenApi"`
+		Session struct {
+			Enabled     bool   `json:"enabled"`
+			Dir         string `json:"dir"`         // defaults to <data dir>/sessions
+			WarmUpHours int    `json:"warmUpHours"` // how often scrapers rerun their warm-up steps, 0 for new sessions only
+		} `json:"session"`
+		Circuit struct {
+			Threshold          int `json:"threshold"`          // consecutive block pages pausing a site
+			CoolDownSeconds    int `json:"coolDownSeconds"`    // first pause, doubled each time the site blocks again
+			MaxCoolDownSeconds int `json:"maxCoolDownSeconds"` // longest pause
+		} `json:"circuit"`
+		Validation struct {
+			MaxPriceRatio float64 `json:"maxPriceRatio"` // largest price change factor saved without review, 0 for the default
+			HealthWindow  int     `json:"healthWindow"`  // recent results per field the health report judges
+		} `json:"validation"`
 	} `json:"scraping"`
 
+	Currency struct {
+		RatesFile string `json:"ratesFile"` // static exchange-rate table, relative to the config file
+		RatesURL  string `json:"ratesURL"`  // rate service, preferred over RatesFile when set
+	} `json:"currency"`
+
 	API struct {
 		RateLimit  int `json:"rateLimit"`
 		MaxResults int `json:"maxResults"`
 	} `json:"api"`
 }
 
+// SiteScraping holds the crawl limits of one site. Zero values keep the
+// scraper's defaults.
+type SiteScraping struct {
+	Parallelism     int      `json:"parallelism"`
+	DelayMs         int      `json:"delayMs"`         // delay between requests to the site
+	RandomDelayMs   int      `json:"randomDelayMs"`   // extra random delay up to this value
+	CacheTTLSeconds int      `json:"cacheTTLSeconds"` // how long cached pages of the site stay fresh
+	Fetcher         string   `json:"fetcher"`         // colly (default) or render, through scraping.render
+	Proxies         []string `json:"proxies"`         // proxies of scraping.proxies.urls the site uses; empty for all
+	HeaderProfiles  []string `json:"headerProfiles"`  // names of the header profiles the site uses; empty for all
+	HeaderRotation  string   `json:"headerRotation"`  // overrides scraping.headers.rotation
+	BlockMarkers    []string `json:"blockMarkers"`    // text of block pages, in addition to the scraper's own
+	Cookies         []Cookie `json:"cookies"`         // seeded into the site's session, e.g. consent or age confirmation
+	WarmUpURLs      []string `json:"warmUpURLs"`      // pages visited to warm up a session, replacing the scraper's own
+
+	JSONRequests []JSONRequest `json:"jsonRequests"` // follow-up requests of product pages, after the scraper's own
+}
+
+// JSONRequest is a follow-up request sent after a product page for data
+// the page loads by script, e.g. price and stock
+type JSONRequest struct {
+	Name   string            `json:"name"`
+	URL    string            `json:"url"`    // template with {url}, {id} and {var} placeholders
+	Vars   map[string]string `json:"vars"`   // variable -> "selector" or "selector@attr" on the page
+	Fields map[string]string `json:"fields"` // product field -> JSONPath in the response
+}
+
+// Cookie is a cookie seeded into a site's session. Seeds replace saved
+// cookies of the same name whenever the scrapers start.
+type Cookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Domain string `json:"domain"` // e.g. ".rakuten.co.jp" to cover every subdomain
+	Path   string `json:"path"`   // defaults to /
+}
+
+// HeaderProfile is a set of request headers sent together, like a browser
+// would. An empty UserAgent uses scraping.userAgent; empty Accept headers
+// use the defaults.
+type HeaderProfile struct {
+	Name           string `json:"name"`
+	UserAgent      string `json:"userAgent"`
+	AcceptLanguage string `json:"acceptLanguage"`
+	Accept         string `json:"accept"`
+}
+
 // LoadConfig loads the configuration from the specified environment
 func LoadConfig(env string) (*Config, error) {
 	if env == "" {
 		env = "dev" // Default to dev environment
 	}
 
-	configPath := filepath.Join("configs", env, "config.json")
+	return LoadConfigFile(filepath.Join("configs", env, "config.json"))
+}
 
+// LoadConfigFile loads the configuration from a config file
+func LoadConfigFile(configPath string) (*Config, error) {
 	// Read config file
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -51,5 +164,16 @@ func LoadConfig(env string) (*Config, error) {
 		return nil, fmt.Errorf("error parsing config file: %w", err)
 	}
 
+	// Files named by the config are relative to its directory
+	config.Currency.RatesFile = resolvePath(filepath.Dir(configPath), config.Currency.RatesFile)
+
 	return &config, nil
 }
+
+// resolvePath returns path joined to dir unless it is empty or absolute
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
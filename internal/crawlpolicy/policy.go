@@ -0,0 +1,317 @@
+// Package crawlpolicy decides whether and when the scrapers may fetch a URL:
+// robots.txt rules and crawl-delay per host, per-site request limits and a
+// global requests-per-minute budget. URLs refused by the policy are recorded
+// for reporting.
+package crawlpolicy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/temoto/robotstxt"
+)
+
+// ErrDisallowed is returned for URLs the policy refuses to fetch
+var ErrDisallowed = errors.New("disallowed by crawl policy")
+
+// Skip reasons
+const (
+	ReasonRobots            = "robots.txt"
+	ReasonRobotsUnreachable = "robots.txt unreachable"
+)
+
+// DefaultRobotsTTL is how long a fetched robots.txt is trusted
+const DefaultRobotsTTL = 24 * time.Hour
+
+// RetryBackoff is the pause before the first retry of a failed request,
+// doubled for each further retry
+const RetryBackoff = time.Second
+
+// maxSkips bounds the skipped-URL report; older entries are dropped
+const maxSkips = 1000
+
+// maxRobotsSize bounds the size of a robots.txt file read
+const maxRobotsSize = 512 << 10
+
+// Config configures a Policy
+type Config struct {
+	UserAgent         string        // agent matched against robots.txt groups
+	IgnoreRobotsTxt   bool          // fetch regardless of robots.txt
+	RequestsPerMinute int           // global budget across all sites; 0 means unlimited
+	RobotsTTL         time.Duration // defaults to DefaultRobotsTTL
+	Retries           int           // times a request failing without a response or with a 500, 502 or 504 is sent again
+
+	// Transport fetches robots.txt, e.g. through the scrapers' proxies;
+	// nil uses the default transport
+	Transport http.RoundTripper
+}
+
+// SiteLimits are the request limits of one site
+type SiteLimits struct {
+	Parallelism int
+	Delay       time.Duration // between requests to the same domain
+	RandomDelay time.Duration // extra random delay up to this duration
+}
+
+// LimitRule returns the colly limit rule enforcing l for domains matching glob
+func (l SiteLimits) LimitRule(domainGlob string) *colly.LimitRule {
+	parallelism := l.Parallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	return &colly.LimitRule{
+		DomainGlob:  domainGlob,
+		Parallelism: parallelism,
+		Delay:       l.Delay,
+		RandomDelay: l.RandomDelay,
+	}
+}
+
+// Skip is a URL the policy refused to fetch
+type Skip struct {
+	URL    string    `json:"url"`
+	Host   string    `json:"host"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// robotsEntry is a cached robots.txt
+type robotsEntry struct {
+	data      *robotstxt.RobotsData
+	fetchedAt time.Time
+}
+
+// Policy enforces the crawl policy. It is safe for concurrent use and is
+// shared by all scrapers of a process.
+type Policy struct {
+	cfg    Config
+	client *http.Client
+
+	mutex      sync.Mutex
+	robots     map[string]*robotsEntry // keyed by scheme://host
+	hostNext   map[string]time.Time    // earliest next request per host (crawl-delay)
+	budgetNext time.Time               // earliest next request overall (budget)
+	skips      []Skip
+
+	sleep func(time.Duration) // replaced in tests
+	now   func() time.Time
+}
+
+// New creates a crawl policy
+func New(cfg Config) *Policy {
+	if cfg.RobotsTTL <= 0 {
+		cfg.RobotsTTL = DefaultRobotsTTL
+	}
+	return &Policy{
+		cfg:      cfg,
+		client:   &http.Client{Transport: cfg.Transport, Timeout: 10 * time.Second},
+		robots:   make(map[string]*robotsEntry),
+		hostNext: make(map[string]time.Time),
+		sleep:    time.Sleep,
+		now:      time.Now,
+	}
+}
+
+// Check reports whether u may be fetched with the configured user agent.
+// A refused URL is recorded in the skip report and an error wrapping
+// ErrDisallowed is returned.
+func (p *Policy) Check(u *url.URL) error {
+	return p.CheckAgent(u, p.cfg.UserAgent)
+}
+
+// CheckAgent is like Check for requests sending another user agent, e.g.
+// that of a header profile
+func (p *Policy) CheckAgent(u *url.URL, userAgent string) error {
+	if p.cfg.IgnoreRobotsTxt {
+		return nil
+	}
+
+	robots, err := p.robotsData(u)
+	if err != nil {
+		p.skip(u, ReasonRobotsUnreachable)
+		return fmt.Errorf("%w: %s (%v)", ErrDisallowed, u, err)
+	}
+	if !robots.TestAgent(u.RequestURI(), userAgent) {
+		p.skip(u, ReasonRobots)
+		return fmt.Errorf("%w: %s (robots.txt)", ErrDisallowed, u)
+	}
+	return nil
+}
+
+// Wait blocks until a request to u fits both the host's robots.txt
+// crawl-delay for the configured user agent and the global
+// requests-per-minute budget
+func (p *Policy) Wait(u *url.URL) {
+	p.WaitAgent(u, p.cfg.UserAgent)
+}
+
+// WaitAgent is like Wait for requests sending another user agent
+func (p *Policy) WaitAgent(u *url.URL, userAgent string) {
+	var crawlDelay time.Duration
+	if !p.cfg.IgnoreRobotsTxt {
+		if robots, err := p.robotsData(u); err == nil {
+			crawlDelay = robots.FindGroup(userAgent).CrawlDelay
+		}
+	}
+
+	p.mutex.Lock()
+	now := p.now()
+	at := now
+
+	// Reserve the next slot for the host and in the budget
+	if crawlDelay > 0 {
+		at = later(at, p.hostNext[u.Host])
+	}
+	var interval time.Duration
+	if p.cfg.RequestsPerMinute > 0 {
+		interval = time.Minute / time.Duration(p.cfg.RequestsPerMinute)
+		at = later(at, p.budgetNext)
+	}
+	if crawlDelay > 0 {
+		p.hostNext[u.Host] = at.Add(crawlDelay)
+	}
+	if interval > 0 {
+		p.budgetNext = at.Add(interval)
+	}
+	p.mutex.Unlock()
+
+	if wait := at.Sub(now); wait > 0 {
+		p.sleep(wait)
+	}
+}
+
+// Attach makes collector c check and pace every request through the policy,
+// under the robots.txt rules of the user agent the request sends. Refused
+// requests are aborted and failed ones retried with a growing
+// backoff, up to Config.Retries times. Collectors created with Clone must be
+// attached again, as colly doesn't copy callbacks.
+func (p *Policy) Attach(c *colly.Collector) {
+	// Robots rules are enforced here, with caching and reporting
+	c.IgnoreRobotsTxt = true
+
+	c.OnRequest(func(r *colly.Request) {
+		userAgent := r.Headers.Get("User-Agent")
+		if userAgent == "" {
+			userAgent = p.cfg.UserAgent
+		}
+		if err := p.CheckAgent(r.URL, userAgent); err != nil {
+			r.Abort()
+			return
+		}
+		p.WaitAgent(r.URL, userAgent)
+	})
+
+	if p.cfg.Retries > 0 {
+		c.OnError(func(r *colly.Response, err error) {
+			if !retryable(r.StatusCode) {
+				return
+			}
+			// The context is shared with the requests the page leads to, hence
+			// the URL in the key
+			key := "crawlpolicy.retries " + r.Request.URL.String()
+			n, _ := r.Ctx.GetAny(key).(int)
+			if n >= p.cfg.Retries {
+				return
+			}
+			r.Ctx.Put(key, n+1)
+			p.sleep(RetryBackoff << n)
+			r.Request.Retry()
+		})
+	}
+}
+
+// retryable reports whether a request that failed with status, 0 if no
+// response arrived, may succeed when sent again. Block statuses aren't
+// retried; the circuit breaker handles them.
+func retryable(status int) bool {
+	switch status {
+	case 0, http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Skipped returns the URLs refused by the policy, oldest first
+func (p *Policy) Skipped() []Skip {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return append([]Skip{}, p.skips...)
+}
+
+// skip records a refused URL
+func (p *Policy) skip(u *url.URL, reason string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.skips = append(p.skips, Skip{URL: u.String(), Host: u.Host, Reason: reason, Time: p.now()})
+	if len(p.skips) > maxSkips {
+		p.skips = p.skips[len(p.skips)-maxSkips:]
+	}
+}
+
+// robotsData returns the robots.txt of u's host, fetching it if it isn't
+// cached or has expired
+func (p *Policy) robotsData(u *url.URL) (*robotstxt.RobotsData, error) {
+	key := u.Scheme + "://" + u.Host
+
+	p.mutex.Lock()
+	entry, ok := p.robots[key]
+	p.mutex.Unlock()
+
+	if !ok || p.now().Sub(entry.fetchedAt) > p.cfg.RobotsTTL {
+		data, err := p.fetchRobots(key + "/robots.txt")
+		if err != nil {
+			return nil, err
+		}
+		entry = &robotsEntry{data: data, fetchedAt: p.now()}
+
+		p.mutex.Lock()
+		p.robots[key] = entry
+		p.mutex.Unlock()
+	}
+
+	return entry.data, nil
+}
+
+// fetchRobots downloads and parses a robots.txt file. A missing file (4xx)
+// allows everything; a server error (5xx) disallows everything.
+func (p *Policy) fetchRobots(robotsURL string) (*robotstxt.RobotsData, error) {
+	req, err := http.NewRequest(http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.cfg.UserAgent != "" {
+		req.Header.Set("User-Agent", p.cfg.UserAgent)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", robotsURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", robotsURL, err)
+	}
+
+	data, err := robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", robotsURL, err)
+	}
+	return data, nil
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,186 @@
+package crawlpolicy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+func robotsServer(t *testing.T, robots string, status int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(status)
+			fmt.Fprint(w, robots)
+			return
+		}
+		fmt.Fprint(w, "<html><body>ok</body></html>")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestCheckHonoursRobots(t *testing.T) {
+	server := robotsServer(t, "User-agent: *\nDisallow: /private/\n", http.StatusOK)
+	p := New(Config{UserAgent: "test-agent"})
+
+	if err := p.Check(mustParse(t, server.URL+"/item/1/")); err != nil {
+		t.Errorf("Expected allowed URL, got %v", err)
+	}
+
+	err := p.Check(mustParse(t, server.URL+"/private/1/"))
+	if !errors.Is(err, ErrDisallowed) {
+		t.Fatalf("Expected ErrDisallowed, got %v", err)
+	}
+
+	skipped := p.Skipped()
+	if len(skipped) != 1 || skipped[0].Reason != ReasonRobots {
+		t.Errorf("Unexpected skip report: %+v", skipped)
+	}
+
+	if err := New(Config{IgnoreRobotsTxt: true}).Check(mustParse(t, server.URL+"/private/1/")); err != nil {
+		t.Errorf("Expected robots.txt to be ignored, got %v", err)
+	}
+}
+
+func TestCheckRobotsStatus(t *testing.T) {
+	missing := robotsServer(t, "", http.StatusNotFound)
+	if err := New(Config{}).Check(mustParse(t, missing.URL+"/any")); err != nil {
+		t.Errorf("Expected missing robots.txt to allow all, got %v", err)
+	}
+
+	failing := robotsServer(t, "", http.StatusServiceUnavailable)
+	if err := New(Config{}).Check(mustParse(t, failing.URL+"/any")); !errors.Is(err, ErrDisallowed) {
+		t.Errorf("Expected server error to disallow all, got %v", err)
+	}
+}
+
+func TestWaitPacesRequests(t *testing.T) {
+	server := robotsServer(t, "User-agent: *\nCrawl-delay: 5\n", http.StatusOK)
+
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	var slept []time.Duration
+	p := New(Config{RequestsPerMinute: 30})
+	p.now = func() time.Time { return now }
+	p.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	u := mustParse(t, server.URL+"/item/1/")
+	p.Wait(u) // first request goes out immediately
+	p.Wait(u) // crawl-delay 5s on the host
+	p.Wait(u) // two slots of 5s later
+
+	if len(slept) != 2 || slept[0] != 5*time.Second || slept[1] != 10*time.Second {
+		t.Errorf("Expected sleeps of 5s and 10s, got %v", slept)
+	}
+
+	// The 30/minute budget spaces requests 2s apart across hosts
+	slept = nil
+	p = New(Config{RequestsPerMinute: 30, IgnoreRobotsTxt: true})
+	p.now = func() time.Time { return now }
+	p.sleep = func(d time.Duration) { slept = append(slept, d) }
+	p.Wait(mustParse(t, "https://a.example/"))
+	p.Wait(mustParse(t, "https://b.example/"))
+	if len(slept) != 1 || slept[0] != 2*time.Second {
+		t.Errorf("Expected one 2s sleep from the budget, got %v", slept)
+	}
+}
+
+func TestAttachAbortsDisallowedRequests(t *testing.T) {
+	server := robotsServer(t, "User-agent: *\nDisallow: /private/\n", http.StatusOK)
+	p := New(Config{})
+
+	c := colly.NewCollector()
+	p.Attach(c)
+
+	var visited []string
+	c.OnResponse(func(r *colly.Response) {
+		visited = append(visited, r.Request.URL.Path)
+	})
+
+	c.Visit(server.URL + "/public/")
+	c.Visit(server.URL + "/private/")
+	c.Wait()
+
+	if len(visited) != 1 || visited[0] != "/public/" {
+		t.Errorf("Expected only /public/ to be fetched, got %v", visited)
+	}
+	if len(p.Skipped()) != 1 {
+		t.Errorf("Expected one skipped URL, got %+v", p.Skipped())
+	}
+}
+
+func TestAttachChecksRequestUserAgent(t *testing.T) {
+	server := robotsServer(t, "User-agent: OtherBot\nDisallow: /\n\nUser-agent: *\nDisallow: /private/\n", http.StatusOK)
+	p := New(Config{UserAgent: "test-agent"})
+
+	var visited []string
+	visit := func(userAgent string) {
+		c := colly.NewCollector(colly.AllowURLRevisit())
+		c.UserAgent = userAgent
+		p.Attach(c)
+		c.OnResponse(func(r *colly.Response) {
+			visited = append(visited, userAgent)
+		})
+		c.Visit(server.URL + "/item/")
+		c.Wait()
+	}
+
+	// A profile's user agent is judged by its own group of robots.txt
+	visit("test-agent")
+	visit("OtherBot/2.0")
+	if len(visited) != 1 || visited[0] != "test-agent" {
+		t.Errorf("Expected only test-agent to fetch the page, got %v", visited)
+	}
+}
+
+func TestAttachRetriesFailedRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		switch {
+		case r.URL.Path == "/flaky/" && n < 3:
+			w.WriteHeader(http.StatusBadGateway)
+		case r.URL.Path == "/blocked/":
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	p := New(Config{IgnoreRobotsTxt: true, Retries: 2})
+	var pauses []time.Duration
+	p.sleep = func(d time.Duration) { pauses = append(pauses, d) }
+
+	c := colly.NewCollector()
+	p.Attach(c)
+	ok := 0
+	c.OnResponse(func(r *colly.Response) { ok++ })
+
+	// Two failures are retried with a growing backoff
+	c.Visit(server.URL + "/flaky/")
+	if ok != 1 || requests.Load() != 3 || len(pauses) != 2 || pauses[1] != 2*RetryBackoff {
+		t.Errorf("Expected success on the third request, got %d responses after %d requests (%v)", ok, requests.Load(), pauses)
+	}
+
+	// Blocks aren't retried
+	requests.Store(0)
+	c.Visit(server.URL + "/blocked/")
+	if requests.Load() != 1 {
+		t.Errorf("Expected a block not retried, got %d requests", requests.Load())
+	}
+}
@@ -0,0 +1,134 @@
+package currency
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// Converter converts prices using the rates valid at the time of each price
+type Converter struct {
+	provider Provider
+	store    *SnapshotStore
+	fetched  map[string]*Snapshot // provider answers by requested date
+	mutex    sync.Mutex
+}
+
+// NewConverter creates a converter. Snapshots fetched from provider are saved
+// to store; provider may be nil to use stored snapshots only.
+func NewConverter(provider Provider, store *SnapshotStore) *Converter {
+	return &Converter{
+		provider: provider,
+		store:    store,
+		fetched:  make(map[string]*Snapshot),
+	}
+}
+
+// SnapshotAt returns the rates valid at the given time. A stored snapshot for
+// that day is preferred; otherwise the provider is asked and its answer is
+// persisted. The provider is asked once per day: its answer is kept even when
+// it is dated earlier, as on weekends. If the provider fails, the latest
+// earlier stored snapshot is used.
+func (c *Converter) SnapshotAt(at time.Time) (*Snapshot, error) {
+	date := at.UTC().Format(DateLayout)
+	if snapshot, ok := c.store.Get(date); ok {
+		return snapshot, nil
+	}
+
+	var providerErr error
+	if c.provider != nil {
+		c.mutex.Lock()
+		snapshot, ok := c.fetched[date]
+		c.mutex.Unlock()
+		if ok {
+			return snapshot, nil
+		}
+
+		snapshot, err := c.provider.Rates(at)
+		if err == nil {
+			if _, ok := c.store.Get(snapshot.Date); !ok {
+				if err := c.store.Save(snapshot); err != nil {
+					log.Printf("Warning: failed to save rates snapshot for %s: %v", snapshot.Date, err)
+				}
+			}
+			c.mutex.Lock()
+			c.fetched[date] = snapshot
+			c.mutex.Unlock()
+			return snapshot, nil
+		}
+		providerErr = err
+	}
+
+	if snapshot, ok := c.store.Latest(date); ok {
+		return snapshot, nil
+	}
+	if providerErr != nil {
+		return nil, providerErr
+	}
+	return nil, fmt.Errorf("%w for %s", ErrNoRates, date)
+}
+
+// Convert converts an amount to the target currency using the rates valid at
+// the given time
+func (c *Converter) Convert(m models.Money, to string, at time.Time) (models.Money, error) {
+	to = strings.ToUpper(to)
+	if m.Currency == to {
+		return m, nil
+	}
+
+	snapshot, err := c.SnapshotAt(at)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	rate, err := snapshot.Rate(m.Currency, to)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return convert(m, rate, to), nil
+}
+
+// convert multiplies the minor units of m by rate, rescales them to the
+// minor unit of to and rounds half away from zero
+func convert(m models.Money, rate *big.Rat, to string) models.Money {
+	amount := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+	scale := models.CurrencyExponent(to) - models.CurrencyExponent(m.Currency)
+	pow := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(max(scale, -scale))), nil))
+	if scale >= 0 {
+		amount.Mul(amount, pow)
+	} else {
+		amount.Quo(amount, pow)
+	}
+
+	q, r := new(big.Int).QuoRem(amount.Num(), amount.Denom(), new(big.Int))
+	if r.Mul(r.Abs(r), big.NewInt(2)).Cmp(amount.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(amount.Num().Sign())))
+	}
+	return models.NewMoney(q.Int64(), to)
+}
+
+// ConvertProduct converts a product's current price using the rates valid at
+// its last update
+func (c *Converter) ConvertProduct(p *models.Product, to string) (models.Money, error) {
+	return c.Convert(p.CurrentPrice, to, p.LastUpdated)
+}
+
+// ConvertHistory converts every price point using the rates valid at its
+// timestamp
+func (c *Converter) ConvertHistory(history []models.PricePoint, to string) ([]models.PricePoint, error) {
+	converted := make([]models.PricePoint, len(history))
+	for i, pp := range history {
+		price, err := c.Convert(pp.Price, to, pp.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = pp
+		converted[i].Price = price
+	}
+	return converted, nil
+}
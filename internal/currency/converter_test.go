@@ -0,0 +1,192 @@
+package currency
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func day(date string) time.Time {
+	t, _ := time.Parse(DateLayout, date)
+	return t.Add(12 * time.Hour)
+}
+
+func TestSnapshotRate(t *testing.T) {
+	snapshot := &Snapshot{Date: "2025-01-01", Base: "JPY", Rates: map[string]float64{"USD": 0.005, "EUR": 0.004}}
+
+	if rate, _ := snapshot.Rate("JPY", "USD"); rate.Cmp(big.NewRat(1, 200)) != 0 {
+		t.Errorf("Expected JPY->USD 0.005, got %v", rate)
+	}
+	if rate, _ := snapshot.Rate("USD", "JPY"); rate.Cmp(big.NewRat(200, 1)) != 0 {
+		t.Errorf("Expected USD->JPY 200, got %v", rate)
+	}
+	if rate, _ := snapshot.Rate("usd", "EUR"); rate.Cmp(big.NewRat(4, 5)) != 0 {
+		t.Errorf("Expected USD->EUR 0.8, got %v", rate)
+	}
+	if _, err := snapshot.Rate("JPY", "GBP"); err == nil {
+		t.Error("Expected error for unknown currency")
+	}
+}
+
+func TestConvertExact(t *testing.T) {
+	// 700 * 0.00645 is 4.515 exactly, but 4.51499... in floating point
+	snapshot := &Snapshot{Date: "2025-01-01", Base: "JPY", Rates: map[string]float64{"USD": 0.00645, "KWD": 0.002}}
+	tests := []struct {
+		from     models.Money
+		to       string
+		expected models.Money
+	}{
+		{models.NewMoney(700, "JPY"), "USD", models.NewMoney(452, "USD")},
+		{models.NewMoney(-700, "JPY"), "USD", models.NewMoney(-452, "USD")},
+		{models.NewMoney(452, "USD"), "JPY", models.NewMoney(701, "JPY")},
+		{models.NewMoney(1234, "JPY"), "KWD", models.NewMoney(2468, "KWD")},
+	}
+	for _, tt := range tests {
+		rate, err := snapshot.Rate(tt.from.Currency, tt.to)
+		if err != nil {
+			t.Fatalf("Failed to get rate: %v", err)
+		}
+		if got := convert(tt.from, rate, tt.to); got != tt.expected {
+			t.Errorf("Expected %s to convert to %s, got %s", tt.from, tt.expected, got)
+		}
+	}
+}
+
+func TestConverterStaticProvider(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	ratesFile := filepath.Join(tmpDir, "rates.json")
+	table := `[
+		{"date": "2025-01-01", "base": "JPY", "rates": {"USD": 0.005}},
+		{"date": "2025-02-01", "base": "JPY", "rates": {"USD": 0.01}}
+	]`
+	if err := os.WriteFile(ratesFile, []byte(table), 0644); err != nil {
+		t.Fatalf("Failed to write rates file: %v", err)
+	}
+
+	provider, err := NewProvider(ratesFile, "")
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	store, err := NewSnapshotStore(filepath.Join(tmpDir, "exchange_rates.json"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	converter := NewConverter(provider, store)
+
+	// Each price point converts with the rate of its own day
+	history := []models.PricePoint{
+		{Price: models.NewMoney(2000, "JPY"), Timestamp: day("2025-01-15")},
+		{Price: models.NewMoney(2000, "JPY"), Timestamp: day("2025-02-15")},
+	}
+	converted, err := converter.ConvertHistory(history, "USD")
+	if err != nil {
+		t.Fatalf("Failed to convert history: %v", err)
+	}
+	if converted[0].Price != models.NewMoney(1000, "USD") {
+		t.Errorf("Expected 10.00 USD in January, got %s", converted[0].Price)
+	}
+	if converted[1].Price != models.NewMoney(2000, "USD") {
+		t.Errorf("Expected 20.00 USD in February, got %s", converted[1].Price)
+	}
+	if history[0].Price.Currency != "JPY" {
+		t.Error("Expected original history to be left unchanged")
+	}
+
+	if _, err := converter.Convert(models.NewMoney(100, "JPY"), "USD", day("2024-12-31")); !errors.Is(err, ErrNoRates) {
+		t.Errorf("Expected ErrNoRates before the first snapshot, got %v", err)
+	}
+
+	// Snapshots used are persisted with the data
+	reloaded, err := NewSnapshotStore(filepath.Join(tmpDir, "exchange_rates.json"))
+	if err != nil {
+		t.Fatalf("Failed to reload store: %v", err)
+	}
+	if _, ok := reloaded.Get("2025-02-01"); !ok {
+		t.Error("Expected February snapshot to be persisted")
+	}
+}
+
+func TestConverterHTTPProvider(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		date := strings.TrimPrefix(r.URL.Path, "/")
+		if date > "2025-03-01" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(Snapshot{Date: date, Base: "EUR", Rates: map[string]float64{"JPY": 160, "USD": 1.1}})
+	}))
+	defer server.Close()
+
+	store, err := NewSnapshotStore(filepath.Join(t.TempDir(), "exchange_rates.json"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	converter := NewConverter(NewHTTPProvider(server.URL), store)
+
+	price, err := converter.Convert(models.NewMoney(1600, "JPY"), "EUR", day("2025-03-01"))
+	if err != nil {
+		t.Fatalf("Failed to convert: %v", err)
+	}
+	if price != models.NewMoney(1000, "EUR") {
+		t.Errorf("Expected 10.00 EUR, got %s", price)
+	}
+
+	// Second lookup for the same day is served from the stored snapshot
+	if _, err := converter.Convert(models.NewMoney(1600, "JPY"), "USD", day("2025-03-01")); err != nil {
+		t.Fatalf("Failed to convert: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request to the rate service, got %d", requests)
+	}
+
+	// When the service has no rates, the latest stored snapshot is used
+	price, err = converter.Convert(models.NewMoney(1600, "JPY"), "EUR", day("2025-03-05"))
+	if err != nil {
+		t.Fatalf("Expected fallback to stored snapshot, got %v", err)
+	}
+	if price != models.NewMoney(1000, "EUR") {
+		t.Errorf("Expected 10.00 EUR from fallback, got %s", price)
+	}
+}
+
+// providerFunc adapts a function to Provider
+type providerFunc func(day time.Time) (*Snapshot, error)
+
+func (f providerFunc) Rates(day time.Time) (*Snapshot, error) {
+	return f(day)
+}
+
+func TestConverterFetchesOncePerDay(t *testing.T) {
+	requests := 0
+	provider := providerFunc(func(time.Time) (*Snapshot, error) {
+		requests++
+		// Weekend: the latest rates are Friday's
+		return &Snapshot{Date: "2025-03-07", Base: "JPY", Rates: map[string]float64{"USD": 0.005}}, nil
+	})
+	store, err := NewSnapshotStore(filepath.Join(t.TempDir(), "exchange_rates.json"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	converter := NewConverter(provider, store)
+
+	for i := 0; i < 3; i++ {
+		if _, err := converter.Convert(models.NewMoney(2000, "JPY"), "USD", day("2025-03-08")); err != nil {
+			t.Fatalf("Failed to convert: %v", err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request to the provider for the day, got %d", requests)
+	}
+}
@@ -0,0 +1,163 @@
+// Package currency converts prices between currencies using daily
+// exchange-rate snapshots
+package currency
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of snapshot dates
+const DateLayout = "2006-01-02"
+
+// ErrNoRates is returned when no exchange rates are known for a date
+var ErrNoRates = errors.New("no exchange rates available")
+
+// Snapshot is a table of exchange rates valid for one day. Rates are the
+// number of units of each currency per one unit of Base.
+type Snapshot struct {
+	Date  string             `json:"date"`
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Rate returns the exact multiplier converting an amount in from into to,
+// taking each rate as the shortest decimal that reads back as it
+func (s *Snapshot) Rate(from, to string) (*big.Rat, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	fromRate, err := s.baseRate(from)
+	if err != nil {
+		return nil, err
+	}
+	toRate, err := s.baseRate(to)
+	if err != nil {
+		return nil, err
+	}
+	return toRate.Quo(toRate, fromRate), nil
+}
+
+// baseRate returns the units of currency per one unit of the base currency
+func (s *Snapshot) baseRate(currency string) (*big.Rat, error) {
+	if currency == strings.ToUpper(s.Base) {
+		return big.NewRat(1, 1), nil
+	}
+	rate, ok := s.Rates[currency]
+	if !ok || rate <= 0 {
+		return nil, fmt.Errorf("no %s rate in %s snapshot for %s", currency, s.Base, s.Date)
+	}
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(rate, 'g', -1, 64))
+	return r, nil
+}
+
+// Provider supplies exchange rates
+type Provider interface {
+	// Rates returns the snapshot valid on the given day
+	Rates(day time.Time) (*Snapshot, error)
+}
+
+// NewProvider creates the provider selected by configuration: an HTTP
+// provider if ratesURL is set, otherwise a static provider if ratesFile is
+// set. It returns nil if neither is configured.
+func NewProvider(ratesFile, ratesURL string) (Provider, error) {
+	switch {
+	case ratesURL != "":
+		return NewHTTPProvider(ratesURL), nil
+	case ratesFile != "":
+		return NewStaticProvider(ratesFile)
+	}
+	return nil, nil
+}
+
+// StaticProvider serves rates from a local JSON file containing a list of
+// snapshots. The latest snapshot dated on or before the requested day is used.
+type StaticProvider struct {
+	snapshots []*Snapshot // sorted by date
+}
+
+// NewStaticProvider loads a static exchange-rate table
+func NewStaticProvider(filePath string) (*StaticProvider, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	var snapshots []*Snapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Date < snapshots[j].Date })
+	return &StaticProvider{snapshots: snapshots}, nil
+}
+
+// Rates returns the latest snapshot dated on or before day
+func (p *StaticProvider) Rates(day time.Time) (*Snapshot, error) {
+	date := day.UTC().Format(DateLayout)
+	for i := len(p.snapshots) - 1; i >= 0; i-- {
+		if p.snapshots[i].Date <= date {
+			return p.snapshots[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w for %s", ErrNoRates, date)
+}
+
+// HTTPProvider fetches daily rates from a rate service. A request for a day
+// is sent as GET {BaseURL}/{YYYY-MM-DD} and must return a Snapshot as JSON,
+// the format used by Frankfurter-style services and local stand-ins.
+type HTTPProvider struct {
+	BaseURL string
+	Base    string // optional base currency, sent as ?from=
+	Client  *http.Client
+}
+
+// NewHTTPProvider creates an HTTP rate provider
+func NewHTTPProvider(baseURL string) *HTTPProvider {
+	return &HTTPProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Rates fetches the snapshot for day from the rate service
+func (p *HTTPProvider) Rates(day time.Time) (*Snapshot, error) {
+	date := day.UTC().Format(DateLayout)
+	reqURL := p.BaseURL + "/" + date
+	if p.Base != "" {
+		reqURL += "?" + url.Values{"from": {p.Base}}.Encode()
+	}
+
+	resp, err := p.Client.Get(reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w for %s", ErrNoRates, date)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rate service returned %s", resp.Status)
+	}
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse rates: %w", err)
+	}
+	if snapshot.Date == "" {
+		snapshot.Date = date
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,96 @@
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// SnapshotStore persists daily rate snapshots to a JSON file next to the
+// product data, so past prices keep converting with the rates of their day
+type SnapshotStore struct {
+	filePath  string
+	snapshots map[string]*Snapshot
+	mutex     sync.RWMutex
+}
+
+// NewSnapshotStore creates a snapshot store backed by filePath
+func NewSnapshotStore(filePath string) (*SnapshotStore, error) {
+	store := &SnapshotStore{
+		filePath:  filePath,
+		snapshots: make(map[string]*Snapshot),
+	}
+
+	// Load existing data if file exists
+	if _, err := os.Stat(filePath); err == nil {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rates snapshot file: %w", err)
+		}
+
+		var snapshots []*Snapshot
+		if err := json.Unmarshal(data, &snapshots); err != nil {
+			return nil, fmt.Errorf("failed to parse rates snapshot file: %w", err)
+		}
+
+		for _, s := range snapshots {
+			store.snapshots[s.Date] = s
+		}
+	}
+
+	return store, nil
+}
+
+// Get returns the snapshot for an exact date
+func (s *SnapshotStore) Get(date string) (*Snapshot, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	snapshot, ok := s.snapshots[date]
+	return snapshot, ok
+}
+
+// Latest returns the most recent snapshot dated on or before date
+func (s *SnapshotStore) Latest(date string) (*Snapshot, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var latest *Snapshot
+	for d, snapshot := range s.snapshots {
+		if d <= date && (latest == nil || d > latest.Date) {
+			latest = snapshot
+		}
+	}
+	return latest, latest != nil
+}
+
+// Save stores a snapshot, replacing any snapshot with the same date
+func (s *SnapshotStore) Save(snapshot *Snapshot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.snapshots[snapshot.Date] = snapshot
+	return s.writeToFile()
+}
+
+// writeToFile persists the snapshots, sorted by date
+func (s *SnapshotStore) writeToFile() error {
+	snapshots := make([]*Snapshot, 0, len(s.snapshots))
+	for _, snapshot := range s.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Date < snapshots[j].Date })
+
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal rates snapshots: %w", err)
+	}
+
+	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write rates snapshot file: %w", err)
+	}
+
+	return nil
+}
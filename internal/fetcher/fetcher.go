@@ -0,0 +1,51 @@
+// Package fetcher chooses how the pages of a site are fetched: by colly's
+// own HTTP requests, or by an external rendering service that runs the
+// page's scripts first, so prices loaded by XHR are in the HTML. A fetcher
+// sits beneath colly as its transport, so the crawl policy, rate limits,
+// header profiles, block detection and sessions apply to every site alike.
+package fetcher
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Fetcher names
+const (
+	NameColly  = "colly"  // plain HTTP requests, the default
+	NameRender = "render" // a rendering service
+)
+
+// Fetcher fetches the pages of a site for its collectors
+type Fetcher interface {
+	// Name identifies the fetcher, e.g. NameColly
+	Name() string
+	// Transport returns the transport collectors fetch pages through, given
+	// the one colly would use otherwise (nil for the default)
+	Transport(next http.RoundTripper) http.RoundTripper
+}
+
+// Colly fetches pages with colly's own HTTP requests
+type Colly struct{}
+
+// Name returns NameColly
+func (Colly) Name() string {
+	return NameColly
+}
+
+// Transport returns next unchanged
+func (Colly) Transport(next http.RoundTripper) http.RoundTripper {
+	return next
+}
+
+// New creates the fetcher called name, "" being colly. The rendering
+// service needs render.URL.
+func New(name string, render RenderConfig) (Fetcher, error) {
+	switch name {
+	case "", NameColly:
+		return Colly{}, nil
+	case NameRender:
+		return NewRenderer(render)
+	}
+	return nil, fmt.Errorf("unknown fetcher: %s", name)
+}
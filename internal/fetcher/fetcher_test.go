@@ -0,0 +1,139 @@
+package fetcher
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"", NameColly} {
+		f, err := New(name, RenderConfig{})
+		if err != nil || f.Name() != NameColly {
+			t.Errorf("Expected colly for %q, got %v (%v)", name, f, err)
+		}
+	}
+	if _, err := New(NameRender, RenderConfig{}); err == nil {
+		t.Error("Expected an error for a renderer without a URL")
+	}
+	if _, err := New("browser", RenderConfig{}); err == nil {
+		t.Error("Expected an error for an unknown fetcher")
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	stub := &StubRenderer{
+		Pages:   map[string]string{"https://shop.example/item/1": "<html><body><span class='price'>1,980円</span></body></html>"},
+		Cookies: []*http.Cookie{{Name: "sid", Value: "abc", Path: "/"}},
+	}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	renderer, err := NewRenderer(RenderConfig{URL: server.URL, Wait: 500 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: renderer.Transport(http.DefaultTransport)}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://shop.example/item/1", nil)
+	req.Header.Set("User-Agent", "go-scrapy/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "1,980円") {
+		t.Errorf("Expected the rendered page, got %d %s", resp.StatusCode, body)
+	}
+	if cookies := resp.Cookies(); len(cookies) != 1 || cookies[0].Value != "abc" {
+		t.Errorf("Expected the page's cookies passed on, got %v", cookies)
+	}
+
+	requests := stub.Requests()
+	if len(requests) != 1 || requests[0].Headers["User-Agent"] != "go-scrapy/1.0" || requests[0].WaitMs != 500 {
+		t.Errorf("Unexpected render request: %+v", requests)
+	}
+
+	// The page's status comes through for block detection
+	resp, err = client.Get("https://shop.example/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected the page's 404, got %d", resp.StatusCode)
+	}
+
+	// XHRs bypass the service
+	next := &directTransport{}
+	client = &http.Client{Transport: renderer.Transport(next)}
+	req, _ = http.NewRequest(http.MethodGet, "https://shop.example/api/stock?item=1", nil)
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	if resp, err = client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(next.urls) != 1 || len(stub.Requests()) != 2 {
+		t.Errorf("Expected the XHR sent directly, got %v and %d render requests", next.urls, len(stub.Requests()))
+	}
+}
+
+// directTransport records the URLs it is asked for and answers them with
+// an empty JSON object
+type directTransport struct {
+	urls []string
+}
+
+func (t *directTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, req.URL.String())
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+}
+
+func TestRendererServiceError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "browser crashed", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	renderer, err := NewRenderer(RenderConfig{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: renderer}
+	_, err = client.Get("https://shop.example/item/1")
+	if err == nil || !strings.Contains(err.Error(), "browser crashed") {
+		t.Errorf("Expected the service's error, got %v", err)
+	}
+}
+
+func TestStubRendererDir(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "shop.example", "item", "1", "index.html")
+	os.MkdirAll(filepath.Dir(page), 0755)
+	os.WriteFile(page, []byte("<html>item 1</html>"), 0644)
+	os.WriteFile(filepath.Join(dir, "secret.html"), []byte("secret"), 0644)
+
+	stub := &StubRenderer{Dir: dir}
+	tests := []struct {
+		url    string
+		status int
+	}{
+		{"https://shop.example/item/1?ref=top", http.StatusOK},
+		{"https://shop.example/item/2", http.StatusNotFound},
+		{"https://shop.example/../../secret.html", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		html, status := stub.page(u)
+		if status != tt.status {
+			t.Errorf("%s: expected %d, got %d (%s)", tt.url, tt.status, status, html)
+		}
+	}
+}
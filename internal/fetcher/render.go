@@ -0,0 +1,154 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultRenderTimeout bounds a page render unless configured otherwise
+const DefaultRenderTimeout = 60 * time.Second
+
+// StatusHeader carries the status the rendered page was served with, 200
+// if absent
+const StatusHeader = "X-Render-Status"
+
+// forwardedHeaders are the request headers passed to the rendering service
+// for the browser to send
+var forwardedHeaders = []string{"User-Agent", "Accept", "Accept-Language", "Cookie"}
+
+// RenderRequest asks a rendering service for a page. It is POSTed as JSON to
+// the service's URL, which answers 200 with the rendered HTML, the page's
+// status in StatusHeader and the cookies it set as Set-Cookie headers. Any
+// other status is a failure of the service, described by the body.
+type RenderRequest struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	WaitMs  int               `json:"wait_ms,omitempty"` // how long scripts may run after the page loaded
+}
+
+// RenderConfig configures a Renderer
+type RenderConfig struct {
+	URL     string        // endpoint of the rendering service, e.g. "http://localhost:9222/render"
+	Timeout time.Duration // per page; defaults to DefaultRenderTimeout
+	Wait    time.Duration // passed to the service as wait_ms
+}
+
+// Renderer fetches pages through a rendering service. The service fetches
+// the pages itself, so the HTTP cache and the proxies don't apply to them;
+// they do to XHRs, which bypass the service.
+type Renderer struct {
+	cfg    RenderConfig
+	client *http.Client
+}
+
+// NewRenderer creates a fetcher using the rendering service at cfg.URL
+func NewRenderer(cfg RenderConfig) (*Renderer, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("no rendering service URL")
+	}
+	if u, err := url.Parse(cfg.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid rendering service URL: %s", cfg.URL)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultRenderTimeout
+	}
+	return &Renderer{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}, nil
+}
+
+// Name returns NameRender
+func (r *Renderer) Name() string {
+	return NameRender
+}
+
+// Transport returns a transport rendering pages with the service. XHRs,
+// such as the JSON follow-up requests of a page, would come back wrapped in
+// HTML, so they are sent through next instead (http.DefaultTransport if nil).
+func (r *Renderer) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &renderTransport{renderer: r, next: next}
+}
+
+// renderTransport routes requests between a Renderer and the transport
+// beneath it
+type renderTransport struct {
+	renderer *Renderer
+	next     http.RoundTripper
+}
+
+// RoundTrip renders pages and sends XHRs through the next transport
+func (t *renderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("X-Requested-With") == "XMLHttpRequest" {
+		return t.next.RoundTrip(req)
+	}
+	return t.renderer.RoundTrip(req)
+}
+
+// RoundTrip has the rendering service render the page req asks for and
+// answers with the rendered HTML as if the site had sent it
+func (r *Renderer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return nil, fmt.Errorf("rendering service can't send %s requests", req.Method)
+	}
+
+	renderReq := RenderRequest{URL: req.URL.String(), WaitMs: int(r.cfg.Wait / time.Millisecond)}
+	for _, name := range forwardedHeaders {
+		if value := req.Header.Get(name); value != "" {
+			if renderReq.Headers == nil {
+				renderReq.Headers = make(map[string]string)
+			}
+			renderReq.Headers[name] = value
+		}
+	}
+	body, err := json.Marshal(renderReq)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, r.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	serviceReq.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(serviceReq)
+	if err != nil {
+		return nil, fmt.Errorf("rendering %s: %w", req.URL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("rendering %s: service answered %s: %s", req.URL, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	status := http.StatusOK
+	if s := resp.Header.Get(StatusHeader); s != "" {
+		if status, err = strconv.Atoi(s); err != nil || status < 100 || status > 999 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("rendering %s: invalid page status %q", req.URL, s)
+		}
+	}
+
+	header := http.Header{"Content-Type": {"text/html; charset=utf-8"}}
+	for _, cookie := range resp.Header.Values("Set-Cookie") {
+		header.Add("Set-Cookie", cookie)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         resp.Proto,
+		ProtoMajor:    resp.ProtoMajor,
+		ProtoMinor:    resp.ProtoMinor,
+		Header:        header,
+		Body:          resp.Body,
+		ContentLength: resp.ContentLength,
+		Request:       req,
+	}, nil
+}
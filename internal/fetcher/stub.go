@@ -0,0 +1,81 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// StubRenderer is a stand-in for a rendering service speaking the protocol
+// of RenderRequest. It serves prepared pages instead of running a browser,
+// so tests and local runs need no browser; unknown pages are served as 404.
+type StubRenderer struct {
+	Pages   map[string]string // rendered HTML by page URL
+	Dir     string            // rendered pages as <dir>/<host>/<path>/index.html, for URLs not in Pages; the query is ignored
+	Cookies []*http.Cookie    // set by every page
+
+	mutex    sync.Mutex
+	requests []RenderRequest
+}
+
+// ServeHTTP answers a render request with the prepared page
+func (s *StubRenderer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "render requests are POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req RenderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid render request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || u.Host == "" {
+		http.Error(w, "invalid page URL: "+req.URL, http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, req)
+	s.mutex.Unlock()
+
+	html, status := s.page(u)
+	for _, c := range s.Cookies {
+		http.SetCookie(w, c)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set(StatusHeader, strconv.Itoa(status))
+	w.Write([]byte(html))
+}
+
+// page returns the rendered HTML of u and the status it is served with
+func (s *StubRenderer) page(u *url.URL) (string, int) {
+	if html, ok := s.Pages[u.String()]; ok {
+		return html, http.StatusOK
+	}
+	if s.Dir != "" {
+		// Cleaned as rooted so the URL can't address files outside Dir
+		file := filepath.Join(s.Dir, u.Host, filepath.FromSlash(path.Clean("/"+u.Path)), "index.html")
+		html, err := os.ReadFile(file)
+		if err == nil {
+			return string(html), http.StatusOK
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "<html><body>" + err.Error() + "</body></html>", http.StatusInternalServerError
+		}
+	}
+	return "<html><body>Not Found</body></html>", http.StatusNotFound
+}
+
+// Requests returns the render requests received so far
+func (s *StubRenderer) Requests() []RenderRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]RenderRequest(nil), s.requests...)
+}
@@ -0,0 +1,129 @@
+// Package headers sets the identifying request headers of the scrapers from
+// configured profiles. A profile is picked for each crawl and kept for all
+// of its requests, so a crawl presents one consistent browser.
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+
+	"github.com/gocolly/colly"
+)
+
+// Rotation strategies
+const (
+	RotationFixed      = "fixed"       // always the first profile
+	RotationRoundRobin = "round-robin" // profiles in turn
+	RotationRandom     = "random"      // a random profile
+)
+
+// Defaults of the headers a profile leaves empty
+const (
+	DefaultAccept         = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	DefaultAcceptLanguage = "ja,en-US;q=0.8,en;q=0.6"
+)
+
+// Profile is a set of headers sent together, like a browser would
+type Profile struct {
+	Name           string
+	UserAgent      string
+	AcceptLanguage string
+	Accept         string
+}
+
+// Apply sets the profile's headers on h
+func (p Profile) Apply(h http.Header) {
+	h.Set("User-Agent", p.UserAgent)
+	h.Set("Accept-Language", p.AcceptLanguage)
+	h.Set("Accept", p.Accept)
+}
+
+// Attach makes collector c send the profile's headers with every request.
+// Like the crawl policy, collectors created with Clone must be attached
+// again.
+func (p Profile) Attach(c *colly.Collector) {
+	c.UserAgent = p.UserAgent
+	c.OnRequest(func(req *colly.Request) {
+		p.Apply(*req.Headers)
+	})
+}
+
+// Rotator hands out profiles according to a rotation strategy. It is safe
+// for concurrent use.
+type Rotator struct {
+	profiles []Profile
+	rotation string
+
+	mutex sync.Mutex
+	next  int
+	rand  *rand.Rand
+}
+
+// NewRotator creates a rotator over profiles, filling empty Accept and
+// Accept-Language headers with the defaults. Every profile needs a user
+// agent.
+func NewRotator(profiles []Profile, rotation string) (*Rotator, error) {
+	if len(profiles) == 0 {
+		return nil, errors.New("no header profiles")
+	}
+	switch rotation {
+	case "":
+		rotation = RotationFixed
+	case RotationFixed, RotationRoundRobin, RotationRandom:
+	default:
+		return nil, fmt.Errorf("unknown header rotation: %s", rotation)
+	}
+
+	filled := make([]Profile, len(profiles))
+	for i, p := range profiles {
+		if p.UserAgent == "" {
+			return nil, fmt.Errorf("header profile %q has no user agent", p.Name)
+		}
+		if p.Accept == "" {
+			p.Accept = DefaultAccept
+		}
+		if p.AcceptLanguage == "" {
+			p.AcceptLanguage = DefaultAcceptLanguage
+		}
+		filled[i] = p
+	}
+
+	return &Rotator{
+		profiles: filled,
+		rotation: rotation,
+		rand:     rand.New(rand.NewSource(rand.Int63())),
+	}, nil
+}
+
+// Single returns a rotator always handing out one profile with userAgent
+// and the default headers
+func Single(userAgent string) *Rotator {
+	return &Rotator{
+		profiles: []Profile{{
+			Name:           "default",
+			UserAgent:      userAgent,
+			AcceptLanguage: DefaultAcceptLanguage,
+			Accept:         DefaultAccept,
+		}},
+		rotation: RotationFixed,
+	}
+}
+
+// Next returns the profile of the next crawl
+func (r *Rotator) Next() Profile {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch r.rotation {
+	case RotationRoundRobin:
+		p := r.profiles[r.next%len(r.profiles)]
+		r.next++
+		return p
+	case RotationRandom:
+		return r.profiles[r.rand.Intn(len(r.profiles))]
+	}
+	return r.profiles[0]
+}
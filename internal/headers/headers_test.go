@@ -0,0 +1,91 @@
+package headers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+func TestRotation(t *testing.T) {
+	profiles := []Profile{{Name: "a", UserAgent: "A"}, {Name: "b", UserAgent: "B"}}
+
+	fixed, _ := NewRotator(profiles, "")
+	roundRobin, _ := NewRotator(profiles, RotationRoundRobin)
+	for _, want := range []string{"A", "B", "A"} {
+		if got := fixed.Next().UserAgent; got != "A" {
+			t.Errorf("Expected the first profile when fixed, got %s", got)
+		}
+		if got := roundRobin.Next().UserAgent; got != want {
+			t.Errorf("Expected %s in turn, got %s", want, got)
+		}
+	}
+
+	random, _ := NewRotator(profiles, RotationRandom)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		seen[random.Next().UserAgent] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected both profiles picked at random, got %v", seen)
+	}
+}
+
+func TestNewRotator(t *testing.T) {
+	r, err := NewRotator([]Profile{{Name: "ja", UserAgent: "A", AcceptLanguage: "ja"}}, RotationFixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := r.Next(); p.AcceptLanguage != "ja" || p.Accept != DefaultAccept {
+		t.Errorf("Expected the default Accept only, got %+v", p)
+	}
+
+	for _, tt := range []struct {
+		profiles []Profile
+		rotation string
+	}{
+		{nil, RotationFixed},
+		{[]Profile{{Name: "a"}}, RotationFixed},
+		{[]Profile{{Name: "a", UserAgent: "A"}}, "weighted"},
+	} {
+		if _, err := NewRotator(tt.profiles, tt.rotation); err == nil {
+			t.Errorf("Expected an error for %+v / %q", tt.profiles, tt.rotation)
+		}
+	}
+}
+
+func TestAttach(t *testing.T) {
+	var got []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Clone())
+	}))
+	defer server.Close()
+
+	r, _ := NewRotator([]Profile{
+		{Name: "a", UserAgent: "A", AcceptLanguage: "ja"},
+		{Name: "b", UserAgent: "B", AcceptLanguage: "en", Accept: "text/html"},
+	}, RotationRoundRobin)
+
+	// Each collector keeps its profile for all of its requests
+	for i := 0; i < 2; i++ {
+		c := colly.NewCollector(colly.AllowURLRevisit())
+		r.Next().Attach(c)
+		c.Visit(server.URL + "/1")
+		c.Visit(server.URL + "/2")
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("Expected 4 requests, got %d", len(got))
+	}
+	want := []Profile{
+		{UserAgent: "A", AcceptLanguage: "ja", Accept: DefaultAccept},
+		{UserAgent: "B", AcceptLanguage: "en", Accept: "text/html"},
+	}
+	for i, h := range got {
+		w := want[i/2]
+		if h.Get("User-Agent") != w.UserAgent || h.Get("Accept-Language") != w.AcceptLanguage || h.Get("Accept") != w.Accept {
+			t.Errorf("Request %d: unexpected headers %v", i, h)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package health
+
+import (
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// ProductStore holds the saved copies of products
+type ProductStore interface {
+	GetByID(id string) (*models.Product, error)
+	Save(product *models.Product) error
+}
+
+// QuarantineStore holds the products held back by validation
+type QuarantineStore interface {
+	Save(entry *models.QuarantinedProduct) error
+}
+
+// Gate validates scraped products against their stored copies before they
+// replace them, holding suspicious ones in quarantine
+type Gate struct {
+	Store      ProductStore
+	Quarantine QuarantineStore
+	Rules      Rules
+	Monitor    *Monitor // counts every result if set
+}
+
+// Save validates a product scraped from a page of the given type against
+// the stored copy. A valid product is passed to prepare, if set, and saved
+// with the stored history. Otherwise it is quarantined and the issues found
+// are returned; the error only reports failing storage.
+func (g *Gate) Save(page string, product *models.Product, prepare func(*models.Product)) ([]models.ValidationIssue, error) {
+	existing, err := g.Store.GetByID(product.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := g.Rules.Validate(product, existing)
+	if g.Monitor != nil {
+		g.Monitor.Record(page, product, len(issues) > 0)
+	}
+
+	if len(issues) > 0 {
+		entry := &models.QuarantinedProduct{
+			Product:       product,
+			Issues:        issues,
+			QuarantinedAt: time.Now(),
+		}
+		if existing != nil {
+			price := existing.CurrentPrice
+			entry.PreviousPrice = &price
+		}
+		return issues, g.Quarantine.Save(entry)
+	}
+
+	if prepare != nil {
+		prepare(product)
+	}
+	product.MergeHistory(existing)
+	return nil, g.Store.Save(product)
+}
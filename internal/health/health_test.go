@@ -0,0 +1,165 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestValidate(t *testing.T) {
+	rules := DefaultRules()
+	stored := models.NewProduct("item", "Item", "https://item.rakuten.co.jp/shop/item/", "rakuten", models.NewMoney(1980, "JPY"))
+
+	tests := []struct {
+		name     string
+		product  *models.Product
+		previous *models.Product
+		rule     string
+	}{
+		{"valid", models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(1780, "JPY")), stored, ""},
+		{"new product", models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(19, "JPY")), nil, ""},
+		{"empty name", models.NewProduct("item", "", "", "rakuten", models.NewMoney(1980, "JPY")), stored, RuleRequired},
+		{"zero price", models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(0, "JPY")), stored, RulePositive},
+		{"price drop", models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(198, "JPY")), stored, RulePriceJump},
+		{"price rise", models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(19800, "JPY")), stored, RulePriceJump},
+		{"other currency", models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(1500, "USD")), stored, ""},
+	}
+
+	for _, tt := range tests {
+		issues := rules.Validate(tt.product, tt.previous)
+		switch {
+		case tt.rule == "" && len(issues) > 0:
+			t.Errorf("%s: expected no issues, got %+v", tt.name, issues)
+		case tt.rule != "" && (len(issues) != 1 || issues[0].Rule != tt.rule):
+			t.Errorf("%s: expected a %s issue, got %+v", tt.name, tt.rule, issues)
+		}
+	}
+
+	if issues := (Rules{}).Validate(models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(19, "JPY")), stored); len(issues) != 0 {
+		t.Errorf("Expected the jump check disabled, got %+v", issues)
+	}
+}
+
+func TestMonitorReport(t *testing.T) {
+	m := NewMonitor(5)
+
+	full := models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(1980, "JPY"))
+	full.GTIN = "4902370548495"
+	broken := models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(0, "JPY"))
+
+	// GTIN and price were extracted until the last five scrapes
+	for i := 0; i < 10; i++ {
+		m.Record(PageProduct, full, false)
+	}
+	for i := 0; i < 5; i++ {
+		m.Record(PageProduct, broken, true)
+	}
+	m.Record(PageListing, full, false)
+
+	report := m.Report()
+	if len(report) != 2 || report[0].Page != PageListing || report[1].Page != PageProduct {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	product := report[1]
+	if product.Scrapes != 15 || product.Quarantined != 5 || product.Status != StatusFailing {
+		t.Errorf("Unexpected product page health: %+v", product)
+	}
+
+	statuses := make(map[string]string)
+	for _, f := range product.Fields {
+		statuses[f.Field] = f.Status
+	}
+	if statuses["price"] != StatusFailing || statuses["gtin"] != StatusFailing || statuses["name"] != StatusOK {
+		t.Errorf("Unexpected field statuses: %v", statuses)
+	}
+	// Never extracted, so nothing has degraded
+	if statuses["rating"] != StatusOK {
+		t.Errorf("Expected rating ok, got %s", statuses["rating"])
+	}
+
+	if report[0].Status != StatusOK {
+		t.Errorf("Expected listing with too few samples to be ok, got %s", report[0].Status)
+	}
+}
+
+// memoryStore keeps products and quarantined entries in maps
+type memoryStore struct {
+	products    map[string]*models.Product
+	quarantined map[string]*models.QuarantinedProduct
+}
+
+func (s *memoryStore) GetByID(id string) (*models.Product, error) {
+	return s.products[id], nil
+}
+
+func (s *memoryStore) Save(p *models.Product) error {
+	s.products[p.ID] = p
+	return nil
+}
+
+// memoryQuarantine is the quarantine side of a memoryStore
+type memoryQuarantine struct{ *memoryStore }
+
+func (q memoryQuarantine) Save(entry *models.QuarantinedProduct) error {
+	q.quarantined[entry.Product.ID] = entry
+	return nil
+}
+
+func TestGateSave(t *testing.T) {
+	store := &memoryStore{products: make(map[string]*models.Product), quarantined: make(map[string]*models.QuarantinedProduct)}
+	gate := &Gate{Store: store, Quarantine: memoryQuarantine{store}, Rules: NewRules(2), Monitor: NewMonitor(0)}
+
+	prepared := 0
+	prepare := func(*models.Product) { prepared++ }
+	first := models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(1980, "JPY"))
+	if issues, err := gate.Save(PageProduct, first, prepare); err != nil || len(issues) > 0 {
+		t.Fatalf("Expected new product saved, got %v, %v", issues, err)
+	}
+
+	// A price more than doubled exceeds the configured ratio
+	jump := models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(4980, "JPY"))
+	issues, err := gate.Save(PageProduct, jump, prepare)
+	if err != nil || len(issues) != 1 || issues[0].Rule != RulePriceJump {
+		t.Fatalf("Expected price jump quarantined, got %v, %v", issues, err)
+	}
+	if entry := store.quarantined["item"]; entry == nil || *entry.PreviousPrice != first.CurrentPrice {
+		t.Errorf("Expected quarantine entry with the stored price, got %+v", entry)
+	}
+
+	next := models.NewProduct("item", "Item", "", "rakuten", models.NewMoney(1780, "JPY"))
+	if issues, err := gate.Save(PageProduct, next, prepare); err != nil || len(issues) > 0 {
+		t.Fatalf("Expected valid product saved, got %v, %v", issues, err)
+	}
+	if saved := store.products["item"]; len(saved.PriceHistory) != 2 {
+		t.Errorf("Expected the stored history extended, got %+v", saved.PriceHistory)
+	}
+	if prepared != 2 {
+		t.Errorf("Expected only saved products prepared, got %d", prepared)
+	}
+	if report := gate.Monitor.Report(); len(report) != 1 || report[0].Scrapes != 3 || report[0].Quarantined != 1 {
+		t.Errorf("Expected every result monitored, got %+v", report)
+	}
+}
+
+func TestGateSaveKeepsPageFields(t *testing.T) {
+	store := &memoryStore{products: make(map[string]*models.Product), quarantined: make(map[string]*models.QuarantinedProduct)}
+	gate := &Gate{Store: store, Quarantine: memoryQuarantine{store}, Rules: NewRules(0)}
+
+	page := models.NewProduct("shop:item", "Item", "https://item.rakuten.co.jp/shop/item/", "rakuten", models.NewMoney(1980, "JPY"))
+	page.SetIdentifier("4902370548495")
+	page.Description = "説明"
+	page.SetVariants([]models.Variant{{SKU: "xl", Price: models.NewMoney(1980, "JPY")}})
+	if issues, err := gate.Save(PageProduct, page, nil); err != nil || len(issues) > 0 {
+		t.Fatalf("Expected the page result saved, got %v, %v", issues, err)
+	}
+
+	listing := models.NewProduct("shop:item", "Item", "https://item.rakuten.co.jp/shop/item/", "rakuten", models.NewMoney(1880, "JPY"))
+	if issues, err := gate.Save(PageListing, listing, nil); err != nil || len(issues) > 0 {
+		t.Fatalf("Expected the listing result saved, got %v, %v", issues, err)
+	}
+	saved := store.products["shop:item"]
+	if saved.GTIN != "4902370548495" || saved.Description != "説明" || len(saved.Variants) != 1 || len(saved.PriceHistory) != 2 {
+		t.Errorf("Expected the page's fields kept by the listing save, got %+v", saved)
+	}
+}
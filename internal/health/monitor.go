@@ -0,0 +1,249 @@
+package health
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// Page types whose results are tracked separately: product pages carry
+// fields that listing cards never show
+const (
+	PageProduct = "product"
+	PageListing = "listing"
+)
+
+// Field statuses, from best to worst
+const (
+	StatusOK        = "ok"
+	StatusDegrading = "degrading"
+	StatusFailing   = "failing"
+)
+
+// DefaultWindow is the number of recent results a field's recent rate is
+// computed over
+const DefaultWindow = 50
+
+// minSamples is the number of results needed before a status other than ok
+// is reported
+const minSamples = 5
+
+// Thresholds of the field status rules
+const (
+	// requiredDegrading and requiredFailing apply to the fields every page
+	// must have
+	requiredDegrading = 0.9
+	requiredFailing   = 0.5
+	// maxRateDrop is how far an optional field's recent rate may fall below
+	// its overall rate
+	maxRateDrop = 0.25
+)
+
+// fieldExtractors report whether a field was extracted. Name and price are
+// required; the others are missing on some pages anyway and judged against
+// their own history.
+var fieldExtractors = map[string]func(p *models.Product) bool{
+	"name":         func(p *models.Product) bool { return p.Name != "" },
+	"price":        func(p *models.Product) bool { return p.CurrentPrice.IsPositive() },
+	"image_url":    func(p *models.Product) bool { return p.ImageURL != "" },
+	"description":  func(p *models.Product) bool { return p.Description != "" },
+	"gtin":         func(p *models.Product) bool { return p.GTIN != "" },
+	"availability": func(p *models.Product) bool { return p.Availability != models.AvailabilityUnknown },
+	"shipping_fee": func(p *models.Product) bool { return p.ShippingFee != nil },
+	"points":       func(p *models.Product) bool { return p.Points > 0 },
+	"rating":       func(p *models.Product) bool { return p.Rating > 0 },
+	"review_count": func(p *models.Product) bool { return p.ReviewCount > 0 },
+}
+
+// requiredFields must be extracted from every page
+var requiredFields = map[string]bool{"name": true, "price": true}
+
+// FieldHealth reports how reliably a field is extracted
+type FieldHealth struct {
+	Field      string  `json:"field"`
+	Samples    int     `json:"samples"`     // results seen
+	Rate       float64 `json:"rate"`        // share of all results with the field
+	RecentRate float64 `json:"recent_rate"` // share of the recent window with the field
+	Status     string  `json:"status"`
+}
+
+// PageHealth reports the extraction health of one page type of a site
+type PageHealth struct {
+	Website     string        `json:"website"`
+	Page        string        `json:"page"`
+	Scrapes     int           `json:"scrapes"`
+	Quarantined int           `json:"quarantined"`
+	Status      string        `json:"status"` // worst field status
+	LastScrape  time.Time     `json:"last_scrape"`
+	Fields      []FieldHealth `json:"fields"`
+}
+
+// fieldStats counts the results of one field
+type fieldStats struct {
+	total, filled int
+	recent        []bool // ring buffer of the last window results
+	next          int
+}
+
+// pageStats counts the results of one page type of a site
+type pageStats struct {
+	scrapes, quarantined int
+	last                 time.Time
+	fields               map[string]*fieldStats
+}
+
+// pageKey identifies a page type of a site
+type pageKey struct {
+	website, page string
+}
+
+// Monitor collects extraction results in memory only: the counts start
+// over whenever the process restarts, and each process (the API server, a
+// CLI run) keeps its own
+type Monitor struct {
+	window int
+	pages  map[pageKey]*pageStats
+	mutex  sync.Mutex
+}
+
+// NewMonitor creates a monitor judging recent rates over window results
+// (DefaultWindow if not positive)
+func NewMonitor(window int) *Monitor {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Monitor{
+		window: window,
+		pages:  make(map[pageKey]*pageStats),
+	}
+}
+
+// Record counts a scraped product of a page type, and whether it was
+// quarantined
+func (m *Monitor) Record(page string, p *models.Product, quarantined bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := pageKey{p.Website, page}
+	stats, ok := m.pages[key]
+	if !ok {
+		stats = &pageStats{fields: make(map[string]*fieldStats)}
+		m.pages[key] = stats
+	}
+
+	stats.scrapes++
+	stats.last = time.Now()
+	if quarantined {
+		stats.quarantined++
+	}
+
+	for field, extracted := range fieldExtractors {
+		fs, ok := stats.fields[field]
+		if !ok {
+			fs = &fieldStats{}
+			stats.fields[field] = fs
+		}
+		fs.add(extracted(p), m.window)
+	}
+}
+
+// add counts one result
+func (fs *fieldStats) add(filled bool, window int) {
+	fs.total++
+	if filled {
+		fs.filled++
+	}
+
+	if len(fs.recent) < window {
+		fs.recent = append(fs.recent, filled)
+		return
+	}
+	fs.recent[fs.next] = filled
+	fs.next = (fs.next + 1) % window
+}
+
+// Report returns the health of every site and page type seen, sorted by
+// website and page
+func (m *Monitor) Report() []PageHealth {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	report := make([]PageHealth, 0, len(m.pages))
+	for key, stats := range m.pages {
+		ph := PageHealth{
+			Website:     key.website,
+			Page:        key.page,
+			Scrapes:     stats.scrapes,
+			Quarantined: stats.quarantined,
+			Status:      StatusOK,
+			LastScrape:  stats.last,
+		}
+
+		for field, fs := range stats.fields {
+			fh := fs.health(field)
+			if severity(fh.Status) > severity(ph.Status) {
+				ph.Status = fh.Status
+			}
+			ph.Fields = append(ph.Fields, fh)
+		}
+		sort.Slice(ph.Fields, func(i, j int) bool { return ph.Fields[i].Field < ph.Fields[j].Field })
+
+		report = append(report, ph)
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Website != report[j].Website {
+			return report[i].Website < report[j].Website
+		}
+		return report[i].Page < report[j].Page
+	})
+	return report
+}
+
+// health judges a field. Required fields must be present on nearly every
+// page; optional fields are degrading when their recent rate falls well
+// below their overall rate, and failing once they stop appearing entirely.
+func (fs *fieldStats) health(field string) FieldHealth {
+	recentFilled := 0
+	for _, filled := range fs.recent {
+		if filled {
+			recentFilled++
+		}
+	}
+
+	fh := FieldHealth{
+		Field:      field,
+		Samples:    fs.total,
+		Rate:       float64(fs.filled) / float64(fs.total),
+		RecentRate: float64(recentFilled) / float64(len(fs.recent)),
+		Status:     StatusOK,
+	}
+	if fs.total < minSamples {
+		return fh
+	}
+
+	switch {
+	case requiredFields[field] && fh.RecentRate < requiredFailing:
+		fh.Status = StatusFailing
+	case requiredFields[field] && fh.RecentRate < requiredDegrading:
+		fh.Status = StatusDegrading
+	case fh.RecentRate == 0 && fs.filled > 0:
+		fh.Status = StatusFailing
+	case fh.Rate-fh.RecentRate > maxRateDrop:
+		fh.Status = StatusDegrading
+	}
+	return fh
+}
+
+// severity orders statuses from ok to failing
+func severity(status string) int {
+	switch status {
+	case StatusFailing:
+		return 2
+	case StatusDegrading:
+		return 1
+	}
+	return 0
+}
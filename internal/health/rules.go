@@ -0,0 +1,116 @@
+// Package health validates scraped products before they replace stored data
+// and tracks how reliably each site's extraction fills product fields, so a
+// broken selector shows up as a degrading field instead of silently saving
+// empty or zero values.
+package health
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// Validation rule names
+const (
+	RuleRequired  = "required"
+	RulePositive  = "positive"
+	RulePriceJump = "price_jump"
+)
+
+// DefaultMaxPriceRatio is the largest factor a price may move between two
+// scrapes before the result is treated as suspicious
+const DefaultMaxPriceRatio = 3.0
+
+// Rules are the validation rules applied to a scraped product
+type Rules struct {
+	// MaxPriceRatio bounds the change from the stored price in either
+	// direction, e.g. 3 flags a drop below a third or a rise above triple.
+	// 0 disables the check.
+	MaxPriceRatio float64
+}
+
+// DefaultRules returns the rules used unless configured otherwise
+func DefaultRules() Rules {
+	return Rules{MaxPriceRatio: DefaultMaxPriceRatio}
+}
+
+// NewRules returns the default rules with a configured maximum price ratio,
+// keeping the default for 0
+func NewRules(maxPriceRatio float64) Rules {
+	rules := DefaultRules()
+	if maxPriceRatio > 0 {
+		rules.MaxPriceRatio = maxPriceRatio
+	}
+	return rules
+}
+
+// Validate checks a scraped product against the rules. previous is the
+// stored product, or nil for a new one. A product without issues can be
+// saved.
+func (r Rules) Validate(p, previous *models.Product) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+
+	if p.Name == "" {
+		issues = append(issues, models.ValidationIssue{
+			Field:   "name",
+			Rule:    RuleRequired,
+			Message: "name is empty",
+		})
+	}
+
+	if !p.CurrentPrice.IsPositive() {
+		issues = append(issues, models.ValidationIssue{
+			Field:   "price",
+			Rule:    RulePositive,
+			Message: fmt.Sprintf("price is %s", p.CurrentPrice),
+		})
+	} else if previous != nil && r.MaxPriceRatio > 0 {
+		if price := comparablePrice(p, previous); r.implausibleJump(previous.CurrentPrice, price) {
+			issues = append(issues, models.ValidationIssue{
+				Field:   "price",
+				Rule:    RulePriceJump,
+				Message: fmt.Sprintf("price moved from %s to %s", previous.CurrentPrice, price),
+			})
+		}
+	}
+
+	return issues
+}
+
+// comparablePrice returns the price of p to compare with the stored price:
+// the price of the variant the stored product tracks, since a fresh scrape
+// only picks the tracked variant up when its history is merged
+func comparablePrice(p, previous *models.Product) models.Money {
+	if p.TrackedVariant == "" && previous.TrackedVariant != "" {
+		if v := p.Variant(previous.TrackedVariant); v != nil && v.Price.IsPositive() {
+			return v.Price
+		}
+	}
+	return p.CurrentPrice
+}
+
+// implausibleJump reports whether the move from old to price exceeds
+// MaxPriceRatio. Prices in different currencies aren't compared.
+func (r Rules) implausibleJump(old, price models.Money) bool {
+	if !old.IsPositive() || old.Currency != price.Currency {
+		return false
+	}
+
+	ratio := price.Float64() / old.Float64()
+	return ratio > r.MaxPriceRatio || ratio < 1/r.MaxPriceRatio
+}
+
+// ErrQuarantined is returned when a scraped product was quarantined instead
+// of saved
+var ErrQuarantined = errors.New("quarantined")
+
+// QuarantineError wraps ErrQuarantined with the issues found
+func QuarantineError(issues []models.ValidationIssue) error {
+	messages := make([]string, len(issues))
+	for i, issue := range issues {
+		messages[i] = issue.Message
+	}
+	return fmt.Errorf("%w: %s", ErrQuarantined, strings.Join(messages, "; "))
+}
@@ -0,0 +1,329 @@
+// Package httpcache is an on-disk HTTP cache used as the transport of the
+// scrapers' collectors. Responses are kept per canonical URL for a TTL that
+// depends on the site; stale entries are revalidated with ETag and
+// If-Modified-Since before they are fetched again.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTTL is used for hosts without a TTL of their own
+const DefaultTTL = time.Hour
+
+// Header marks responses served from the cache without a request
+const Header = "X-Scrapy-Cache"
+
+// Filter reports whether a successful response may be stored, e.g. false for
+// a block page served with status 200
+type Filter func(status int, header http.Header, body []byte) bool
+
+// Stats counts cache outcomes
+type Stats struct {
+	Hits          int64 `json:"hits"`          // served from cache without a request
+	Revalidations int64 `json:"revalidations"` // stale entries confirmed by a 304
+	Misses        int64 `json:"misses"`        // fetched in full
+}
+
+// entry is a cached response
+type entry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+}
+
+// Cache is an http.RoundTripper caching successful GET responses on disk
+type Cache struct {
+	dir        string
+	defaultTTL time.Duration
+	transport  http.RoundTripper
+
+	mutex   sync.RWMutex
+	ttls    map[string]time.Duration // keyed by host suffix, e.g. "rakuten.co.jp"
+	filters map[string]Filter        // keyed like ttls
+
+	hits, revalidations, misses atomic.Int64
+
+	now func() time.Time
+}
+
+// New creates a cache storing entries under dir and fetching through
+// transport (http.DefaultTransport if nil)
+func New(dir string, defaultTTL time.Duration, transport http.RoundTripper) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if defaultTTL <= 0 {
+		defaultTTL = DefaultTTL
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Cache{
+		dir:        dir,
+		defaultTTL: defaultTTL,
+		transport:  transport,
+		ttls:       make(map[string]time.Duration),
+		filters:    make(map[string]Filter),
+		now:        time.Now,
+	}, nil
+}
+
+// SetTTL sets the TTL for a domain and its subdomains
+func (c *Cache) SetTTL(domain string, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.ttls[strings.ToLower(domain)] = ttl
+}
+
+// TTL returns the TTL for a host: that of the longest matching domain, or
+// the default
+func (c *Cache) TTL(host string) time.Duration {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if domain := match(host, c.ttls); domain != "" {
+		return c.ttls[domain]
+	}
+	return c.defaultTTL
+}
+
+// SetFilter sets the filter deciding which responses of a domain and its
+// subdomains are stored
+func (c *Cache) SetFilter(domain string, filter Filter) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.filters[strings.ToLower(domain)] = filter
+}
+
+// storable reports whether a response for host passes the filter of the
+// longest matching domain, if any
+func (c *Cache) storable(host string, status int, header http.Header, body []byte) bool {
+	c.mutex.RLock()
+	domain := match(host, c.filters)
+	filter := c.filters[domain]
+	c.mutex.RUnlock()
+
+	return domain == "" || filter(status, header, body)
+}
+
+// match returns the longest key of domains that host equals or is a
+// subdomain of, or "" if there is none
+func match[T any](host string, domains map[string]T) string {
+	host = strings.ToLower(host)
+	matched := ""
+	for domain := range domains {
+		if (host == domain || strings.HasSuffix(host, "."+domain)) && len(domain) > len(matched) {
+			matched = domain
+		}
+	}
+	return matched
+}
+
+// Evict removes the entry for u, e.g. once its body turned out to be a block
+// page
+func (c *Cache) Evict(u *url.URL) {
+	path := c.path(CanonicalURL(u))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to evict cache entry for %s: %v", u, err)
+	}
+}
+
+// Replayed reports whether a response was served from the cache without a
+// request
+func (c *Cache) Replayed(header http.Header) bool {
+	return header.Get(Header) != ""
+}
+
+// Stats returns the cache counters
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:          c.hits.Load(),
+		Revalidations: c.revalidations.Load(),
+		Misses:        c.misses.Load(),
+	}
+}
+
+// RoundTrip serves GET requests from the cache when fresh, revalidates stale
+// entries and stores new successful responses that pass the host's filter
+func (c *Cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.transport.RoundTrip(req)
+	}
+
+	key := CanonicalURL(req.URL)
+	cached := c.load(key)
+
+	if cached != nil && c.now().Sub(cached.StoredAt) < c.TTL(req.URL.Host) {
+		c.hits.Add(1)
+		resp := cached.response(req)
+		resp.Header.Set(Header, "hit")
+		return resp, nil
+	}
+
+	// Ask the server whether a stale entry is still current
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		c.revalidations.Add(1)
+		cached.StoredAt = c.now()
+		c.store(key, cached)
+		return cached.response(req), nil
+	}
+
+	c.misses.Add(1)
+	if resp.StatusCode != http.StatusOK || strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if !c.storable(req.URL.Hostname(), resp.StatusCode, resp.Header, body) {
+		return resp, nil
+	}
+
+	// Cookies belong to the visitor the page was fetched for, not to
+	// whoever it is replayed to
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	c.store(key, &entry{
+		URL:        key,
+		StatusCode: resp.StatusCode,
+		Header:     header,
+		Body:       body,
+		StoredAt:   c.now(),
+	})
+	return resp, nil
+}
+
+// response rebuilds an http.Response from the entry
+func (e *entry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// path returns the file holding the entry for key
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name+".json")
+}
+
+// load reads the entry for key, or nil if there is none
+func (c *Cache) load(key string) *entry {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.URL != key {
+		return nil
+	}
+	return &e
+}
+
+// store writes the entry for key; failures only cost a future miss
+func (c *Cache) store(key string, e *entry) {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("Warning: failed to create cache directory: %v", err)
+		return
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Warning: failed to encode cache entry for %s: %v", key, err)
+		return
+	}
+
+	// Write to a temporary file of its own first so readers never see a
+	// partial entry and concurrent stores of the key don't interleave
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		log.Printf("Warning: failed to write cache entry for %s: %v", key, err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("Warning: failed to write cache entry for %s: %v", key, err)
+	}
+}
+
+// CanonicalURL normalizes a URL for use as a cache key: lower-case scheme and
+// host, no default port, no fragment and sorted query parameters
+func CanonicalURL(u *url.URL) string {
+	c := *u
+	c.Scheme = strings.ToLower(c.Scheme)
+	c.Host = strings.ToLower(c.Host)
+	if (c.Scheme == "http" && strings.HasSuffix(c.Host, ":80")) || (c.Scheme == "https" && strings.HasSuffix(c.Host, ":443")) {
+		c.Host = c.Host[:strings.LastIndex(c.Host, ":")]
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	c.Fragment = ""
+	c.RawFragment = ""
+
+	if c.RawQuery != "" {
+		params := strings.Split(c.RawQuery, "&")
+		sort.Strings(params)
+		c.RawQuery = strings.Join(params, "&")
+	}
+	return c.String()
+}
@@ -0,0 +1,165 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheServesRevalidatesAndCounts(t *testing.T) {
+	var requests, conditional int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Write([]byte("<html>page</html>"))
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir(), time.Minute, nil)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	client := &http.Client{Transport: cache}
+
+	var cookie string
+	get := func(u string) string {
+		t.Helper()
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", u, err)
+		}
+		defer resp.Body.Close()
+		cookie = resp.Header.Get("Set-Cookie")
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// Miss, then a hit for the same page with reordered query parameters.
+	// The cookie is passed on but not replayed.
+	if get(server.URL + "/item?b=2&a=1"); cookie != "session=abc" {
+		t.Errorf("Expected the cookie on the fetched page, got %q", cookie)
+	}
+	if body := get(server.URL + "/item?a=1&b=2#reviews"); body != "<html>page</html>" || cookie != "" {
+		t.Errorf("Unexpected cached body %q with cookie %q", body, cookie)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request to the server, got %d", requests)
+	}
+
+	// Once stale, the entry is revalidated with its ETag
+	now = now.Add(2 * time.Minute)
+	if body := get(server.URL + "/item?a=1&b=2"); body != "<html>page</html>" {
+		t.Errorf("Unexpected revalidated body: %q", body)
+	}
+	if conditional != 1 {
+		t.Errorf("Expected 1 conditional request, got %d", conditional)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Revalidations != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestCacheTTLPerDomain(t *testing.T) {
+	cache, err := New(t.TempDir(), time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.SetTTL("rakuten.co.jp", 10*time.Minute)
+	cache.SetTTL("ranking.rakuten.co.jp", time.Minute)
+
+	tests := map[string]time.Duration{
+		"item.rakuten.co.jp":    10 * time.Minute,
+		"rakuten.co.jp":         10 * time.Minute,
+		"ranking.rakuten.co.jp": time.Minute,
+		"example.com":           time.Hour,
+		"notrakuten.co.jp":      time.Hour,
+	}
+	for host, expected := range tests {
+		if got := cache.TTL(host); got != expected {
+			t.Errorf("TTL(%q) = %v; want %v", host, got, expected)
+		}
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	tests := map[string]string{
+		"HTTPS://Item.Rakuten.co.jp:443/book/1/?b=2&a=1#x": "https://item.rakuten.co.jp/book/1/?a=1&b=2",
+		"http://example.com":        "http://example.com/",
+		"http://example.com:8080/a": "http://example.com:8080/a",
+	}
+	for raw, expected := range tests {
+		u, _ := url.Parse(raw)
+		if got := CanonicalURL(u); got != expected {
+			t.Errorf("CanonicalURL(%q) = %q; want %q", raw, got, expected)
+		}
+	}
+}
+
+func TestCacheSkipsFilteredResponses(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Write([]byte("<html>captcha</html>"))
+			return
+		}
+		w.Write([]byte("<html>page</html>"))
+	}))
+	defer server.Close()
+
+	cache, err := New(t.TempDir(), time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.SetFilter("127.0.0.1", func(status int, header http.Header, body []byte) bool {
+		return !strings.Contains(string(body), "captcha")
+	})
+	client := &http.Client{Transport: cache}
+
+	get := func() (string, http.Header) {
+		t.Helper()
+		resp, err := client.Get(server.URL + "/item")
+		if err != nil {
+			t.Fatalf("GET failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), resp.Header
+	}
+
+	// The block page is passed on but not stored, so the next visit
+	// reaches the server and the real page is cached
+	if body, _ := get(); body != "<html>captcha</html>" {
+		t.Errorf("Expected the block page, got %q", body)
+	}
+	if body, header := get(); body != "<html>page</html>" || cache.Replayed(header) {
+		t.Errorf("Expected the real page from the server, got %q", body)
+	}
+	if body, header := get(); body != "<html>page</html>" || !cache.Replayed(header) {
+		t.Errorf("Expected the real page from the cache, got %q", body)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests to the server, got %d", requests)
+	}
+
+	// An evicted entry is fetched again
+	u, _ := url.Parse(server.URL + "/item")
+	cache.Evict(u)
+	if _, header := get(); cache.Replayed(header) || requests != 3 {
+		t.Errorf("Expected a request after eviction, got %d requests", requests)
+	}
+}
@@ -0,0 +1,164 @@
+// Package jobs runs long-running work such as catalog crawls in the
+// background and keeps their status for polling
+package jobs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Job is a unit of background work
+type Job struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"` // e.g. "shop_crawl"
+	Status     string     `json:"status"`
+	Result     any        `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Limits on the finished jobs a manager keeps
+const (
+	DefaultTTL         = time.Hour // how long finished jobs are kept
+	DefaultMaxFinished = 100       // how many finished jobs are kept at most
+)
+
+// Func is the work performed by a job. Its result is stored on the job.
+type Func func() (any, error)
+
+// Manager runs jobs in goroutines and keeps them in memory. Finished jobs
+// are evicted once older than TTL, and the oldest beyond MaxFinished.
+type Manager struct {
+	TTL         time.Duration
+	MaxFinished int
+
+	jobs  map[string]*Job
+	next  int
+	mutex sync.RWMutex
+	wg    sync.WaitGroup
+	now   func() time.Time
+}
+
+// NewManager creates a job manager keeping finished jobs for DefaultTTL, at
+// most DefaultMaxFinished of them
+func NewManager() *Manager {
+	return &Manager{
+		TTL:         DefaultTTL,
+		MaxFinished: DefaultMaxFinished,
+		jobs:        make(map[string]*Job),
+		now:         time.Now,
+	}
+}
+
+// Submit starts fn in the background and returns a snapshot of the new job
+func (m *Manager) Submit(kind string, fn Func) Job {
+	m.mutex.Lock()
+	m.evict()
+	m.next++
+	job := &Job{
+		ID:        fmt.Sprintf("%s-%d", kind, m.next),
+		Kind:      kind,
+		Status:    StatusPending,
+		CreatedAt: m.now(),
+	}
+	m.jobs[job.ID] = job
+	snapshot := *job
+	m.mutex.Unlock()
+
+	m.wg.Add(1)
+	go m.run(job, fn)
+
+	return snapshot
+}
+
+// run executes fn and records its outcome on job
+func (m *Manager) run(job *Job, fn Func) {
+	defer m.wg.Done()
+
+	m.mutex.Lock()
+	started := m.now()
+	job.Status = StatusRunning
+	job.StartedAt = &started
+	m.mutex.Unlock()
+
+	result, err := fn()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	finished := m.now()
+	job.FinishedAt = &finished
+	job.Result = result
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusSucceeded
+	}
+	m.evict()
+}
+
+// evict drops finished jobs older than TTL and the oldest finished jobs
+// beyond MaxFinished. The caller holds the lock.
+func (m *Manager) evict() {
+	var finished []*Job
+	for id, job := range m.jobs {
+		if job.FinishedAt == nil {
+			continue
+		}
+		if m.TTL > 0 && m.now().Sub(*job.FinishedAt) > m.TTL {
+			delete(m.jobs, id)
+			continue
+		}
+		finished = append(finished, job)
+	}
+
+	if m.MaxFinished <= 0 || len(finished) <= m.MaxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(*finished[j].FinishedAt) })
+	for _, job := range finished[:len(finished)-m.MaxFinished] {
+		delete(m.jobs, job.ID)
+	}
+}
+
+// Get returns a snapshot of the job with the given ID
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns snapshots of all jobs, oldest first
+func (m *Manager) List() []Job {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Wait blocks until all submitted jobs have finished
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
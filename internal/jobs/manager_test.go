@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManagerRunsJobs(t *testing.T) {
+	m := NewManager()
+
+	ok := m.Submit("test", func() (any, error) { return 42, nil })
+	failed := m.Submit("test", func() (any, error) { return nil, errors.New("boom") })
+	if ok.ID == failed.ID {
+		t.Fatalf("Expected unique job IDs, got %s twice", ok.ID)
+	}
+	if ok.Status != StatusPending {
+		t.Errorf("Expected new job to be pending, got %s", ok.Status)
+	}
+
+	m.Wait()
+
+	job, found := m.Get(ok.ID)
+	if !found {
+		t.Fatalf("Job %s not found", ok.ID)
+	}
+	if job.Status != StatusSucceeded || job.Result != 42 || job.FinishedAt == nil {
+		t.Errorf("Unexpected succeeded job: %+v", job)
+	}
+
+	job, _ = m.Get(failed.ID)
+	if job.Status != StatusFailed || job.Error != "boom" {
+		t.Errorf("Unexpected failed job: %+v", job)
+	}
+
+	if _, found := m.Get("missing"); found {
+		t.Error("Expected missing job not to be found")
+	}
+	if n := len(m.List()); n != 2 {
+		t.Errorf("Expected 2 jobs, got %d", n)
+	}
+}
+
+func TestManagerEvictsFinishedJobs(t *testing.T) {
+	m := NewManager()
+	m.MaxFinished = 2
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		ids = append(ids, m.Submit("test", func() (any, error) { return nil, nil }).ID)
+		m.Wait()
+		now = now.Add(time.Second)
+	}
+	if _, found := m.Get(ids[0]); found {
+		t.Error("Expected the oldest finished job beyond the maximum to be evicted")
+	}
+	if n := len(m.List()); n != 2 {
+		t.Errorf("Expected 2 jobs kept, got %d", n)
+	}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	running := m.Submit("test", func() (any, error) { close(started); <-release; return nil, nil })
+	<-started
+	now = now.Add(DefaultTTL + time.Minute)
+	m.Submit("test", func() (any, error) { return nil, nil })
+	if _, found := m.Get(ids[2]); found {
+		t.Error("Expected finished jobs older than the TTL to be evicted")
+	}
+	if _, found := m.Get(running.ID); !found {
+		t.Error("Expected running jobs to be kept")
+	}
+	close(release)
+	m.Wait()
+}
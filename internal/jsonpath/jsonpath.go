@@ -0,0 +1,123 @@
+// Package jsonpath selects values from decoded JSON documents with the
+// subset of JSONPath that field mappings need: member names in dot or
+// bracket notation and array indexes, e.g. $.items[0].price or
+// $['item-data'].stock[-1].
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// step is one member name or array index of a path
+type step struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// Path is a compiled JSONPath expression
+type Path struct {
+	expr  string
+	steps []step
+}
+
+// Compile parses a path starting at the root, $
+func Compile(expr string) (*Path, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(expr), "$")
+	if !ok {
+		return nil, fmt.Errorf("jsonpath %q: must start with $", expr)
+	}
+
+	p := &Path{expr: expr}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("jsonpath %q: empty member name", expr)
+			}
+			p.steps = append(p.steps, step{key: key, isKey: true})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("jsonpath %q: unclosed [", expr)
+			}
+			s, err := parseBracket(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath %q: %w", expr, err)
+			}
+			p.steps = append(p.steps, s)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("jsonpath %q: unexpected %q", expr, rest[0])
+		}
+	}
+	return p, nil
+}
+
+// MustCompile is like Compile but panics on an invalid path
+func MustCompile(expr string) *Path {
+	p, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// parseBracket parses the content of a bracket: a quoted member name or an
+// index, negative indexes counting from the end
+func parseBracket(s string) (step, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return step{key: s[1 : len(s)-1], isKey: true}, nil
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return step{}, fmt.Errorf("invalid index %q", s)
+	}
+	return step{index: index}, nil
+}
+
+// String returns the expression the path was compiled from
+func (p *Path) String() string {
+	return p.expr
+}
+
+// Get returns the value at the path in doc, as decoded by encoding/json
+// into an any, and whether it exists
+func (p *Path) Get(doc any) (any, bool) {
+	v := doc
+	for _, s := range p.steps {
+		if s.isKey {
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if v, ok = obj[s.key]; !ok {
+				return nil, false
+			}
+			continue
+		}
+
+		arr, ok := v.([]any)
+		if !ok {
+			return nil, false
+		}
+		i := s.index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return nil, false
+		}
+		v = arr[i]
+	}
+	return v, true
+}
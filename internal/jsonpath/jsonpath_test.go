@@ -0,0 +1,51 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	var doc any
+	json.Unmarshal([]byte(`{
+		"item": {"price": 1980, "stock": {"status": "in_stock"}},
+		"item-data": [{"sku": "A"}, {"sku": "B"}],
+		"empty": null
+	}`), &doc)
+
+	tests := []struct {
+		path  string
+		want  any
+		found bool
+	}{
+		{"$", doc, true},
+		{"$.item.price", float64(1980), true},
+		{"$.item['stock'].status", "in_stock", true},
+		{`$["item-data"][1].sku`, "B", true},
+		{"$['item-data'][-1].sku", "B", true},
+		{"$.empty", nil, true},
+		{"$.item.missing", nil, false},
+		{"$['item-data'][2]", nil, false},
+		{"$.item[0]", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, found := MustCompile(tt.path).Get(doc)
+			if found != tt.found {
+				t.Fatalf("Expected found=%t, got %t", tt.found, found)
+			}
+			if tt.path != "$" && got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, expr := range []string{"item.price", "$.", "$.items[0", "$.items[x]", "$..price", "$items"} {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
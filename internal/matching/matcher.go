@@ -0,0 +1,219 @@
+// Package matching groups products scraped from different websites into items,
+// so that offers for the same thing can be compared
+package matching
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// DefaultTitleThreshold is the minimum title similarity for two products
+// without a shared identifier to be considered the same item
+const DefaultTitleThreshold = 0.8
+
+// Match methods recorded on offers
+const (
+	MatchedByGTIN  = "gtin"
+	MatchedByTitle = "title"
+)
+
+// Item is a group of products that represent the same thing
+type Item struct {
+	ID       string            `json:"id"`
+	GTIN     string            `json:"gtin,omitempty"`
+	Name     string            `json:"name"`
+	Products []*models.Product `json:"-"`
+}
+
+// Offer is a single website's current price for an item
+type Offer struct {
+	ProductID   string       `json:"product_id"`
+	Website     string       `json:"website"`
+	Name        string       `json:"name"`
+	URL         string       `json:"url"`
+	Price       models.Money `json:"price"`
+	LastUpdated time.Time    `json:"last_updated"`
+	MatchedBy   string       `json:"matched_by"`
+
+	// ConvertedPrice is set when offers are compared in a target currency;
+	// ConversionError tells why it couldn't be
+	ConvertedPrice  *models.Money `json:"converted_price,omitempty"`
+	ConversionError string        `json:"conversion_error,omitempty"`
+}
+
+// Matcher groups products into items by identifier or title similarity
+type Matcher struct {
+	// TitleThreshold is the minimum TitleSimilarity for a fuzzy match
+	TitleThreshold float64
+}
+
+// NewMatcher creates a matcher with the default title threshold
+func NewMatcher() *Matcher {
+	return &Matcher{TitleThreshold: DefaultTitleThreshold}
+}
+
+// Group partitions products into items. Products sharing a GTIN are always
+// grouped; products are grouped by title only if they do not carry
+// conflicting GTINs. Items are returned sorted by ID.
+func (m *Matcher) Group(products []*models.Product) []*Item {
+	// Sort for deterministic item IDs and representatives
+	sorted := make([]*models.Product, len(products))
+	copy(sorted, products)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	parent := make([]int, len(sorted))
+	gtins := make([]string, len(sorted))
+	for i, p := range sorted {
+		parent[i] = i
+		gtins[i] = p.GTIN
+	}
+
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		ra, rb := find(a), find(b)
+		if ra == rb {
+			return
+		}
+		if rb < ra {
+			ra, rb = rb, ra
+		}
+		parent[rb] = ra
+		if gtins[ra] == "" {
+			gtins[ra] = gtins[rb]
+		}
+	}
+
+	// Exact identifier matches
+	byGTIN := make(map[string]int)
+	for i, p := range sorted {
+		if p.GTIN == "" {
+			continue
+		}
+		if j, ok := byGTIN[p.GTIN]; ok {
+			union(i, j)
+		} else {
+			byGTIN[p.GTIN] = i
+		}
+	}
+
+	// Fuzzy title matches between groups whose identifiers don't conflict
+	for i := range sorted {
+		for j := i + 1; j < len(sorted); j++ {
+			ri, rj := find(i), find(j)
+			if ri == rj {
+				continue
+			}
+			if gtins[ri] != "" && gtins[rj] != "" && gtins[ri] != gtins[rj] {
+				continue
+			}
+			if TitleSimilarity(sorted[i].Name, sorted[j].Name) >= m.TitleThreshold {
+				union(i, j)
+			}
+		}
+	}
+
+	groups := make(map[int]*Item)
+	var items []*Item
+	for i, p := range sorted {
+		root := find(i)
+		item, ok := groups[root]
+		if !ok {
+			item = &Item{ID: sorted[root].ID, GTIN: gtins[root], Name: sorted[root].Name}
+			if item.GTIN != "" {
+				item.ID = item.GTIN
+			}
+			groups[root] = item
+			items = append(items, item)
+		}
+		item.Products = append(item.Products, p)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// FindItem groups products and returns the item with the given ID. The ID
+// may be an item ID, a GTIN or the ID of any product in the item.
+func (m *Matcher) FindItem(products []*models.Product, id string) *Item {
+	for _, item := range m.Group(products) {
+		if item.ID == id || item.GTIN == id {
+			return item
+		}
+		for _, p := range item.Products {
+			if p.ID == id {
+				return item
+			}
+		}
+	}
+	return nil
+}
+
+// Offers returns the cheapest current offer per website for the item,
+// sorted by ascending price. Products without a known price are skipped.
+// Offers in different currencies are ordered by currency code first.
+func (item *Item) Offers() []Offer {
+	cheapest := make(map[string]*models.Product)
+	for _, p := range item.Products {
+		if !p.CurrentPrice.IsPositive() {
+			continue
+		}
+		if cur, ok := cheapest[p.Website]; !ok || models.LessPrice(p.CurrentPrice, cur.CurrentPrice) {
+			cheapest[p.Website] = p
+		}
+	}
+
+	offers := make([]Offer, 0, len(cheapest))
+	for _, p := range cheapest {
+		matchedBy := MatchedByTitle
+		if item.GTIN != "" && p.GTIN == item.GTIN {
+			matchedBy = MatchedByGTIN
+		}
+		offers = append(offers, Offer{
+			ProductID:   p.ID,
+			Website:     p.Website,
+			Name:        p.Name,
+			URL:         p.URL,
+			Price:       p.CurrentPrice,
+			LastUpdated: p.LastUpdated,
+			MatchedBy:   matchedBy,
+		})
+	}
+
+	SortOffers(offers)
+	return offers
+}
+
+// SortOffers orders offers by converted price where set, otherwise by price.
+// Once any offer is converted, offers that failed to convert follow the
+// converted ones, since their prices aren't comparable.
+func SortOffers(offers []Offer) {
+	converted := false
+	for _, o := range offers {
+		converted = converted || o.ConvertedPrice != nil
+	}
+	price := func(o Offer) models.Money {
+		if o.ConvertedPrice != nil {
+			return *o.ConvertedPrice
+		}
+		return o.Price
+	}
+
+	sort.SliceStable(offers, func(i, j int) bool {
+		if converted && (offers[i].ConvertedPrice == nil) != (offers[j].ConvertedPrice == nil) {
+			return offers[i].ConvertedPrice != nil
+		}
+		a, b := price(offers[i]), price(offers[j])
+		if a != b {
+			return models.LessPrice(a, b)
+		}
+		return offers[i].Website < offers[j].Website
+	})
+}
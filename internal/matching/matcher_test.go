@@ -0,0 +1,113 @@
+package matching
+
+import (
+	"testing"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected string
+	}{
+		{title: "【送料無料】Nintendo Switch 有機ELモデル", expected: "nintendo switch 有機elモデル"},
+		{title: "Ｎｉｎｔｅｎｄｏ　Ｓｗｉｔｃｈ", expected: "nintendo switch"},
+		{title: "[ポイント10倍] ONE PIECE 107 (ジャンプコミックス)", expected: "one piece 107 ジャンプコミックス"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeTitle(tt.title); got != tt.expected {
+			t.Errorf("NormalizeTitle(%q) = %q, expected %q", tt.title, got, tt.expected)
+		}
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	same := TitleSimilarity("【送料無料】Nintendo Switch 有機ELモデル", "Ｎｉｎｔｅｎｄｏ Ｓｗｉｔｃｈ（有機ELモデル）")
+	if same < DefaultTitleThreshold {
+		t.Errorf("Expected similar titles to score >= %.2f, got %.2f", DefaultTitleThreshold, same)
+	}
+
+	different := TitleSimilarity("Nintendo Switch 有機ELモデル", "PlayStation 5 デジタル・エディション")
+	if different >= DefaultTitleThreshold {
+		t.Errorf("Expected different titles to score < %.2f, got %.2f", DefaultTitleThreshold, different)
+	}
+
+	if TitleSimilarity("", "anything") != 0 {
+		t.Error("Expected empty title to have zero similarity")
+	}
+}
+
+func TestGroupAndOffers(t *testing.T) {
+	bookA := models.NewProduct("a-book", "ONE PIECE 107", "https://books.example.com/1", "rakuten", models.NewMoney(528, "JPY"))
+	bookA.SetIdentifier("9780306406157")
+	bookB := models.NewProduct("b-book", "ワンピース 107巻 ジャンプコミックス", "https://shop.example.com/2", "other", models.NewMoney(500, "JPY"))
+	bookB.SetIdentifier("978-0-306-40615-7")
+	bookC := models.NewProduct("c-book", "ONE PIECE 107", "https://shop.example.com/3", "other", models.NewMoney(480, "JPY"))
+	bookC.SetIdentifier("4006381333931") // same title, different code
+
+	switchA := models.NewProduct("a-switch", "【送料無料】Nintendo Switch 有機ELモデル", "https://item.example.com/4", "rakuten", models.NewMoney(37980, "JPY"))
+	switchB := models.NewProduct("b-switch", "Nintendo Switch（有機ELモデル）", "https://shop.example.com/5", "other", models.NewMoney(36800, "JPY"))
+	switchC := models.NewProduct("c-switch", "Nintendo Switch 有機ELモデル 中古", "https://item.example.com/6", "rakuten", models.NewMoney(0, "JPY"))
+
+	matcher := NewMatcher()
+	items := matcher.Group([]*models.Product{switchC, bookA, switchA, bookB, bookC, switchB})
+
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+
+	book := matcher.FindItem([]*models.Product{bookA, bookB, bookC}, "b-book")
+	if book == nil {
+		t.Fatal("Expected to find the book item")
+	}
+	if book.ID != "9780306406157" {
+		t.Errorf("Expected item ID to be the GTIN, got %s", book.ID)
+	}
+	if len(book.Products) != 2 {
+		t.Errorf("Expected 2 products in book item (conflicting GTIN excluded), got %d", len(book.Products))
+	}
+
+	sw := matcher.FindItem([]*models.Product{switchA, switchB, switchC}, "a-switch")
+	if sw == nil {
+		t.Fatal("Expected to find the switch item")
+	}
+
+	offers := sw.Offers()
+	if len(offers) != 2 {
+		t.Fatalf("Expected 2 offers (one per website), got %d", len(offers))
+	}
+	if offers[0].ProductID != "b-switch" || offers[0].Price != models.NewMoney(36800, "JPY") {
+		t.Errorf("Expected cheapest offer b-switch at 36800, got %s at %s", offers[0].ProductID, offers[0].Price)
+	}
+	if offers[1].ProductID != "a-switch" {
+		t.Errorf("Expected rakuten offer a-switch (unpriced product skipped), got %s", offers[1].ProductID)
+	}
+	if offers[0].MatchedBy != MatchedByTitle {
+		t.Errorf("Expected title match, got %s", offers[0].MatchedBy)
+	}
+
+	if matcher.FindItem([]*models.Product{switchA}, "missing") != nil {
+		t.Error("Expected nil for unknown item ID")
+	}
+}
+
+func TestSortOffersUnconvertedLast(t *testing.T) {
+	usd := models.NewMoney(1500, "USD")
+	offers := []Offer{
+		{Website: "a", Price: models.NewMoney(100, "XXX"), ConversionError: "no rate"},
+		{Website: "b", Price: models.NewMoney(300000, "JPY"), ConvertedPrice: &usd},
+		{Website: "c", Price: models.NewMoney(1000, "USD"), ConvertedPrice: &usd},
+	}
+
+	SortOffers(offers)
+
+	var order string
+	for _, o := range offers {
+		order += o.Website
+	}
+	if order != "bca" {
+		t.Errorf("Expected converted offers first by price and website, got %s", order)
+	}
+}
@@ -0,0 +1,87 @@
+package matching
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// promoPattern strips bracketed shop decorations such as 【送料無料】 or [ポイント10倍]
+// that differ between shops listing the same item
+var promoPattern = regexp.MustCompile(`【[^】]*】|\[[^\]]*\]|≪[^≫]*≫|《[^》]*》`)
+
+// NormalizeTitle folds a product title into a comparable form: full-width
+// characters become half-width, case is lowered, shop decorations are removed
+// and punctuation collapses to single spaces
+func NormalizeTitle(title string) string {
+	title = promoPattern.ReplaceAllString(toHalfWidth(title), " ")
+	title = strings.ToLower(title)
+
+	var b strings.Builder
+	space := false
+	for _, r := range title {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		if !space && b.Len() > 0 {
+			b.WriteRune(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// TitleSimilarity returns the Dice coefficient of the character bigrams of two
+// normalized titles, in the range [0, 1]. Bigrams work for Japanese titles,
+// which have no word separators.
+func TitleSimilarity(a, b string) float64 {
+	ga, gb := bigrams(NormalizeTitle(a)), bigrams(NormalizeTitle(b))
+	if len(ga) == 0 || len(gb) == 0 {
+		return 0
+	}
+
+	total := 0
+	for _, n := range ga {
+		total += n
+	}
+	for _, n := range gb {
+		total += n
+	}
+
+	shared := 0
+	for g, na := range ga {
+		if nb, ok := gb[g]; ok {
+			shared += min(na, nb)
+		}
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+// bigrams counts the adjacent rune pairs in s, ignoring spaces
+func bigrams(s string) map[string]int {
+	runes := []rune(strings.ReplaceAll(s, " ", ""))
+	grams := make(map[string]int)
+	if len(runes) == 1 {
+		grams[string(runes)]++
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])]++
+	}
+	return grams
+}
+
+// toHalfWidth converts full-width ASCII variants and the ideographic space
+// to their half-width equivalents
+func toHalfWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case r >= '！' && r <= '～':
+			return r - 0xFEE0
+		}
+		return r
+	}, s)
+}
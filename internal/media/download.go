@@ -0,0 +1,121 @@
+package media
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/crawlpolicy"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// DefaultMaxBytes is the largest image downloaded
+const DefaultMaxBytes = 10 << 20
+
+// downloadTimeout bounds an image download
+const downloadTimeout = 30 * time.Second
+
+// Config configures a Downloader
+type Config struct {
+	Transport http.RoundTripper   // nil uses the default transport
+	Policy    *crawlpolicy.Policy // checks and paces downloads if set
+	UserAgent string
+	MaxBytes  int64 // defaults to DefaultMaxBytes
+}
+
+// Downloader downloads the listing images of products into a store
+type Downloader struct {
+	store     *Store
+	client    *http.Client
+	policy    *crawlpolicy.Policy
+	userAgent string
+	maxBytes  int64
+	now       func() time.Time
+}
+
+// NewDownloader creates a downloader saving images to store
+func NewDownloader(store *Store, cfg Config) *Downloader {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	return &Downloader{
+		store:     store,
+		client:    &http.Client{Transport: cfg.Transport, Timeout: downloadTimeout},
+		policy:    cfg.Policy,
+		userAgent: cfg.UserAgent,
+		maxBytes:  cfg.MaxBytes,
+		now:       time.Now,
+	}
+}
+
+// Store returns the store images are saved to
+func (d *Downloader) Store() *Store {
+	return d.store
+}
+
+// Download fetches the listing image of p, saves it and records the version
+// on p. Products without an image are left alone.
+func (d *Downloader) Download(p *models.Product) error {
+	if p.ImageURL == "" {
+		return nil
+	}
+	u, err := url.Parse(p.ImageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid image URL: %s", p.ImageURL)
+	}
+
+	if d.policy != nil {
+		if err := d.policy.Check(u); err != nil {
+			return err
+		}
+		d.policy.Wait(u)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "image/jpeg,image/png,image/gif,image/*;q=0.8")
+	if d.userAgent != "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	}
+	if p.URL != "" {
+		req.Header.Set("Referer", p.URL)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download image %s: status %d", p.ImageURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, d.maxBytes+1))
+	if err != nil {
+		return fmt.Errorf("failed to download image: %w", err)
+	}
+	if int64(len(data)) > d.maxBytes {
+		return fmt.Errorf("image %s is larger than %d bytes", p.ImageURL, d.maxBytes)
+	}
+
+	meta, err := d.store.Save(p.ImageURL, data)
+	if err != nil {
+		return err
+	}
+
+	now := d.now()
+	p.RecordImage(models.ImageVersion{
+		ID:        meta.ID,
+		URL:       p.ImageURL,
+		Hash:      meta.Hash,
+		Width:     meta.Width,
+		Height:    meta.Height,
+		FirstSeen: now,
+		LastSeen:  now,
+	})
+	return nil
+}
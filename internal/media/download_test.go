@@ -0,0 +1,68 @@
+package media
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestDownload(t *testing.T) {
+	pictures := map[string][]byte{
+		"/a.png": encodePNG(t, testPicture(300, 300, false)),
+		"/b.png": encodePNG(t, testPicture(300, 300, true)),
+	}
+	var referer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		referer = r.Header.Get("Referer")
+		data, ok := pictures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	store, err := NewStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDownloader(store, Config{UserAgent: "test"})
+
+	p := models.NewProduct("item-1", "Item", "https://item.rakuten.co.jp/shop/item-1/", "rakuten", models.NewMoney(1000, "JPY"))
+	p.ImageURL = server.URL + "/a.png"
+	if err := d.Download(p); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Download(p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Images) != 1 || p.Images[0].ID != ID(pictures["/a.png"]) || p.Images[0].Width != 300 {
+		t.Fatalf("Expected one version of a.png, got %+v", p.Images)
+	}
+	if referer != p.URL {
+		t.Errorf("Expected the product page as referer, got %q", referer)
+	}
+
+	p.ImageURL = server.URL + "/b.png"
+	if err := d.Download(p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Images) != 2 || !p.Images[1].Changed {
+		t.Errorf("Expected b.png recorded as a change, got %+v", p.Images)
+	}
+
+	p.ImageURL = server.URL + "/missing.png"
+	if err := d.Download(p); err == nil {
+		t.Error("Expected an error for a missing image")
+	}
+
+	d = NewDownloader(store, Config{MaxBytes: 100})
+	p.ImageURL = server.URL + "/a.png"
+	if err := d.Download(p); err == nil {
+		t.Error("Expected an error for an image over the size limit")
+	}
+}
@@ -0,0 +1,82 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Hash computes the 64-bit difference hash of img: the picture is shrunk to
+// 9x8 grey pixels and each bit tells whether a pixel is brighter than its
+// right neighbour. Resized or re-encoded copies of a picture hash alike, so
+// hashes in few bits apart show the same picture.
+func Hash(img image.Image) uint64 {
+	small := resize(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if luminance(small.RGBAAt(x, y)) > luminance(small.RGBAAt(x+1, y)) {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// FormatHash writes a hash as 16 hex digits, as stored on image versions
+func FormatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// Thumbnail shrinks img to fit in a size x size square, keeping its aspect
+// ratio. Smaller images are returned as they are.
+func Thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+	return resize(img, w, h)
+}
+
+// resize scales img to w x h by averaging the source pixels that fall into
+// each destination pixel, which is good enough for shrinking
+func resize(img image.Image, w, h int) *image.RGBA {
+	src := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := src.Min.Y + y*src.Dy()/h
+		y1 := max(y0+1, src.Min.Y+(y+1)*src.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := src.Min.X + x*src.Dx()/w
+			x1 := max(x0+1, src.Min.X+(x+1)*src.Dx()/w)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// luminance returns the perceived brightness of a pixel
+func luminance(c color.RGBA) int {
+	return 299*int(c.R) + 587*int(c.G) + 114*int(c.B)
+}
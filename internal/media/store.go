@@ -0,0 +1,135 @@
+// Package media downloads product images into a content-addressed store,
+// with thumbnails and perceptual hashes, so products keep their images when
+// the remote links rot and a changed listing image can be told apart from
+// the same picture re-encoded
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders of the formats shops use
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/blobstore"
+)
+
+// ErrNotFound is returned for unknown images
+var ErrNotFound = errors.New("image not found")
+
+// DefaultThumbnailSize is the longest side of thumbnails
+const DefaultThumbnailSize = 200
+
+// Meta describes a stored image. Identical files share one image;
+// FirstSeen and LastSeen span the downloads that returned it.
+type Meta struct {
+	ID          string `json:"id"` // SHA-256 of the file
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int    `json:"size"` // bytes
+	Hash        string `json:"hash"` // perceptual hash, see Hash
+	blobstore.Seen
+}
+
+// Store keeps images under a directory as <id[:2]>/<id>.<format> with a
+// <id>.thumb.jpg thumbnail and a <id>.json metadata file next to each
+type Store struct {
+	files         *blobstore.Store
+	thumbnailSize int
+	now           func() time.Time
+}
+
+// NewStore creates an image store under dir making thumbnails of
+// thumbnailSize, or DefaultThumbnailSize if 0
+func NewStore(dir string, thumbnailSize int) (*Store, error) {
+	files, err := blobstore.New(dir, "image", ErrNotFound)
+	if err != nil {
+		return nil, err
+	}
+	if thumbnailSize <= 0 {
+		thumbnailSize = DefaultThumbnailSize
+	}
+	return &Store{files: files, thumbnailSize: thumbnailSize, now: time.Now}, nil
+}
+
+// ID returns the content address of an image file
+func ID(data []byte) string {
+	return blobstore.ID(data)
+}
+
+// Save stores an image downloaded from url with its thumbnail and returns
+// its metadata. Files that aren't GIF, JPEG or PNG images are refused.
+func (s *Store) Save(url string, data []byte) (*Meta, error) {
+	id := ID(data)
+	meta := &Meta{}
+	err := s.files.Save(id, s.now(), meta, func() error {
+		img, format, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to decode image: %w", err)
+		}
+		*meta = Meta{
+			ID:          id,
+			URL:         url,
+			ContentType: "image/" + format,
+			Width:       img.Bounds().Dx(),
+			Height:      img.Bounds().Dy(),
+			Size:        len(data),
+			Hash:        FormatHash(Hash(img)),
+		}
+		return s.writeImage(meta, data, img)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// Load returns the metadata and file of an image
+func (s *Store) Load(id string) (*Meta, []byte, error) {
+	return s.load(id, func(meta *Meta) string { return "." + format(meta) })
+}
+
+// LoadThumbnail returns the metadata of an image and its JPEG thumbnail
+func (s *Store) LoadThumbnail(id string) (*Meta, []byte, error) {
+	return s.load(id, func(*Meta) string { return ".thumb.jpg" })
+}
+
+// load reads the metadata of an image and its file with the suffix it gives
+func (s *Store) load(id string, suffix func(*Meta) string) (*Meta, []byte, error) {
+	var meta Meta
+	if err := s.files.ReadMeta(id, &meta); err != nil {
+		return nil, nil, err
+	}
+	data, err := s.files.ReadFile(id, suffix(&meta))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &meta, data, nil
+}
+
+// format returns the file extension of an image, e.g. "jpeg"
+func format(meta *Meta) string {
+	return meta.ContentType[len("image/"):]
+}
+
+// writeImage writes the file of an image and its thumbnail
+func (s *Store) writeImage(meta *Meta, data []byte, img image.Image) error {
+	if err := os.WriteFile(s.files.Path(meta.ID, "."+format(meta)), data, 0644); err != nil {
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, Thumbnail(img, s.thumbnailSize), &jpeg.Options{Quality: 85}); err != nil {
+		return fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	if err := os.WriteFile(s.files.Path(meta.ID, ".thumb.jpg"), thumb.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+	return nil
+}
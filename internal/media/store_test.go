@@ -0,0 +1,114 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// testPicture draws a w x h picture of diagonal bands, mirrored if flip is
+// set
+func testPicture(w, h int, flip bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			px := x
+			if flip {
+				px = w - 1 - x
+			}
+			v := uint8((px*255/w + y*128/h) % 256)
+			img.Set(x, y, color.RGBA{R: v, G: 255 - v, B: v / 2, A: 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestStoreSaveLoad(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 100)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	data := encodePNG(t, testPicture(400, 300, false))
+	meta, err := store.Save("https://thumbnail.image.rakuten.co.jp/item.png", data)
+	if err != nil {
+		t.Fatalf("Failed to save image: %v", err)
+	}
+	if meta.ID != ID(data) || meta.ContentType != "image/png" || meta.Width != 400 || meta.Height != 300 || len(meta.Hash) != 16 {
+		t.Errorf("Unexpected metadata: %+v", meta)
+	}
+
+	// Saving the same file again keeps one image and extends LastSeen
+	now = now.Add(24 * time.Hour)
+	if again, _ := store.Save("https://thumbnail.image.rakuten.co.jp/item.png", data); again.ID != meta.ID || !again.LastSeen.After(again.FirstSeen) {
+		t.Errorf("Expected the same image seen again, got %+v", again)
+	}
+
+	_, loaded, err := store.Load(meta.ID)
+	if err != nil || !bytes.Equal(loaded, data) {
+		t.Errorf("Expected the stored file, got %d bytes: %v", len(loaded), err)
+	}
+
+	_, thumb, err := store.LoadThumbnail(meta.ID)
+	if err != nil {
+		t.Fatalf("Failed to load thumbnail: %v", err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	if err != nil || img.Bounds().Dx() != 100 || img.Bounds().Dy() != 75 {
+		t.Errorf("Expected a 100x75 JPEG thumbnail, got %v: %v", img.Bounds(), err)
+	}
+
+	if _, err := store.Save("https://example.com/page.html", []byte("<html></html>")); err == nil {
+		t.Error("Expected an error for a file that isn't an image")
+	}
+	for _, id := range []string{"../../etc/passwd", ID([]byte("unknown"))} {
+		if _, _, err := store.Load(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for %q, got %v", id, err)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	picture := testPicture(400, 300, false)
+
+	// The same picture smaller and as a JPEG
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, Thumbnail(picture, 150), &jpeg.Options{Quality: 60})
+	reencoded, _, err := image.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := models.ImageHashDistance(FormatHash(Hash(picture)), FormatHash(Hash(reencoded))); d > 4 {
+		t.Errorf("Expected a re-encoded copy to hash alike, got distance %d", d)
+	}
+	if d := models.ImageHashDistance(FormatHash(Hash(picture)), FormatHash(Hash(testPicture(400, 300, true)))); d < 20 {
+		t.Errorf("Expected a different picture to hash apart, got distance %d", d)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	if b := Thumbnail(testPicture(120, 600, false), 200).Bounds(); b.Dx() != 40 || b.Dy() != 200 {
+		t.Errorf("Expected 40x200, got %v", b)
+	}
+	if b := Thumbnail(testPicture(50, 80, false), 200).Bounds(); b.Dx() != 50 || b.Dy() != 80 {
+		t.Errorf("Expected a small picture to be kept, got %v", b)
+	}
+}
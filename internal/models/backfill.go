@@ -0,0 +1,75 @@
+package models
+
+// LinkSnapshot records the archived HTML that the latest price point was
+// extracted from
+func (p *Product) LinkSnapshot(id string) {
+	if len(p.PriceHistory) > 0 {
+		p.PriceHistory[len(p.PriceHistory)-1].SnapshotID = id
+	}
+}
+
+// FillMissing copies fields that are empty on p from src, a product
+// re-extracted from an archived page, and returns the names of the fields
+// filled. Fields that already have a value are never overwritten.
+func (p *Product) FillMissing(src *Product) []string {
+	var filled []string
+	fill := func(name string, dst *string, value string) {
+		if *dst == "" && value != "" {
+			*dst = value
+			filled = append(filled, name)
+		}
+	}
+
+	fill("name", &p.Name, src.Name)
+	fill("image_url", &p.ImageURL, src.ImageURL)
+	fill("description", &p.Description, src.Description)
+	fill("description_html", &p.DescriptionHTML, src.DescriptionHTML)
+	fill("gtin", &p.GTIN, src.GTIN)
+	fill("isbn", &p.ISBN, src.ISBN)
+	fill("shop_code", &p.ShopCode, src.ShopCode)
+	filled = append(filled, p.OfferDetails.fillMissing(src.OfferDetails)...)
+
+	if p.Rating == 0 && p.ReviewCount == 0 && (src.Rating > 0 || src.ReviewCount > 0) {
+		p.Rating, p.ReviewCount = src.Rating, src.ReviewCount
+		filled = append(filled, "rating", "review_count")
+	}
+	if len(p.Attributes) == 0 && len(src.Attributes) > 0 {
+		p.Attributes = src.Attributes
+		filled = append(filled, "attributes")
+	}
+	if len(p.Variants) == 0 && len(src.Variants) > 0 {
+		p.Variants = src.Variants
+		filled = append(filled, "variants")
+	}
+	return filled
+}
+
+// FillMissing fills an empty price and offer details of the point from a
+// product re-extracted from the point's snapshot
+func (pp *PricePoint) FillMissing(src *Product) []string {
+	var filled []string
+	if pp.Price.IsZero() && src.CurrentPrice.IsPositive() {
+		pp.Price = src.CurrentPrice
+		filled = append(filled, "price")
+	}
+	return append(filled, pp.OfferDetails.fillMissing(src.OfferDetails)...)
+}
+
+// fillMissing copies unset offer details from src
+func (d *OfferDetails) fillMissing(src OfferDetails) []string {
+	var filled []string
+	if d.Availability == AvailabilityUnknown && src.Availability != AvailabilityUnknown {
+		d.Availability = src.Availability
+		filled = append(filled, "availability")
+	}
+	if d.ShippingFee == nil && src.ShippingFee != nil {
+		fee := *src.ShippingFee
+		d.ShippingFee = &fee
+		filled = append(filled, "shipping_fee")
+	}
+	if d.Points == 0 && src.Points > 0 {
+		d.Points = src.Points
+		filled = append(filled, "points")
+	}
+	return filled
+}
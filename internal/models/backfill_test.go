@@ -0,0 +1,36 @@
+package models
+
+import "testing"
+
+func TestFillMissing(t *testing.T) {
+	p := NewProduct("item", "Item", "https://example.com/item", "rakuten", NewMoney(0, "JPY"))
+	p.ImageURL = "https://example.com/old.jpg"
+	p.LinkSnapshot("abc")
+
+	fee := NewMoney(0, "JPY")
+	src := NewProduct("item", "Item (re-extracted)", "https://example.com/item", "rakuten", NewMoney(1980, "JPY"))
+	src.ImageURL = "https://example.com/new.jpg"
+	src.GTIN = "4902370548495"
+	src.SetOfferDetails(OfferDetails{Availability: AvailabilityInStock, ShippingFee: &fee})
+	src.Attributes = map[string]string{"メーカー": "任天堂"}
+
+	filled := p.FillMissing(src)
+	if p.Name != "Item" || p.ImageURL != "https://example.com/old.jpg" {
+		t.Errorf("Expected existing fields to be kept, got %q, %q", p.Name, p.ImageURL)
+	}
+	if p.GTIN != src.GTIN || p.Availability != AvailabilityInStock || p.ShippingFee == nil || p.Attributes["メーカー"] != "任天堂" {
+		t.Errorf("Expected missing fields to be filled, got %+v", p)
+	}
+	if len(filled) != 4 {
+		t.Errorf("Expected gtin, attributes, availability and shipping_fee filled, got %v", filled)
+	}
+
+	pp := &p.PriceHistory[0]
+	if pp.SnapshotID != "abc" {
+		t.Errorf("Expected price point linked to snapshot, got %q", pp.SnapshotID)
+	}
+	pp.FillMissing(src)
+	if pp.Price != NewMoney(1980, "JPY") || pp.Availability != AvailabilityInStock {
+		t.Errorf("Expected price point backfilled, got %+v", pp)
+	}
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// PriceStats summarizes a product's price history
+type PriceStats struct {
+	Min           Money   `json:"min"`
+	Max           Money   `json:"max"`
+	Average       Money   `json:"average"`
+	First         Money   `json:"first"`
+	Last          Money   `json:"last"`
+	Change        Money   `json:"change"`         // Last - First
+	ChangePercent float64 `json:"change_percent"` // relative to First
+	Points        int     `json:"points"`
+}
+
+// AvailabilityChange records a stock status transition in the price history
+type AvailabilityChange struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PriceStats computes statistics over the list prices in the history. Zero
+// prices (failed extractions) are ignored. All points must share one currency.
+func (p *Product) PriceStats() (PriceStats, error) {
+	return p.stats(func(pp PricePoint) Money { return pp.Price })
+}
+
+// EffectivePriceStats computes statistics over the effective prices
+// (price plus shipping minus points) in the history
+func (p *Product) EffectivePriceStats() (PriceStats, error) {
+	return p.stats(PricePoint.EffectivePrice)
+}
+
+// AvailabilityChanges lists the availability transitions in the history.
+// Points with unknown availability are skipped.
+func (p *Product) AvailabilityChanges() []AvailabilityChange {
+	var changes []AvailabilityChange
+	last := AvailabilityUnknown
+	for _, pp := range p.PriceHistory {
+		if pp.Availability == AvailabilityUnknown {
+			continue
+		}
+		if last != AvailabilityUnknown && pp.Availability != last {
+			changes = append(changes, AvailabilityChange{From: last, To: pp.Availability, Timestamp: pp.Timestamp})
+		}
+		last = pp.Availability
+	}
+	return changes
+}
+
+// stats computes statistics over the amounts selected from each price point
+func (p *Product) stats(amount func(PricePoint) Money) (PriceStats, error) {
+	var stats PriceStats
+	var total Money
+
+	for _, pp := range p.PriceHistory {
+		if !pp.Price.IsPositive() {
+			continue
+		}
+		price := amount(pp)
+
+		if stats.Points == 0 {
+			stats.Min, stats.Max, stats.First = price, price, price
+		}
+		if cmp, err := price.Cmp(stats.Min); err != nil {
+			return PriceStats{}, fmt.Errorf("price history of %s: %w", p.ID, err)
+		} else if cmp < 0 {
+			stats.Min = price
+		}
+		if cmp, _ := price.Cmp(stats.Max); cmp > 0 {
+			stats.Max = price
+		}
+
+		total, _ = total.Add(price)
+		stats.Last = price
+		stats.Points++
+	}
+
+	if stats.Points == 0 {
+		return stats, nil
+	}
+
+	stats.Average = total.Div(int64(stats.Points))
+	stats.Change, _ = stats.Last.Sub(stats.First)
+	stats.ChangePercent, _ = stats.First.PercentChange(stats.Last)
+	return stats, nil
+}
+
+// MergeHistory carries over the price, review, rank and image history of a
+// previously stored copy of the product, including per-variant history and
+// the tracked variant, so that saving a fresh scrape extends the time series
+// instead of replacing it. Fields only product pages provide (codes,
+// description, attributes and variants) are kept from the stored copy when
+// the fresh scrape, e.g. of a search or ranking listing, lacks them.
+func (p *Product) MergeHistory(previous *Product) {
+	if previous == nil {
+		return
+	}
+
+	p.PriceHistory = append(append([]PricePoint{}, previous.PriceHistory...), p.PriceHistory...)
+	p.ReviewHistory = append(append([]ReviewPoint{}, previous.ReviewHistory...), p.ReviewHistory...)
+	p.RankHistory = append(append([]RankSnapshot{}, previous.RankHistory...), p.RankHistory...)
+
+	images := p.Images
+	p.Images = append([]ImageVersion{}, previous.Images...)
+	for _, v := range images {
+		p.RecordImage(v)
+	}
+
+	for i := range p.Variants {
+		if old := previous.Variant(p.Variants[i].SKU); old != nil {
+			p.Variants[i].PriceHistory = append(append([]PricePoint{}, old.PriceHistory...), p.Variants[i].PriceHistory...)
+		}
+	}
+	if p.TrackedVariant == "" && previous.TrackedVariant != "" && p.Variant(previous.TrackedVariant) != nil {
+		p.TrackedVariant = previous.TrackedVariant
+		p.syncTrackedVariant()
+	}
+
+	p.keepPageFields(previous)
+}
+
+// keepPageFields copies the fields only product pages provide, with their
+// provenance, from previous where p has none. Kept variants stay as stored;
+// the tracked one no longer sets the price, which comes from the fresh
+// scrape.
+func (p *Product) keepPageFields(previous *Product) {
+	keep := func(field string) {
+		if source, ok := previous.Provenance[field]; ok {
+			p.Provenance.Set(field, source)
+		}
+	}
+
+	if p.GTIN == "" && previous.GTIN != "" {
+		p.GTIN, p.ISBN = previous.GTIN, previous.ISBN
+		keep("gtin")
+	}
+	if p.Description == "" && previous.Description != "" {
+		p.Description, p.DescriptionHTML = previous.Description, previous.DescriptionHTML
+		keep("description")
+	}
+	if len(p.Attributes) == 0 && len(previous.Attributes) > 0 {
+		p.Attributes = previous.Attributes
+		keep("attributes")
+	}
+	if len(p.Variants) == 0 && len(previous.Variants) > 0 {
+		p.Variants = append([]Variant{}, previous.Variants...)
+		if p.TrackedVariant == "" {
+			p.TrackedVariant = previous.TrackedVariant
+		}
+		keep("variants")
+	}
+}
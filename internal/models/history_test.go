@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestMergeHistoryKeepsPageFields(t *testing.T) {
+	page := NewProduct("shop:item", "Test Shirt", "https://item.rakuten.co.jp/shop/item/", "rakuten", NewMoney(1980, "JPY"))
+	page.SetIdentifier("4902370548495")
+	page.Description = "綿100%"
+	page.DescriptionHTML = "<p>綿100%</p>"
+	page.Attributes = map[string]string{"サイズ": "XL"}
+	page.SetVariants([]Variant{{SKU: "xl", Price: NewMoney(2480, "JPY")}})
+	if err := page.TrackVariant("xl"); err != nil {
+		t.Fatal(err)
+	}
+	page.Provenance.Set("gtin", FieldSource{Rule: "table", Confidence: ConfidenceMedium})
+
+	// A listing result has none of the page's fields
+	listing := NewProduct("shop:item", "Test Shirt", "https://item.rakuten.co.jp/shop/item/", "rakuten", NewMoney(2280, "JPY"))
+	listing.MergeHistory(page)
+
+	if listing.GTIN != "4902370548495" || listing.Description != "綿100%" || listing.DescriptionHTML != "<p>綿100%</p>" || listing.Attributes["サイズ"] != "XL" {
+		t.Errorf("Expected the page's fields kept, got %+v", listing)
+	}
+	if listing.TrackedVariant != "xl" || len(listing.Variants) != 1 || len(listing.Variant("xl").PriceHistory) != 1 {
+		t.Errorf("Expected the variants kept with their history, got %q %+v", listing.TrackedVariant, listing.Variants)
+	}
+	if listing.CurrentPrice != NewMoney(2280, "JPY") || len(listing.PriceHistory) != 2 {
+		t.Errorf("Expected the listing's price added to the history, got %s %+v", listing.CurrentPrice, listing.PriceHistory)
+	}
+	if listing.Provenance["gtin"].Rule != "table" {
+		t.Errorf("Expected the provenance of kept fields, got %+v", listing.Provenance)
+	}
+
+	// Fields a fresh scrape has replace the stored ones
+	fresh := NewProduct("shop:item", "Test Shirt", "https://item.rakuten.co.jp/shop/item/", "rakuten", NewMoney(1980, "JPY"))
+	fresh.Description = "綿100% 日本製"
+	fresh.MergeHistory(listing)
+	if fresh.Description != "綿100% 日本製" || fresh.DescriptionHTML != "" || fresh.GTIN != "4902370548495" {
+		t.Errorf("Expected the fresh description with the stored code, got %+v", fresh)
+	}
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"strings"
+)
+
+// NormalizeGTIN cleans up a JAN/EAN/UPC/ISBN code and returns it as a
+// 13-digit GTIN. ISBN-10, UPC-A and EAN-8 codes are converted to their
+// GTIN-13 form. An empty string is returned if the code is not a valid
+// identifier.
+func NormalizeGTIN(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+
+	switch len(code) {
+	case 10:
+		// ISBN-10 may end with an X check digit
+		return ISBN10To13(code)
+	case 12:
+		// UPC-A is a GTIN-13 with a leading zero
+		code = "0" + code
+	case 8:
+		// EAN-8 is a GTIN-13 with five leading zeros
+		code = "00000" + code
+	case 13:
+	default:
+		return ""
+	}
+
+	if !isDigits(code) || !validGTINChecksum(code) {
+		return ""
+	}
+	return code
+}
+
+// ISBN10To13 converts an ISBN-10 to its ISBN-13 (GTIN-13) representation.
+// An empty string is returned if the ISBN-10 is invalid.
+func ISBN10To13(isbn10 string) string {
+	isbn10 = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(isbn10), "-", ""))
+	if len(isbn10) != 10 || !isDigits(isbn10[:9]) {
+		return ""
+	}
+
+	// Validate the ISBN-10 check digit (weights 10..1, mod 11)
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(isbn10[i]-'0') * (10 - i)
+	}
+	switch check := isbn10[9]; {
+	case check == 'X':
+		sum += 10
+	case check >= '0' && check <= '9':
+		sum += int(check - '0')
+	default:
+		return ""
+	}
+	if sum%11 != 0 {
+		return ""
+	}
+
+	body := "978" + isbn10[:9]
+	return body + string(rune('0'+gtinCheckDigit(body)))
+}
+
+// IsISBN reports whether a normalized GTIN-13 belongs to the book (Bookland) range
+func IsISBN(gtin string) bool {
+	return len(gtin) == 13 && (strings.HasPrefix(gtin, "978") || strings.HasPrefix(gtin, "979"))
+}
+
+// validGTINChecksum verifies the trailing check digit of a GTIN-8/12/13/14
+func validGTINChecksum(code string) bool {
+	body := code[:len(code)-1]
+	return int(code[len(code)-1]-'0') == gtinCheckDigit(body)
+}
+
+// gtinCheckDigit computes the GS1 check digit for the given digits
+func gtinCheckDigit(body string) int {
+	sum := 0
+	// Weights alternate 3,1,3,... starting from the rightmost digit of the body
+	for i := len(body) - 1; i >= 0; i-- {
+		d := int(body[i] - '0')
+		if (len(body)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// isDigits reports whether s consists solely of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
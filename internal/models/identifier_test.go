@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{name: "EAN-13", code: "4006381333931", expected: "4006381333931"},
+		{name: "EAN-13 with spaces", code: " 4006381333931 ", expected: "4006381333931"},
+		{name: "ISBN-13 with hyphens", code: "978-0-306-40615-7", expected: "9780306406157"},
+		{name: "ISBN-10", code: "0-306-40615-2", expected: "9780306406157"},
+		{name: "ISBN-10 with X check digit", code: "0-8044-2957-X", expected: "9780804429573"},
+		{name: "UPC-A", code: "036000291452", expected: "0036000291452"},
+		{name: "EAN-8", code: "96385074", expected: "0000096385074"},
+		{name: "Bad checksum", code: "4006381333932", expected: ""},
+		{name: "Bad ISBN-10 checksum", code: "0306406153", expected: ""},
+		{name: "Wrong length", code: "12345", expected: ""},
+		{name: "Letters", code: "40063813339AB", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeGTIN(tt.code); got != tt.expected {
+				t.Errorf("NormalizeGTIN(%q) = %q, expected %q", tt.code, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSetIdentifier(t *testing.T) {
+	product := NewProduct("test-123", "Test Book", "https://example.com/book", "rakuten", NewMoney(1500, "JPY"))
+
+	if product.SetIdentifier("not-a-code") {
+		t.Error("Expected invalid code to be rejected")
+	}
+
+	if !product.SetIdentifier("978-0-306-40615-7") {
+		t.Fatal("Expected ISBN to be accepted")
+	}
+	if product.GTIN != "9780306406157" {
+		t.Errorf("Expected GTIN 9780306406157, got %s", product.GTIN)
+	}
+	if product.ISBN != "9780306406157" {
+		t.Errorf("Expected ISBN 9780306406157, got %s", product.ISBN)
+	}
+
+	other := NewProduct("test-456", "Test Item", "https://example.com/item", "rakuten", NewMoney(500, "JPY"))
+	other.SetIdentifier("4006381333931")
+	if other.ISBN != "" {
+		t.Errorf("Expected no ISBN for a non-book GTIN, got %s", other.ISBN)
+	}
+}
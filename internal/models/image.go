@@ -0,0 +1,75 @@
+package models
+
+import (
+	"math/bits"
+	"strconv"
+	"time"
+)
+
+// ImageChangeDistance is the number of differing perceptual hash bits from
+// which two versions of a listing image show different pictures rather than
+// one picture resized or re-encoded
+const ImageChangeDistance = 10
+
+// ImageVersion is one downloaded version of a product's listing image
+type ImageVersion struct {
+	ID        string    `json:"id"`   // SHA-256 of the file, its address in the media store
+	URL       string    `json:"url"`  // where it was downloaded from
+	Hash      string    `json:"hash"` // 64-bit perceptual hash in hex
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Changed   bool      `json:"changed,omitempty"` // shows a different picture than the version before
+}
+
+// RecordImage adds a downloaded version of the listing image to the
+// product's images. The file seen again extends the latest version; a new
+// file is marked Changed if its picture differs from the latest.
+func (p *Product) RecordImage(v ImageVersion) {
+	if n := len(p.Images); n > 0 {
+		last := &p.Images[n-1]
+		if last.ID == v.ID {
+			if v.LastSeen.After(last.LastSeen) {
+				last.LastSeen = v.LastSeen
+			}
+			return
+		}
+		v.Changed = ImageHashDistance(last.Hash, v.Hash) >= ImageChangeDistance
+	}
+	p.Images = append(p.Images, v)
+}
+
+// Image returns the product's version of the image with the given ID, or
+// nil
+func (p *Product) Image(id string) *ImageVersion {
+	for i := range p.Images {
+		if p.Images[i].ID == id {
+			return &p.Images[i]
+		}
+	}
+	return nil
+}
+
+// ImageChanges returns the versions of the listing image that show a
+// different picture than the version before, oldest first
+func (p *Product) ImageChanges() []ImageVersion {
+	var changes []ImageVersion
+	for _, v := range p.Images {
+		if v.Changed {
+			changes = append(changes, v)
+		}
+	}
+	return changes
+}
+
+// ImageHashDistance returns the number of bits in which two perceptual
+// hashes differ, or 64 if either isn't a hash
+func ImageHashDistance(a, b string) int {
+	x, errA := strconv.ParseUint(a, 16, 64)
+	y, errB := strconv.ParseUint(b, 16, 64)
+	if errA != nil || errB != nil {
+		return 64
+	}
+	return bits.OnesCount64(x ^ y)
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordImage(t *testing.T) {
+	p := NewProduct("test123", "Test Product", "https://example.com/product", "rakuten", NewMoney(1000, "JPY"))
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	p.RecordImage(ImageVersion{ID: "a", Hash: "f0f0f0f0f0f0f0f0", FirstSeen: day, LastSeen: day})
+	p.RecordImage(ImageVersion{ID: "a", Hash: "f0f0f0f0f0f0f0f0", FirstSeen: day.AddDate(0, 0, 1), LastSeen: day.AddDate(0, 0, 1)})
+	if len(p.Images) != 1 || !p.Images[0].LastSeen.Equal(day.AddDate(0, 0, 1)) {
+		t.Fatalf("Expected the same file to extend the version, got %+v", p.Images)
+	}
+
+	// Re-encoded: a new file with nearly the same hash
+	p.RecordImage(ImageVersion{ID: "b", Hash: "f0f0f0f0f0f0f0f1", FirstSeen: day.AddDate(0, 0, 2), LastSeen: day.AddDate(0, 0, 2)})
+	// A different picture
+	p.RecordImage(ImageVersion{ID: "c", Hash: "0f0f0f0f0f0f0f0f", FirstSeen: day.AddDate(0, 0, 3), LastSeen: day.AddDate(0, 0, 3)})
+	if len(p.Images) != 3 || p.Images[1].Changed || !p.Images[2].Changed {
+		t.Errorf("Unexpected versions: %+v", p.Images)
+	}
+	if changes := p.ImageChanges(); len(changes) != 1 || changes[0].ID != "c" {
+		t.Errorf("Expected one change to c, got %+v", changes)
+	}
+	if p.Image("b") == nil || p.Image("x") != nil {
+		t.Error("Expected to find versions by ID")
+	}
+}
+
+func TestMergeHistoryImages(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	stored := NewProduct("test123", "Test Product", "https://example.com/product", "rakuten", NewMoney(1000, "JPY"))
+	stored.RecordImage(ImageVersion{ID: "a", Hash: "f0f0f0f0f0f0f0f0", FirstSeen: day, LastSeen: day})
+
+	p := NewProduct("test123", "Test Product", "https://example.com/product", "rakuten", NewMoney(1000, "JPY"))
+	p.RecordImage(ImageVersion{ID: "c", Hash: "0f0f0f0f0f0f0f0f", FirstSeen: day.AddDate(0, 0, 1), LastSeen: day.AddDate(0, 0, 1)})
+	p.MergeHistory(stored)
+
+	if len(p.Images) != 2 || p.Images[0].ID != "a" || !p.Images[1].Changed {
+		t.Errorf("Expected the stored version followed by a change, got %+v", p.Images)
+	}
+	if len(stored.Images) != 1 {
+		t.Error("Expected the stored copy to be left alone")
+	}
+}
+
+func TestImageHashDistance(t *testing.T) {
+	if d := ImageHashDistance("00000000000000ff", "0000000000000000"); d != 8 {
+		t.Errorf("Expected 8, got %d", d)
+	}
+	if d := ImageHashDistance("", "0000000000000000"); d != 64 {
+		t.Errorf("Expected 64 for a missing hash, got %d", d)
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// legacyPriceKeys are the fields that held a decimal number next to a
+// "currency" field before prices were encoded as Money
+var legacyPriceKeys = []string{"current_price", "price"}
+
+// UpgradeLegacyJSON rewrites products and price points stored in the layout
+// written before prices were encoded as Money, e.g. "current_price": 1980
+// next to "currency": "JPY", into the Money layout. Data in the current
+// layout is returned with the same content.
+func UpgradeLegacyJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(upgradeLegacyPrices(v))
+}
+
+// upgradeLegacyPrices rewrites the legacy prices in v and the values it holds
+func upgradeLegacyPrices(v any) any {
+	switch v := v.(type) {
+	case []any:
+		for i := range v {
+			v[i] = upgradeLegacyPrices(v[i])
+		}
+	case map[string]any:
+		if currency, ok := v["currency"].(string); ok {
+			upgraded := false
+			for _, key := range legacyPriceKeys {
+				if amount, ok := v[key].(json.Number); ok {
+					v[key] = map[string]any{"amount": amount.String(), "currency": currency}
+					upgraded = true
+				}
+			}
+			if upgraded {
+				delete(v, "currency")
+			}
+		}
+		for k := range v {
+			v[k] = upgradeLegacyPrices(v[k])
+		}
+	}
+	return v
+}
@@ -0,0 +1,374 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrCurrencyMismatch is returned when combining amounts in different currencies
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// ErrNoPrice is returned when no amount can be found in a price text
+var ErrNoPrice = errors.New("no price found")
+
+// Money is an exact monetary amount stored as integer minor units
+// (e.g. cents for USD, yen for JPY) of an ISO 4217 currency. It is encoded
+// in JSON as {"amount": "1234.56", "currency": "USD"}, the amount a decimal
+// string in major units.
+type Money struct {
+	Amount   int64  // minor units
+	Currency string // ISO 4217 code, e.g. "JPY"
+}
+
+// currencyExponents lists the number of minor-unit digits per currency.
+// Currencies not listed default to 2.
+var currencyExponents = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"CLP": 0,
+	"BHD": 3,
+	"KWD": 3,
+}
+
+// CurrencyExponent returns the number of minor-unit digits for a currency
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// NewMoney creates a Money value from minor units
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// MoneyFromFloat converts a major-unit float to Money, rounding to the
+// currency's minor unit. Prefer ParseDecimal when the source is text.
+func MoneyFromFloat(value float64, currency string) Money {
+	scale := math.Pow10(CurrencyExponent(currency))
+	return NewMoney(int64(math.Round(value*scale)), currency)
+}
+
+// ParseDecimal parses a plain decimal string in major units ("1234.56") into
+// Money. Extra fraction digits beyond the currency's minor unit are rounded
+// half away from zero.
+func ParseDecimal(s, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return NewMoney(0, currency), nil
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" {
+		intPart = "0"
+	}
+	if !isDigits(intPart) || (fracPart != "" && !isDigits(fracPart)) {
+		// Fall back for exponent notation written by older float encoders
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Money{}, fmt.Errorf("invalid decimal amount %q", s)
+		}
+		if neg {
+			f = -f
+		}
+		return MoneyFromFloat(f, currency), nil
+	}
+
+	exp := CurrencyExponent(currency)
+	roundUp := false
+	if len(fracPart) > exp {
+		roundUp = fracPart[exp] >= '5'
+		fracPart = fracPart[:exp]
+	}
+	fracPart += strings.Repeat("0", exp-len(fracPart))
+
+	amount, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid decimal amount %q: %w", s, err)
+	}
+	if roundUp {
+		amount++
+	}
+	if neg {
+		amount = -amount
+	}
+	return NewMoney(amount, currency), nil
+}
+
+// currencyMarkers maps price symbols and codes to ISO 4217 currencies, in
+// the order they are checked ("US$" before "$")
+var currencyMarkers = []struct {
+	marker   string
+	currency string
+}{
+	{"US$", "USD"},
+	{"USD", "USD"},
+	{"EUR", "EUR"},
+	{"€", "EUR"},
+	{"JPY", "JPY"},
+	{"円", "JPY"},
+	{"¥", "JPY"},
+	{"￥", "JPY"},
+	{"$", "USD"},
+}
+
+// amountPattern matches a run of digits with grouping/decimal separators
+var amountPattern = regexp.MustCompile(`\d(?:[\d.,]*\d)?`)
+
+// ParseMoney parses a displayed price such as "¥1,234", "1,234円（税込）",
+// "$1,234.56", "US$ 12.50", "€1.234,56" or "1.234,56 €". The currency is
+// detected from the symbol or code in the text, falling back to
+// defaultCurrency. When several numbers appear, the one nearest the currency
+// symbol is used.
+func ParseMoney(text, defaultCurrency string) (Money, error) {
+	text = toHalfWidthDigits(strings.TrimSpace(text))
+
+	currency := strings.ToUpper(defaultCurrency)
+	markerPos := -1
+	for _, m := range currencyMarkers {
+		if i := strings.Index(text, m.marker); i >= 0 {
+			currency = m.currency
+			markerPos = i
+			break
+		}
+	}
+
+	runs := amountPattern.FindAllStringIndex(text, -1)
+	if len(runs) == 0 {
+		return Money{}, ErrNoPrice
+	}
+
+	run := runs[0]
+	if markerPos >= 0 {
+		best := math.MaxInt
+		for _, r := range runs {
+			dist := min(abs(r[0]-markerPos), abs(r[1]-markerPos))
+			if dist < best {
+				best, run = dist, r
+			}
+		}
+	}
+
+	return ParseDecimal(normalizeSeparators(text[run[0]:run[1]], CurrencyExponent(currency)), currency)
+}
+
+// normalizeSeparators rewrites a localized number ("1.234,56", "1,234.56",
+// "1,234") into a plain decimal string ("1234.56", "1234.56", "1234")
+func normalizeSeparators(num string, exp int) string {
+	lastDot, lastComma := strings.LastIndex(num, "."), strings.LastIndex(num, ",")
+
+	decimal := -1
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		// Both present: whichever comes last is the decimal separator
+		decimal = max(lastDot, lastComma)
+	case lastDot >= 0 || lastComma >= 0:
+		sep := max(lastDot, lastComma)
+		count := strings.Count(num, string(num[sep]))
+		// A single separator followed by exactly three digits is grouping
+		// unless the currency itself has three minor digits
+		if count == 1 && (len(num)-sep-1 != 3 || exp == 3) {
+			decimal = sep
+		}
+	}
+
+	var b strings.Builder
+	for i, r := range num {
+		switch {
+		case i == decimal:
+			b.WriteByte('.')
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// toHalfWidthDigits converts full-width digits and separators to ASCII
+func toHalfWidthDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '０' && r <= '９':
+			return r - '０' + '0'
+		case r == '，':
+			return ','
+		case r == '．':
+			return '.'
+		}
+		return r
+	}, s)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Decimal formats the amount in major units without grouping, e.g. "1234.56"
+func (m Money) Decimal() string {
+	exp := CurrencyExponent(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// MarshalJSON encodes the amount as a decimal string in major units
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// UnmarshalJSON reads the layout written by MarshalJSON. A numeric amount is
+// read in major units, like the decimal string.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Amount   json.Number `json:"amount"`
+		Currency string      `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	money, err := ParseDecimal(aux.Amount.String(), aux.Currency)
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
+
+// Float64 returns the amount in major units as a float, for display and
+// statistics only
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / math.Pow10(CurrencyExponent(m.Currency))
+}
+
+// String formats the amount with its currency, e.g. "1234.56 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// Add returns m + o. Both amounts must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currency(o)}, nil
+}
+
+// Sub returns m - o. Both amounts must be in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currency(o)}, nil
+}
+
+// Cmp compares m and o, returning -1, 0 or +1. Both amounts must be in the
+// same currency.
+func (m Money) Cmp(o Money) (int, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// LessPrice orders amounts by amount, grouping amounts in different
+// currencies by currency code. It is the order of all price sorts.
+func LessPrice(a, b Money) bool {
+	if cmp, err := a.Cmp(b); err == nil {
+		return cmp < 0
+	}
+	return a.Currency < b.Currency
+}
+
+// Div returns m divided by n, rounded half away from zero to the minor unit
+func (m Money) Div(n int64) Money {
+	q, r := m.Amount/n, m.Amount%n
+	if abs64(2*r) >= abs64(n) {
+		if (m.Amount < 0) != (n < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money{Amount: q, Currency: m.Currency}
+}
+
+// PercentChange returns the relative change from m to o in percent
+// (e.g. -10 for a 10% drop). It returns 0 if m is zero.
+func (m Money) PercentChange(o Money) (float64, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return 0, err
+	}
+	if m.Amount == 0 {
+		return 0, nil
+	}
+	return float64(o.Amount-m.Amount) / float64(m.Amount) * 100, nil
+}
+
+// checkCurrency returns ErrCurrencyMismatch unless both amounts share a
+// currency. A zero amount without a currency is compatible with any currency.
+func (m Money) checkCurrency(o Money) error {
+	if m.Currency == o.Currency || (m.Currency == "" && m.Amount == 0) || (o.Currency == "" && o.Amount == 0) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+}
+
+// currency returns the currency shared by m and o
+func (m Money) currency(o Money) string {
+	if m.Currency != "" {
+		return m.Currency
+	}
+	return o.Currency
+}
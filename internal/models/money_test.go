@@ -0,0 +1,163 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		currency string
+		expected Money
+	}{
+		{name: "Yen suffix", text: "1,980円", currency: "JPY", expected: NewMoney(1980, "JPY")},
+		{name: "Yen prefix", text: "¥12,800", currency: "JPY", expected: NewMoney(12800, "JPY")},
+		{name: "Full-width yen", text: "￥１，２８０（税込）", currency: "JPY", expected: NewMoney(1280, "JPY")},
+		{name: "Number nearest symbol", text: "ポイント10倍 3,480円 送料無料", currency: "JPY", expected: NewMoney(3480, "JPY")},
+		{name: "Plain number uses default", text: "2980", currency: "JPY", expected: NewMoney(2980, "JPY")},
+		{name: "US dollars", text: "$1,234.56", currency: "JPY", expected: NewMoney(123456, "USD")},
+		{name: "US dollars code", text: "US$ 12.5", currency: "JPY", expected: NewMoney(1250, "USD")},
+		{name: "Euro continental", text: "1.234,56 €", currency: "JPY", expected: NewMoney(123456, "EUR")},
+		{name: "Euro prefix", text: "€1,50", currency: "JPY", expected: NewMoney(150, "EUR")},
+		{name: "Euro grouping only", text: "EUR 1.234", currency: "JPY", expected: NewMoney(123400, "EUR")},
+		{name: "Yen with decimals rounds", text: "1,234.5円", currency: "JPY", expected: NewMoney(1235, "JPY")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.text, tt.currency)
+			if err != nil {
+				t.Fatalf("ParseMoney(%q) returned error: %v", tt.text, err)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseMoney(%q) = %s, expected %s", tt.text, got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := ParseMoney("売り切れ", "JPY"); !errors.Is(err, ErrNoPrice) {
+		t.Errorf("Expected ErrNoPrice, got %v", err)
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money    Money
+		expected string
+	}{
+		{money: NewMoney(1980, "JPY"), expected: "1980"},
+		{money: NewMoney(123456, "USD"), expected: "1234.56"},
+		{money: NewMoney(5, "USD"), expected: "0.05"},
+		{money: NewMoney(-150, "EUR"), expected: "-1.50"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.Decimal(); got != tt.expected {
+			t.Errorf("Decimal() = %q, expected %q", got, tt.expected)
+		}
+		parsed, err := ParseDecimal(tt.expected, tt.money.Currency)
+		if err != nil || parsed != tt.money {
+			t.Errorf("ParseDecimal(%q) = %s, %v; expected %s", tt.expected, parsed, err, tt.money)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	a, b := NewMoney(1000, "JPY"), NewMoney(800, "JPY")
+
+	sum, err := a.Add(b)
+	if err != nil || sum != NewMoney(1800, "JPY") {
+		t.Errorf("Expected 1800 JPY, got %s (%v)", sum, err)
+	}
+
+	diff, err := b.Sub(a)
+	if err != nil || diff != NewMoney(-200, "JPY") {
+		t.Errorf("Expected -200 JPY, got %s (%v)", diff, err)
+	}
+
+	if cmp, _ := a.Cmp(b); cmp != 1 {
+		t.Errorf("Expected 1000 JPY > 800 JPY")
+	}
+
+	if pct, _ := a.PercentChange(b); pct != -20 {
+		t.Errorf("Expected -20%% change, got %.2f", pct)
+	}
+
+	if _, err := a.Add(NewMoney(100, "USD")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected ErrCurrencyMismatch, got %v", err)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	for _, m := range []Money{NewMoney(123456, "USD"), NewMoney(-5, "USD"), NewMoney(1980, "JPY"), NewMoney(1500, "KWD")} {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("Failed to marshal %s: %v", m, err)
+		}
+		var got Money
+		if err := json.Unmarshal(data, &got); err != nil || got != m {
+			t.Errorf("Expected %s to round trip through %s, got %s (%v)", m, data, got, err)
+		}
+	}
+
+	data, _ := json.Marshal(NewMoney(123456, "USD"))
+	if string(data) != `{"amount":"1234.56","currency":"USD"}` {
+		t.Errorf("Unexpected encoding %s", data)
+	}
+
+	// Numeric amounts are in major units too
+	var number Money
+	if err := json.Unmarshal([]byte(`{"amount":1234.56,"currency":"USD"}`), &number); err != nil || number != NewMoney(123456, "USD") {
+		t.Errorf("Expected numeric amount 1234.56 USD, got %s (%v)", number, err)
+	}
+}
+
+func TestMoneyDiv(t *testing.T) {
+	tests := []struct {
+		amount, n, expected int64
+	}{
+		{1000, 3, 333},
+		{1001, 2, 501},
+		{2000, 3, 667},
+		{-2000, 3, -667},
+		{-1001, 2, -501},
+	}
+	for _, tt := range tests {
+		if got := NewMoney(tt.amount, "JPY").Div(tt.n); got.Amount != tt.expected {
+			t.Errorf("%d / %d = %d, expected %d", tt.amount, tt.n, got.Amount, tt.expected)
+		}
+	}
+}
+
+func TestPriceStats(t *testing.T) {
+	product := NewProduct("test-123", "Test Product", "https://example.com/product", "rakuten", NewMoney(1000, "JPY"))
+	product.UpdatePrice(NewMoney(0, "JPY")) // failed extraction, ignored
+	product.UpdatePrice(NewMoney(1200, "JPY"))
+	product.UpdatePrice(NewMoney(800, "JPY"))
+
+	stats, err := product.PriceStats()
+	if err != nil {
+		t.Fatalf("Failed to compute stats: %v", err)
+	}
+
+	if stats.Points != 3 {
+		t.Errorf("Expected 3 points, got %d", stats.Points)
+	}
+	if stats.Min != NewMoney(800, "JPY") || stats.Max != NewMoney(1200, "JPY") {
+		t.Errorf("Expected min 800 and max 1200, got %s and %s", stats.Min, stats.Max)
+	}
+	if stats.Average != NewMoney(1000, "JPY") {
+		t.Errorf("Expected average 1000, got %s", stats.Average)
+	}
+	if stats.Change != NewMoney(-200, "JPY") || stats.ChangePercent != -20 {
+		t.Errorf("Expected change -200 (-20%%), got %s (%.2f%%)", stats.Change, stats.ChangePercent)
+	}
+
+	product.UpdatePrice(NewMoney(10, "USD"))
+	if _, err := product.PriceStats(); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected ErrCurrencyMismatch for mixed history, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+// Availability states of a product listing
+const (
+	AvailabilityUnknown    = ""
+	AvailabilityInStock    = "in_stock"
+	AvailabilityOutOfStock = "out_of_stock"
+	AvailabilityPreorder   = "preorder"
+)
+
+// PointValue is the value of one loyalty point in minor units of JPY
+// (one Rakuten point is worth one yen)
+const PointValue = 1
+
+// OfferDetails describes the purchase conditions beyond the list price
+type OfferDetails struct {
+	Availability string `json:"availability,omitempty"`
+	ShippingFee  *Money `json:"shipping_fee,omitempty"` // nil if unknown, zero if free
+	Points       int64  `json:"points,omitempty"`       // loyalty points awarded
+}
+
+// EffectivePrice returns what a purchase really costs: the price plus
+// shipping, minus the value of the points awarded. Points are only valued
+// for JPY prices; a shipping fee in another currency is ignored.
+func (d OfferDetails) EffectivePrice(price Money) Money {
+	effective := price
+	if d.ShippingFee != nil {
+		if sum, err := effective.Add(*d.ShippingFee); err == nil {
+			effective = sum
+		}
+	}
+	if d.Points > 0 && effective.Currency == "JPY" {
+		effective.Amount -= d.Points * PointValue
+	}
+	return effective
+}
+
+// IsAvailable reports whether the listing can be bought now. Unknown
+// availability counts as available.
+func (d OfferDetails) IsAvailable() bool {
+	return d.Availability != AvailabilityOutOfStock
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEffectivePrice(t *testing.T) {
+	fee := NewMoney(660, "JPY")
+	free := NewMoney(0, "JPY")
+
+	tests := []struct {
+		name     string
+		price    Money
+		details  OfferDetails
+		expected Money
+	}{
+		{name: "No details", price: NewMoney(1980, "JPY"), details: OfferDetails{}, expected: NewMoney(1980, "JPY")},
+		{name: "Shipping fee", price: NewMoney(1980, "JPY"), details: OfferDetails{ShippingFee: &fee}, expected: NewMoney(2640, "JPY")},
+		{name: "Free shipping and points", price: NewMoney(1980, "JPY"), details: OfferDetails{ShippingFee: &free, Points: 190}, expected: NewMoney(1790, "JPY")},
+		{name: "Points not valued outside JPY", price: NewMoney(1999, "USD"), details: OfferDetails{Points: 50}, expected: NewMoney(1999, "USD")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.details.EffectivePrice(tt.price); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestOfferDetailsHistory(t *testing.T) {
+	fee := NewMoney(500, "JPY")
+
+	product := NewProduct("test-123", "Test Product", "https://example.com/product", "rakuten", NewMoney(1000, "JPY"))
+	product.SetOfferDetails(OfferDetails{Availability: AvailabilityInStock, ShippingFee: &fee})
+	product.UpdatePrice(NewMoney(900, "JPY"))
+	product.SetOfferDetails(OfferDetails{Availability: AvailabilityOutOfStock, ShippingFee: &fee})
+	product.UpdatePrice(NewMoney(900, "JPY"))
+	product.SetOfferDetails(OfferDetails{Availability: AvailabilityInStock, Points: 100})
+
+	if got := product.EffectivePrice(); got != NewMoney(800, "JPY") {
+		t.Errorf("Expected effective price 800 JPY, got %s", got)
+	}
+
+	stats, err := product.EffectivePriceStats()
+	if err != nil {
+		t.Fatalf("Failed to compute stats: %v", err)
+	}
+	if stats.First != NewMoney(1500, "JPY") || stats.Last != NewMoney(800, "JPY") {
+		t.Errorf("Expected effective prices from 1500 to 800, got %s to %s", stats.First, stats.Last)
+	}
+
+	changes := product.AvailabilityChanges()
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 availability changes, got %d", len(changes))
+	}
+	if changes[0].From != AvailabilityInStock || changes[0].To != AvailabilityOutOfStock {
+		t.Errorf("Expected in_stock -> out_of_stock, got %s -> %s", changes[0].From, changes[0].To)
+	}
+	if changes[1].To != AvailabilityInStock {
+		t.Errorf("Expected restock, got %s", changes[1].To)
+	}
+
+	data, err := json.Marshal(product)
+	if err != nil {
+		t.Fatalf("Failed to marshal product: %v", err)
+	}
+	var decoded Product
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal product: %v", err)
+	}
+	if decoded.Availability != AvailabilityInStock || decoded.Points != 100 {
+		t.Errorf("Expected offer details to round trip, got %+v", decoded.OfferDetails)
+	}
+	if decoded.PriceHistory[1].ShippingFee == nil || *decoded.PriceHistory[1].ShippingFee != fee {
+		t.Errorf("Expected price point shipping fee to round trip, got %v", decoded.PriceHistory[1].ShippingFee)
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -10,23 +11,44 @@ type Product struct {
 	Name         string       `json:"name"`
 	URL          string       `json:"url"`
 	ImageURL     string       `json:"image_url"`
-	Description  string       `json:"description"`
+	Description  string       `json:"description"` // plain text, paragraphs separated by blank lines
 	PriceHistory []PricePoint `json:"price_history"`
-	CurrentPrice float64      `json:"current_price"`
-	Currency     string       `json:"currency"`
+	CurrentPrice Money        `json:"current_price"`
 	LastUpdated  time.Time    `json:"last_updated"`
-	Website      string       `json:"website"` // e.g., "rakuten"
+	Website      string       `json:"website"`             // e.g., "rakuten"
+	ShopCode     string       `json:"shop_code,omitempty"` // links to Shop.Code
+	GTIN         string       `json:"gtin,omitempty"`      // JAN/EAN normalized to GTIN-13
+	ISBN         string       `json:"isbn,omitempty"`      // ISBN-13, set for books
+	OfferDetails
+
+	DescriptionHTML string            `json:"description_html,omitempty"` // sanitized: no scripts, styles or attributes
+	Attributes      map[string]string `json:"attributes,omitempty"`       // spec table entries, e.g. メーカー, 型番 or サイズ
+
+	Rating        float64        `json:"rating,omitempty"` // average review rating, 0-5
+	ReviewCount   int            `json:"review_count,omitempty"`
+	ReviewHistory []ReviewPoint  `json:"review_history,omitempty"`
+	RankHistory   []RankSnapshot `json:"rank_history,omitempty"`
+
+	Images []ImageVersion `json:"images,omitempty"` // downloaded versions of the listing image, oldest first
+
+	Variants       []Variant `json:"variants,omitempty"`
+	TrackedVariant string    `json:"tracked_variant,omitempty"` // SKU whose price CurrentPrice follows
+
+	// Provenance records how the latest scrape extracted each field. It is
+	// not stored; the API shows it on request.
+	Provenance Provenance `json:"-"`
 }
 
 // PricePoint represents a price at a specific point in time
 type PricePoint struct {
-	Price     float64   `json:"price"`
-	Currency  string    `json:"currency"`
-	Timestamp time.Time `json:"timestamp"`
+	Price      Money     `json:"price"`
+	Timestamp  time.Time `json:"timestamp"`
+	SnapshotID string    `json:"snapshot_id,omitempty"` // archived HTML the point was extracted from
+	OfferDetails
 }
 
 // NewProduct creates a new product with default values
-func NewProduct(id, name, url, website string, price float64, currency string) *Product {
+func NewProduct(id, name, url, website string, price Money) *Product {
 	now := time.Now()
 	return &Product{
 		ID:           id,
@@ -34,11 +56,9 @@ func NewProduct(id, name, url, website string, price float64, currency string) *
 		URL:          url,
 		Website:      website,
 		CurrentPrice: price,
-		Currency:     currency,
 		PriceHistory: []PricePoint{
 			{
 				Price:     price,
-				Currency:  currency,
 				Timestamp: now,
 			},
 		},
@@ -47,16 +67,67 @@ func NewProduct(id, name, url, website string, price float64, currency string) *
 }
 
 // UpdatePrice adds a new price point to the product's price history
-func (p *Product) UpdatePrice(price float64, currency string) {
+func (p *Product) UpdatePrice(price Money) {
 	now := time.Now()
 	p.CurrentPrice = price
-	p.Currency = currency
 	p.LastUpdated = now
 
 	// Add to price history
 	p.PriceHistory = append(p.PriceHistory, PricePoint{
-		Price:     price,
-		Currency:  currency,
-		Timestamp: now,
+		Price:        price,
+		Timestamp:    now,
+		OfferDetails: p.OfferDetails,
 	})
 }
+
+// SetOfferDetails records availability, shipping and points on the product
+// and on its latest price point
+func (p *Product) SetOfferDetails(details OfferDetails) {
+	p.OfferDetails = details
+	if n := len(p.PriceHistory); n > 0 {
+		p.PriceHistory[n-1].OfferDetails = details
+	}
+}
+
+// EffectivePrice returns the current price plus shipping minus points
+func (p *Product) EffectivePrice() Money {
+	return p.OfferDetails.EffectivePrice(p.CurrentPrice)
+}
+
+// EffectivePrice returns the point's price plus shipping minus points
+func (pp PricePoint) EffectivePrice() Money {
+	return pp.OfferDetails.EffectivePrice(pp.Price)
+}
+
+// SetIdentifier records a JAN/EAN/ISBN code on the product if it is valid.
+// It returns false if the code could not be normalized.
+func (p *Product) SetIdentifier(code string) bool {
+	gtin := NormalizeGTIN(code)
+	if gtin == "" {
+		return false
+	}
+
+	p.GTIN = gtin
+	if IsISBN(gtin) {
+		p.ISBN = gtin
+	}
+	return true
+}
+
+// MarshalJSON adds the derived effective price
+func (p Product) MarshalJSON() ([]byte, error) {
+	type alias Product
+	return json.Marshal(struct {
+		alias
+		EffectivePrice Money `json:"effective_price"`
+	}{alias: alias(p), EffectivePrice: p.EffectivePrice()})
+}
+
+// MarshalJSON adds the derived effective price
+func (pp PricePoint) MarshalJSON() ([]byte, error) {
+	type alias PricePoint
+	return json.Marshal(struct {
+		alias
+		EffectivePrice Money `json:"effective_price"`
+	}{alias: alias(pp), EffectivePrice: pp.EffectivePrice()})
+}
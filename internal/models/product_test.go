@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -12,8 +14,7 @@ func TestNewProduct(t *testing.T) {
 		prodName string
 		url      string
 		website  string
-		price    float64
-		currency string
+		price    Money
 	}{
 		{
 			name:     "Valid product creation",
@@ -21,8 +22,7 @@ func TestNewProduct(t *testing.T) {
 			prodName: "Test Product",
 			url:      "https://example.com/product",
 			website:  "rakuten",
-			price:    99.99,
-			currency: "JPY",
+			price:    NewMoney(9999, "USD"),
 		},
 		{
 			name:     "Zero price product",
@@ -30,14 +30,13 @@ func TestNewProduct(t *testing.T) {
 			prodName: "Free Product",
 			url:      "https://example.com/free",
 			website:  "rakuten",
-			price:    0.0,
-			currency: "JPY",
+			price:    NewMoney(0, "JPY"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			product := NewProduct(tt.id, tt.prodName, tt.url, tt.website, tt.price, tt.currency)
+			product := NewProduct(tt.id, tt.prodName, tt.url, tt.website, tt.price)
 
 			// Validate product fields
 			if product.ID != tt.id {
@@ -53,10 +52,7 @@ func TestNewProduct(t *testing.T) {
 				t.Errorf("Expected Website %s, got %s", tt.website, product.Website)
 			}
 			if product.CurrentPrice != tt.price {
-				t.Errorf("Expected CurrentPrice %f, got %f", tt.price, product.CurrentPrice)
-			}
-			if product.Currency != tt.currency {
-				t.Errorf("Expected Currency %s, got %s", tt.currency, product.Currency)
+				t.Errorf("Expected CurrentPrice %s, got %s", tt.price, product.CurrentPrice)
 			}
 
 			// Validate price history
@@ -64,10 +60,7 @@ func TestNewProduct(t *testing.T) {
 				t.Errorf("Expected 1 price history entry, got %d", len(product.PriceHistory))
 			} else {
 				if product.PriceHistory[0].Price != tt.price {
-					t.Errorf("Expected price history price %f, got %f", tt.price, product.PriceHistory[0].Price)
-				}
-				if product.PriceHistory[0].Currency != tt.currency {
-					t.Errorf("Expected price history currency %s, got %s", tt.currency, product.PriceHistory[0].Currency)
+					t.Errorf("Expected price history price %s, got %s", tt.price, product.PriceHistory[0].Price)
 				}
 			}
 		})
@@ -76,7 +69,7 @@ func TestNewProduct(t *testing.T) {
 
 func TestUpdatePrice(t *testing.T) {
 	// Create a product
-	product := NewProduct("test-123", "Test Product", "https://example.com/product", "rakuten", 99.99, "JPY")
+	product := NewProduct("test-123", "Test Product", "https://example.com/product", "rakuten", NewMoney(9999, "USD"))
 
 	// Initial state validation
 	if len(product.PriceHistory) != 1 {
@@ -90,17 +83,12 @@ func TestUpdatePrice(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 
 	// Update the price
-	newPrice := 89.99
-	newCurrency := "JPY"
-	product.UpdatePrice(newPrice, newCurrency)
+	newPrice := NewMoney(8999, "USD")
+	product.UpdatePrice(newPrice)
 
 	// Validate updated fields
 	if product.CurrentPrice != newPrice {
-		t.Errorf("Expected CurrentPrice %f, got %f", newPrice, product.CurrentPrice)
-	}
-
-	if product.Currency != newCurrency {
-		t.Errorf("Expected Currency %s, got %s", newCurrency, product.Currency)
+		t.Errorf("Expected CurrentPrice %s, got %s", newPrice, product.CurrentPrice)
 	}
 
 	// Validate that LastUpdated changed
@@ -114,10 +102,61 @@ func TestUpdatePrice(t *testing.T) {
 	} else {
 		lastEntry := product.PriceHistory[len(product.PriceHistory)-1]
 		if lastEntry.Price != newPrice {
-			t.Errorf("Expected last price history price %f, got %f", newPrice, lastEntry.Price)
-		}
-		if lastEntry.Currency != newCurrency {
-			t.Errorf("Expected last price history currency %s, got %s", newCurrency, lastEntry.Currency)
+			t.Errorf("Expected last price history price %s, got %s", newPrice, lastEntry.Price)
 		}
 	}
 }
+
+func TestProductJSONCompatibility(t *testing.T) {
+	// Layout written before prices were encoded as Money
+	legacy := `[{"id":"test-123","name":"Test Product","current_price":1980,"currency":"JPY",` +
+		`"price_history":[{"price":2180,"currency":"JPY","timestamp":"2024-01-01T00:00:00Z"},` +
+		`{"price":19.99,"currency":"USD","timestamp":"2024-02-01T00:00:00Z"}]}]`
+
+	data, err := UpgradeLegacyJSON([]byte(legacy))
+	if err != nil {
+		t.Fatalf("Failed to upgrade legacy product: %v", err)
+	}
+	var products []Product
+	if err := json.Unmarshal(data, &products); err != nil {
+		t.Fatalf("Failed to unmarshal legacy product: %v", err)
+	}
+	product := products[0]
+
+	if product.CurrentPrice != NewMoney(1980, "JPY") {
+		t.Errorf("Expected CurrentPrice 1980 JPY, got %s", product.CurrentPrice)
+	}
+	if len(product.PriceHistory) != 2 || product.PriceHistory[0].Price != NewMoney(2180, "JPY") {
+		t.Fatalf("Expected price history to be decoded, got %+v", product.PriceHistory)
+	}
+	if price := product.PriceHistory[1].Price; price != NewMoney(1999, "USD") {
+		t.Errorf("Expected price 19.99 USD, got %s", price)
+	}
+
+	product.UpdatePrice(NewMoney(123456, "USD"))
+	data, err = json.Marshal(&product)
+	if err != nil {
+		t.Fatalf("Failed to marshal product: %v", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Failed to unmarshal product fields: %v", err)
+	}
+	want := map[string]any{"amount": "1234.56", "currency": "USD"}
+	if !reflect.DeepEqual(fields["current_price"], want) || !reflect.DeepEqual(fields["effective_price"], want) {
+		t.Errorf("Expected prices of 1234.56 USD, got %v and %v", fields["current_price"], fields["effective_price"])
+	}
+
+	upgraded, err := UpgradeLegacyJSON(data)
+	if err != nil {
+		t.Fatalf("Failed to upgrade current layout: %v", err)
+	}
+	var roundTrip Product
+	if err := json.Unmarshal(upgraded, &roundTrip); err != nil {
+		t.Fatalf("Failed to unmarshal round trip: %v", err)
+	}
+	if roundTrip.CurrentPrice != product.CurrentPrice || len(roundTrip.PriceHistory) != 3 {
+		t.Errorf("Expected round trip price %s, got %s", product.CurrentPrice, roundTrip.CurrentPrice)
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+// Confidence levels of the rule that extracted a field
+const (
+	ConfidenceHigh   = "high"   // structured data such as schema.org markup
+	ConfidenceMedium = "medium" // a selector specific to the site's layout
+	ConfidenceLow    = "low"    // a generic selector or a pattern in free text
+)
+
+// FieldSource records which extraction rule produced a field
+type FieldSource struct {
+	Rule       string `json:"rule"` // e.g. "span[itemprop='price']@content"
+	Confidence string `json:"confidence"`
+}
+
+// Provenance maps extracted fields, by JSON name, to their sources
+type Provenance map[string]FieldSource
+
+// Set records the source of a field
+func (pv *Provenance) Set(field string, source FieldSource) {
+	if *pv == nil {
+		*pv = make(Provenance)
+	}
+	(*pv)[field] = source
+}
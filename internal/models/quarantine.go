@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ValidationIssue is a validation rule a scraped product failed
+type ValidationIssue struct {
+	Field   string `json:"field"`   // e.g. "price"
+	Rule    string `json:"rule"`    // e.g. "price_jump"
+	Message string `json:"message"` // human-readable detail
+}
+
+// QuarantinedProduct is a scraped product held back from storage because it
+// failed validation, so a broken selector can't overwrite good data
+type QuarantinedProduct struct {
+	Product       *Product          `json:"product"`
+	Issues        []ValidationIssue `json:"issues"`
+	PreviousPrice *Money            `json:"previous_price,omitempty"` // stored price when quarantined
+	QuarantinedAt time.Time         `json:"quarantined_at"`
+}
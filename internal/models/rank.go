@@ -0,0 +1,157 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rank sources. A source identifies one ranked list, e.g. "ranking/daily/100227"
+// for the daily best-seller ranking of genre 100227 or "genre/100227" for the
+// position in the genre listing.
+const (
+	RankSourceRanking = "ranking"
+	RankSourceGenre   = "genre"
+)
+
+// rankPeriods is how often each ranking period's list is published. Genre
+// listings are compared day by day.
+var rankPeriods = map[string]time.Duration{
+	"realtime": time.Hour,
+	"daily":    24 * time.Hour,
+	"weekly":   7 * 24 * time.Hour,
+	"monthly":  31 * 24 * time.Hour,
+}
+
+// Movement statuses
+const (
+	MovementNew      = "new"      // first snapshot of the product in the list
+	MovementUnknown  = "unknown"  // the previous snapshot is older than the previous period
+	MovementCompared = "compared" // compared with the snapshot of the previous period
+)
+
+// RankingSource names a best-seller ranking, e.g. RankingSource("daily", "100227")
+// is "ranking/daily/100227". An empty genre stands for the all-genre ranking.
+func RankingSource(period, genreID string) string {
+	if genreID == "" {
+		genreID = "all"
+	}
+	return strings.Join([]string{RankSourceRanking, period, genreID}, "/")
+}
+
+// GenreSource names a genre listing, e.g. "genre/100227"
+func GenreSource(genreID string) string {
+	return RankSourceGenre + "/" + genreID
+}
+
+// RankSnapshot is the position of a product in a ranked list at a point in time
+type RankSnapshot struct {
+	Source    string    `json:"source"`
+	Rank      int       `json:"rank"` // 1-based
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RankMovement describes how a product's rank in a list changed between its
+// two latest snapshots
+type RankMovement struct {
+	ProductID    string    `json:"product_id"`
+	Name         string    `json:"name"`
+	Source       string    `json:"source"`
+	Rank         int       `json:"rank"`
+	Status       string    `json:"status"`                  // MovementNew, MovementUnknown or MovementCompared
+	PreviousRank int       `json:"previous_rank,omitempty"` // set when compared
+	Change       int       `json:"change"`                  // positions gained; negative when the product dropped
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// RecordRank adds a snapshot of the product's rank in a list
+func (p *Product) RecordRank(source string, rank int, at time.Time) {
+	p.RankHistory = append(p.RankHistory, RankSnapshot{
+		Source:    source,
+		Rank:      rank,
+		Timestamp: at,
+	})
+}
+
+// RankSnapshots returns the product's snapshots for one list, oldest first
+func (p *Product) RankSnapshots(source string) []RankSnapshot {
+	var snapshots []RankSnapshot
+	for _, rs := range p.RankHistory {
+		if rs.Source == source {
+			snapshots = append(snapshots, rs)
+		}
+	}
+	return snapshots
+}
+
+// RankMovement compares the product's two latest snapshots for a list. The
+// movement is only computed when the previous snapshot is from the previous
+// period of the list; a product ranked days ago in a daily ranking is
+// reported with an unknown movement. It reports false if the product has
+// never been ranked in the list.
+func (p *Product) RankMovement(source string) (RankMovement, bool) {
+	snapshots := p.RankSnapshots(source)
+	if len(snapshots) == 0 {
+		return RankMovement{}, false
+	}
+
+	last := snapshots[len(snapshots)-1]
+	movement := RankMovement{
+		ProductID: p.ID,
+		Name:      p.Name,
+		Source:    source,
+		Rank:      last.Rank,
+		Status:    MovementNew,
+		Timestamp: last.Timestamp,
+	}
+	if len(snapshots) > 1 {
+		previous := snapshots[len(snapshots)-2]
+		// Crawls drift, so a period and a half is still the previous period
+		if last.Timestamp.Sub(previous.Timestamp) > rankPeriod(source)*3/2 {
+			movement.Status = MovementUnknown
+			return movement, true
+		}
+		movement.Status = MovementCompared
+		movement.PreviousRank = previous.Rank
+		movement.Change = movement.PreviousRank - last.Rank
+	}
+	return movement, true
+}
+
+// rankPeriod returns how often a list is published
+func rankPeriod(source string) time.Duration {
+	parts := strings.Split(source, "/")
+	if len(parts) > 1 && parts[0] == RankSourceRanking {
+		if period, ok := rankPeriods[parts[1]]; ok {
+			return period
+		}
+	}
+	return rankPeriods["daily"]
+}
+
+// LatestRanking rebuilds the most recent crawl of a list from the products'
+// rank histories: products whose latest snapshot for source belongs to the
+// latest crawl, ordered by rank, with their movement since the previous crawl
+func LatestRanking(products []*Product, source string) []RankMovement {
+	var latest time.Time
+	movements := make([]RankMovement, 0)
+	for _, p := range products {
+		if m, ok := p.RankMovement(source); ok {
+			movements = append(movements, m)
+			if m.Timestamp.After(latest) {
+				latest = m.Timestamp
+			}
+		}
+	}
+
+	// Products that dropped out of the list keep their last snapshot
+	current := movements[:0]
+	for _, m := range movements {
+		if m.Timestamp.Equal(latest) {
+			current = append(current, m)
+		}
+	}
+
+	sort.SliceStable(current, func(i, j int) bool { return current[i].Rank < current[j].Rank })
+	return current
+}
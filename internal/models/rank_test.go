@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRankMovement(t *testing.T) {
+	p := NewProduct("test123", "Test Product", "https://example.com/product", "rakuten", NewMoney(1000, "JPY"))
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, ok := p.RankMovement("ranking/daily/100227"); ok {
+		t.Error("Expected no movement for an unranked product")
+	}
+
+	p.RecordRank("ranking/daily/100227", 12, day)
+	p.RecordRank("genre/100227", 40, day)
+
+	m, ok := p.RankMovement("ranking/daily/100227")
+	if !ok || m.Rank != 12 || m.Status != MovementNew || m.PreviousRank != 0 || m.Change != 0 {
+		t.Errorf("Unexpected movement for new entry: %+v", m)
+	}
+
+	p.RecordRank("ranking/daily/100227", 5, day.AddDate(0, 0, 1))
+	m, _ = p.RankMovement("ranking/daily/100227")
+	if m.Rank != 5 || m.Status != MovementCompared || m.PreviousRank != 12 || m.Change != 7 {
+		t.Errorf("Expected climb from 12 to 5, got %+v", m)
+	}
+
+	// Days later the previous snapshot is stale for a daily ranking
+	p.RecordRank("ranking/daily/100227", 3, day.AddDate(0, 0, 5))
+	m, _ = p.RankMovement("ranking/daily/100227")
+	if m.Rank != 3 || m.Status != MovementUnknown || m.PreviousRank != 0 || m.Change != 0 {
+		t.Errorf("Expected unknown movement against a stale snapshot, got %+v", m)
+	}
+
+	// but still the previous period of a weekly one
+	p.RecordRank("ranking/weekly/100227", 8, day)
+	p.RecordRank("ranking/weekly/100227", 6, day.AddDate(0, 0, 8))
+	m, _ = p.RankMovement("ranking/weekly/100227")
+	if m.Status != MovementCompared || m.Change != 2 {
+		t.Errorf("Expected weekly movement of 2, got %+v", m)
+	}
+
+	p.RecordRank("genre/100227", 45, day.AddDate(0, 0, 1))
+	m, _ = p.RankMovement("genre/100227")
+	if m.Change != -5 {
+		t.Errorf("Expected drop of 5 positions, got %+v", m)
+	}
+
+	if n := len(p.RankSnapshots("genre/100227")); n != 2 {
+		t.Errorf("Expected 2 genre snapshots, got %d", n)
+	}
+}
+
+func TestLatestRanking(t *testing.T) {
+	source := RankingSource("daily", "100227")
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	a := NewProduct("a", "A", "https://example.com/a", "rakuten", NewMoney(1000, "JPY"))
+	b := NewProduct("b", "B", "https://example.com/b", "rakuten", NewMoney(1000, "JPY"))
+	c := NewProduct("c", "C", "https://example.com/c", "rakuten", NewMoney(1000, "JPY"))
+
+	a.RecordRank(source, 1, day)
+	b.RecordRank(source, 2, day)
+	c.RecordRank(source, 3, day)
+
+	// Next day: b climbs, a drops, c falls out of the ranking
+	b.RecordRank(source, 1, day.AddDate(0, 0, 1))
+	a.RecordRank(source, 2, day.AddDate(0, 0, 1))
+
+	ranking := LatestRanking([]*Product{a, b, c}, source)
+	if len(ranking) != 2 {
+		t.Fatalf("Expected 2 ranked products, got %d", len(ranking))
+	}
+	if ranking[0].ProductID != "b" || ranking[0].Change != 1 {
+		t.Errorf("Expected b first having climbed 1, got %+v", ranking[0])
+	}
+	if ranking[1].ProductID != "a" || ranking[1].Change != -1 {
+		t.Errorf("Expected a second having dropped 1, got %+v", ranking[1])
+	}
+
+	if got := LatestRanking([]*Product{a}, GenreSource("100227")); len(got) != 0 {
+		t.Errorf("Expected empty ranking for unknown source, got %+v", got)
+	}
+	if got := RankingSource("weekly", ""); got != "ranking/weekly/all" {
+		t.Errorf("Unexpected all-genre source: %s", got)
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// ReviewPoint is a review rating and count at a specific point in time
+type ReviewPoint struct {
+	Rating    float64   `json:"rating"`
+	Count     int       `json:"count"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// UpdateReviews records the current average rating and review count and adds
+// a point to the review history
+func (p *Product) UpdateReviews(rating float64, count int) {
+	p.Rating = rating
+	p.ReviewCount = count
+	p.ReviewHistory = append(p.ReviewHistory, ReviewPoint{
+		Rating:    rating,
+		Count:     count,
+		Timestamp: time.Now(),
+	})
+}
+
+// ReviewVelocity returns the number of new reviews per day over the given
+// window, measured from the latest review point
+func (p *Product) ReviewVelocity(window time.Duration) float64 {
+	if len(p.ReviewHistory) < 2 {
+		return 0
+	}
+
+	last := p.ReviewHistory[len(p.ReviewHistory)-1]
+	since := last.Timestamp.Add(-window)
+
+	// Oldest point inside the window
+	first := last
+	for _, rp := range p.ReviewHistory {
+		if !rp.Timestamp.Before(since) {
+			first = rp
+			break
+		}
+	}
+
+	days := last.Timestamp.Sub(first.Timestamp).Hours() / 24
+	if days <= 0 {
+		return 0
+	}
+	return float64(last.Count-first.Count) / days
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReviewVelocity(t *testing.T) {
+	product := NewProduct("test-123", "Test Product", "https://example.com/product", "rakuten", NewMoney(1000, "JPY"))
+
+	if product.ReviewVelocity(30*24*time.Hour) != 0 {
+		t.Error("Expected zero velocity without review history")
+	}
+
+	product.UpdateReviews(4.2, 100)
+	if product.Rating != 4.2 || product.ReviewCount != 100 {
+		t.Errorf("Expected rating 4.2 with 100 reviews, got %v with %d", product.Rating, product.ReviewCount)
+	}
+
+	now := time.Now()
+	product.ReviewHistory = []ReviewPoint{
+		{Rating: 4.0, Count: 10, Timestamp: now.Add(-60 * 24 * time.Hour)}, // outside the window
+		{Rating: 4.1, Count: 40, Timestamp: now.Add(-20 * 24 * time.Hour)},
+		{Rating: 4.2, Count: 100, Timestamp: now},
+	}
+
+	if v := product.ReviewVelocity(30 * 24 * time.Hour); v != 3 {
+		t.Errorf("Expected 3 reviews per day, got %v", v)
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Shop represents a seller on a marketplace website
+type Shop struct {
+	Code         string    `json:"code"` // e.g. "book" for item.rakuten.co.jp/book/...
+	Name         string    `json:"name"`
+	URL          string    `json:"url"`
+	Website      string    `json:"website"`
+	Rating       float64   `json:"rating,omitempty"` // average shop review rating, 0-5
+	ReviewCount  int       `json:"review_count,omitempty"`
+	ProductCount int       `json:"product_count,omitempty"` // products found by the last catalog crawl
+	LastUpdated  time.Time `json:"last_updated"`
+}
+
+// NewShop creates a new shop with default values
+func NewShop(code, name, url, website string) *Shop {
+	return &Shop{
+		Code:        code,
+		Name:        name,
+		URL:         url,
+		Website:     website,
+		LastUpdated: time.Now(),
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Variant is a purchasable option of a product, such as a size or colour
+type Variant struct {
+	SKU          string            `json:"sku"`
+	Attributes   map[string]string `json:"attributes"` // e.g. {"カラー": "ブラック", "サイズ": "M"}
+	Price        Money             `json:"price"`
+	Availability string            `json:"availability,omitempty"`
+	PriceHistory []PricePoint      `json:"price_history,omitempty"`
+}
+
+// SetVariants replaces the product's variants with freshly scraped ones and
+// records a price point for each. If a variant is tracked, the product price
+// follows it.
+func (p *Product) SetVariants(variants []Variant) {
+	now := time.Now()
+	for i := range variants {
+		v := &variants[i]
+		v.PriceHistory = append(v.PriceHistory, PricePoint{
+			Price:        v.Price,
+			Timestamp:    now,
+			OfferDetails: OfferDetails{Availability: v.Availability},
+		})
+	}
+	p.Variants = variants
+
+	if p.TrackedVariant != "" {
+		p.syncTrackedVariant()
+	}
+}
+
+// Variant returns the variant with the given SKU, or nil
+func (p *Product) Variant(sku string) *Variant {
+	for i := range p.Variants {
+		if p.Variants[i].SKU == sku {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// TrackVariant makes the product's current price and availability follow
+// the variant with the given SKU
+func (p *Product) TrackVariant(sku string) error {
+	if p.Variant(sku) == nil {
+		return fmt.Errorf("variant %s not found on product %s", sku, p.ID)
+	}
+	p.TrackedVariant = sku
+	p.syncTrackedVariant()
+	return nil
+}
+
+// syncTrackedVariant copies the tracked variant's price and availability to
+// the product. The price history stays the product's own: the variant's
+// prices are recorded in the variant's history.
+func (p *Product) syncTrackedVariant() {
+	v := p.Variant(p.TrackedVariant)
+	if v == nil || !v.Price.IsPositive() {
+		return
+	}
+
+	p.CurrentPrice = v.Price
+	if v.Availability != AvailabilityUnknown {
+		p.Availability = v.Availability
+	}
+}
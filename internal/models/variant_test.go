@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestTrackVariant(t *testing.T) {
+	product := NewProduct("test-123", "Test Shirt", "https://example.com/shirt", "rakuten", NewMoney(1980, "JPY"))
+	product.SetVariants([]Variant{
+		{SKU: "s", Attributes: map[string]string{"サイズ": "S"}, Price: NewMoney(1980, "JPY"), Availability: AvailabilityInStock},
+		{SKU: "xl", Attributes: map[string]string{"サイズ": "XL"}, Price: NewMoney(2480, "JPY"), Availability: AvailabilityOutOfStock},
+	})
+
+	if len(product.Variant("xl").PriceHistory) != 1 {
+		t.Fatalf("Expected a price point per variant")
+	}
+
+	if err := product.TrackVariant("missing"); err == nil {
+		t.Error("Expected error for unknown variant")
+	}
+
+	if err := product.TrackVariant("xl"); err != nil {
+		t.Fatalf("Failed to track variant: %v", err)
+	}
+	if product.CurrentPrice != NewMoney(2480, "JPY") {
+		t.Errorf("Expected product price to follow variant, got %s", product.CurrentPrice)
+	}
+	if product.Availability != AvailabilityOutOfStock {
+		t.Errorf("Expected product availability to follow variant, got %s", product.Availability)
+	}
+
+	// Variant prices stay out of the product's own history
+	if len(product.PriceHistory) != 1 || product.PriceHistory[0].Price != NewMoney(1980, "JPY") {
+		t.Errorf("Expected the product history to hold the base price only, got %+v", product.PriceHistory)
+	}
+	if h := product.Variant("xl").PriceHistory; h[len(h)-1].Price != NewMoney(2480, "JPY") {
+		t.Errorf("Expected the variant price in the variant history, got %+v", h)
+	}
+}
+
+func TestMergeHistory(t *testing.T) {
+	previous := NewProduct("test-123", "Test Shirt", "https://example.com/shirt", "rakuten", NewMoney(1980, "JPY"))
+	previous.SetVariants([]Variant{{SKU: "xl", Price: NewMoney(2480, "JPY")}})
+	if err := previous.TrackVariant("xl"); err != nil {
+		t.Fatalf("Failed to track variant: %v", err)
+	}
+
+	fresh := NewProduct("test-123", "Test Shirt", "https://example.com/shirt", "rakuten", NewMoney(1980, "JPY"))
+	fresh.SetVariants([]Variant{{SKU: "xl", Price: NewMoney(2280, "JPY")}, {SKU: "s", Price: NewMoney(1980, "JPY")}})
+	fresh.MergeHistory(previous)
+
+	if fresh.TrackedVariant != "xl" {
+		t.Errorf("Expected tracked variant to carry over, got %q", fresh.TrackedVariant)
+	}
+	if fresh.CurrentPrice != NewMoney(2280, "JPY") {
+		t.Errorf("Expected current price of tracked variant, got %s", fresh.CurrentPrice)
+	}
+	if n := len(fresh.Variant("xl").PriceHistory); n != 2 {
+		t.Errorf("Expected 2 points in merged variant history, got %d", n)
+	}
+	if n := len(fresh.Variant("s").PriceHistory); n != 1 {
+		t.Errorf("Expected 1 point for new variant, got %d", n)
+	}
+	if len(fresh.PriceHistory) != len(previous.PriceHistory)+1 {
+		t.Errorf("Expected product history to extend the previous one by the fresh point, got %d points", len(fresh.PriceHistory))
+	}
+	for _, pp := range fresh.PriceHistory {
+		if pp.Price != NewMoney(1980, "JPY") {
+			t.Errorf("Expected base prices only in the product history, got %s", pp.Price)
+		}
+	}
+}
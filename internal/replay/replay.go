@@ -0,0 +1,206 @@
+// Package replay records HTTP responses to fixture files and replays them,
+// so scrapers can be tested against real pages without the network. A
+// fixture is a pair of files named after the request URL: <name>.json with
+// the status and headers, and <name>.body with the raw response body, kept
+// as served so charset handling is exercised as well.
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tedjuang/go-scrapy/internal/httpcache"
+)
+
+// ErrNoFixture is returned in replay mode for a request without a fixture
+var ErrNoFixture = errors.New("no fixture recorded")
+
+// Mode selects whether a Transport replays or records fixtures
+type Mode int
+
+const (
+	// Replay serves responses from fixtures and fails on anything else
+	Replay Mode = iota
+	// Record fetches responses through the next transport and saves them
+	Record
+)
+
+// maxNameLength bounds the readable part of a fixture name
+const maxNameLength = 80
+
+// unsafeName matches runs of characters not kept in fixture names
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Fixture is the recorded part of a response besides its body
+type Fixture struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+}
+
+// Transport is an http.RoundTripper replaying or recording fixtures in a
+// directory
+type Transport struct {
+	dir  string
+	mode Mode
+	next http.RoundTripper
+}
+
+// NewTransport creates a transport over the fixtures in dir. In Record mode
+// requests go through next (http.DefaultTransport if nil).
+func NewTransport(dir string, mode Mode, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{dir: dir, mode: mode, next: next}
+}
+
+// RoundTrip answers req from its fixture, or records one in Record mode
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name := Name(req.URL.String())
+	if t.mode == Record {
+		return t.record(req, name)
+	}
+
+	fixture, body, err := t.load(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w for %s (%s); re-record with -record", ErrNoFixture, req.URL, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fixture.response(req, body), nil
+}
+
+// record fetches req and saves the response as a fixture
+func (t *Transport) record(req *http.Request, name string) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := Fixture{
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+	}
+	// The body is saved decoded
+	fixture.Header.Del("Content-Encoding")
+	fixture.Header.Del("Content-Length")
+	fixture.Header.Del("Set-Cookie")
+
+	if err := t.save(name, fixture, body); err != nil {
+		return nil, err
+	}
+	return fixture.response(req, body), nil
+}
+
+// load reads a fixture and its body
+func (t *Transport) load(name string) (*Fixture, []byte, error) {
+	data, err := os.ReadFile(filepath.Join(t.dir, name+".json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, nil, fmt.Errorf("invalid fixture %s: %w", name, err)
+	}
+
+	body, err := os.ReadFile(filepath.Join(t.dir, name+".body"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &fixture, body, nil
+}
+
+// save writes a fixture and its body
+func (t *Transport) save(name string, fixture Fixture, body []byte) error {
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(t.dir, name+".json"), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.dir, name+".body"), body, 0644)
+}
+
+// response builds the response to req from a fixture
+func (f *Fixture) response(req *http.Request, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode)),
+		StatusCode:    f.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// Name returns the fixture name of a URL: its canonical host and path made
+// file-safe, plus a short hash of the canonical URL so query strings and
+// truncated names stay distinct, e.g.
+// item.rakuten.co.jp_shop_item-1a2b3c4d
+func Name(rawURL string) string {
+	key := rawURL
+	readable := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		key = httpcache.CanonicalURL(u)
+		readable = u.Host + u.Path
+	}
+
+	readable = strings.Trim(unsafeName.ReplaceAllString(readable, "_"), "_")
+	if len(readable) > maxNameLength {
+		readable = readable[:maxNameLength]
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return readable + "-" + hex.EncodeToString(sum[:4])
+}
+
+// NewServer starts a local server answering from the fixtures in dir. The
+// fixture is looked up by the original URL, given as the "url" query
+// parameter, so pages can be fetched from tools that can't take a custom
+// transport.
+func NewServer(dir string) *httptest.Server {
+	t := NewTransport(dir, Replay, nil)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("url")
+		fixture, body, err := t.load(Name(target))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%v for %s", ErrNoFixture, target), http.StatusNotFound)
+			return
+		}
+
+		for k, v := range fixture.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(fixture.StatusCode)
+		w.Write(body)
+	}))
+}
@@ -0,0 +1,95 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	requests := 0
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/html; charset=EUC-JP")
+		w.Write([]byte("<html>\xa5\xc6\xa5\xb9\xa5\xc8</html>"))
+	}))
+	defer origin.Close()
+
+	dir := t.TempDir()
+	get := func(client *http.Client) (*http.Response, []byte, error) {
+		resp, err := client.Get(origin.URL + "/item/?a=1")
+		if err != nil {
+			return nil, nil, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp, body, err
+	}
+
+	_, recorded, err := get(&http.Client{Transport: NewTransport(dir, Record, nil)})
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	resp, replayed, err := get(&http.Client{Transport: NewTransport(dir, Replay, nil)})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request to the origin, got %d", requests)
+	}
+	if string(replayed) != string(recorded) {
+		t.Errorf("Replayed body %q differs from recorded %q", replayed, recorded)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/html; charset=EUC-JP" {
+		t.Errorf("Expected recorded content type, got %q", ct)
+	}
+}
+
+func TestReplayMissingFixture(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/missing", nil)
+	_, err := NewTransport(t.TempDir(), Replay, nil).RoundTrip(req)
+	if !errors.Is(err, ErrNoFixture) {
+		t.Errorf("Expected ErrNoFixture, got %v", err)
+	}
+}
+
+func TestName(t *testing.T) {
+	a := Name("https://item.rakuten.co.jp/shop/item/?a=1&b=2")
+	if a != Name("https://item.rakuten.co.jp/shop/item/?b=2&a=1") {
+		t.Error("Expected equivalent URLs to share a fixture")
+	}
+	if a == Name("https://item.rakuten.co.jp/shop/item/?a=2") {
+		t.Error("Expected different queries to get different fixtures")
+	}
+	if got := Name("https://item.rakuten.co.jp/shop/item/"); got[:len("item.rakuten.co.jp_shop_item-")] != "item.rakuten.co.jp_shop_item-" {
+		t.Errorf("Unexpected fixture name %q", got)
+	}
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewTransport(dir, Replay, nil).save(Name("https://example.com/page"), Fixture{
+		URL:        "https://example.com/page",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html"}},
+	}, []byte("<p>hi</p>")); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(dir)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/?url=" + url.QueryEscape("https://example.com/page"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "<p>hi</p>" {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/snapshot"
+)
+
+// BackfillResult summarizes a re-extraction over a product's snapshots
+type BackfillResult struct {
+	ProductID string   `json:"product_id"`
+	Snapshots int      `json:"snapshots"`        // snapshots re-extracted
+	Missing   int      `json:"missing"`          // linked snapshots no longer archived
+	Filled    []string `json:"filled,omitempty"` // fields filled, e.g. "gtin" or "price_history[3].availability"
+}
+
+// Backfill re-runs extraction over the archived snapshots linked to the
+// product's price points and fills fields that are empty, without fetching
+// anything. The latest snapshot also fills product-level fields.
+func Backfill(e Extractor, archive *snapshot.Store, p *models.Product) (BackfillResult, error) {
+	result := BackfillResult{ProductID: p.ID}
+
+	// Snapshots are shared between points when the page didn't change
+	extracted := make(map[string]*models.Product)
+	var latest *models.Product
+
+	for i := range p.PriceHistory {
+		pp := &p.PriceHistory[i]
+		if pp.SnapshotID == "" {
+			continue
+		}
+
+		src, ok := extracted[pp.SnapshotID]
+		if !ok {
+			meta, html, err := archive.Load(pp.SnapshotID)
+			if errors.Is(err, snapshot.ErrNotFound) {
+				result.Missing++
+				continue
+			}
+			if err != nil {
+				return result, err
+			}
+
+			// Identical pages share a snapshot, which records the URL it
+			// was first fetched from rather than this product's
+			url := p.URL
+			if url == "" {
+				url = meta.URL
+			}
+			src, err = e.ExtractProduct(url, html)
+			if err != nil {
+				return result, fmt.Errorf("failed to re-extract snapshot %s: %w", pp.SnapshotID, err)
+			}
+			extracted[pp.SnapshotID] = src
+			result.Snapshots++
+		}
+
+		for _, field := range pp.FillMissing(src) {
+			result.Filled = append(result.Filled, fmt.Sprintf("price_history[%d].%s", i, field))
+		}
+		latest = src
+	}
+
+	if latest != nil {
+		result.Filled = append(result.Filled, p.FillMissing(latest)...)
+		if last := p.PriceHistory[len(p.PriceHistory)-1]; p.CurrentPrice.IsZero() {
+			p.CurrentPrice = last.Price
+		}
+	}
+	return result, nil
+}
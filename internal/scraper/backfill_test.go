@@ -0,0 +1,111 @@
+package scraper
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/snapshot"
+)
+
+const testItemURL = "https://item.rakuten.co.jp/shop/switch-oled/"
+
+func TestExtractProductFromHTML(t *testing.T) {
+	html, err := os.ReadFile("testdata/rakuten_item.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewRakutenScraper().ExtractProduct(testItemURL, html)
+	if err != nil {
+		t.Fatalf("ExtractProduct failed: %v", err)
+	}
+
+	if p.ID != "switch-oled" || p.ShopCode != "shop" || p.Name != "Nintendo Switch 有機ELモデル" {
+		t.Errorf("Unexpected product identity: %+v", p)
+	}
+	if p.CurrentPrice != models.NewMoney(37980, "JPY") {
+		t.Errorf("Expected 37980 JPY, got %s", p.CurrentPrice)
+	}
+	if p.GTIN != "4902370548495" || p.Availability != models.AvailabilityInStock || p.Points != 379 {
+		t.Errorf("Unexpected details: gtin=%s availability=%s points=%d", p.GTIN, p.Availability, p.Points)
+	}
+	if p.ShippingFee == nil || !p.ShippingFee.IsZero() {
+		t.Errorf("Expected free shipping, got %v", p.ShippingFee)
+	}
+	if p.Rating != 4.72 || p.ReviewCount != 1234 {
+		t.Errorf("Unexpected reviews: %v / %d", p.Rating, p.ReviewCount)
+	}
+}
+
+func TestBackfillFromSnapshots(t *testing.T) {
+	html, err := os.ReadFile("testdata/rakuten_item.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := snapshot.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := archive.Save(testItemURL, "rakuten", html)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A product stored when the GTIN and availability selectors were broken
+	p := models.NewProduct("switch-oled", "Nintendo Switch 有機ELモデル", testItemURL, "rakuten", models.NewMoney(37980, "JPY"))
+	p.LinkSnapshot(id)
+	p.PriceHistory = append(p.PriceHistory, models.PricePoint{Price: models.NewMoney(36980, "JPY"), SnapshotID: "0000000000000000000000000000000000000000000000000000000000000000"})
+
+	result, err := Backfill(NewRakutenScraper(), archive, p)
+	if err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	if result.Snapshots != 1 || result.Missing != 1 {
+		t.Errorf("Unexpected backfill counts: %+v", result)
+	}
+	if p.GTIN != "4902370548495" || p.PriceHistory[0].Availability != models.AvailabilityInStock {
+		t.Errorf("Expected GTIN and availability backfilled, got %+v", p)
+	}
+	if p.PriceHistory[0].Price != models.NewMoney(37980, "JPY") {
+		t.Errorf("Expected stored price to be kept, got %s", p.PriceHistory[0].Price)
+	}
+}
+
+// urlRecorder is an Extractor recording the URLs it extracts pages of
+type urlRecorder struct {
+	urls []string
+}
+
+func (r *urlRecorder) ExtractProduct(url string, html []byte) (*models.Product, error) {
+	r.urls = append(r.urls, url)
+	return &models.Product{URL: url}, nil
+}
+
+func (r *urlRecorder) ExtractSearch(url string, html []byte) ([]*models.Product, error) {
+	return nil, nil
+}
+
+func TestBackfillUsesProductURL(t *testing.T) {
+	archive, err := snapshot.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another product's identical page was archived first
+	id, err := archive.Save("https://item.rakuten.co.jp/shop/other/", "rakuten", []byte("<html>same</html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := models.NewProduct("item", "Item", testItemURL, "rakuten", models.NewMoney(1980, "JPY"))
+	p.LinkSnapshot(id)
+
+	var e urlRecorder
+	if _, err := Backfill(&e, archive, p); err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+	if len(e.urls) != 1 || e.urls[0] != testItemURL {
+		t.Errorf("Expected extraction with the product's URL, got %v", e.urls)
+	}
+}
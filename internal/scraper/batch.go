@@ -0,0 +1,36 @@
+package scraper
+
+import (
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// BatchFailure records a URL that could not be scraped or saved
+type BatchFailure struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// BatchResult summarizes a batch scrape
+type BatchResult struct {
+	Requested int            `json:"requested"`
+	Scraped   int            `json:"scraped"`
+	Failures  []BatchFailure `json:"failures,omitempty"`
+}
+
+// ScrapeBatch scrapes each URL in turn and hands the product to save. A
+// failing URL is recorded and the batch continues.
+func ScrapeBatch(s Scraper, urls []string, save func(*models.Product) error) BatchResult {
+	result := BatchResult{Requested: len(urls)}
+	for _, u := range urls {
+		product, err := s.ScrapeProduct(u)
+		if err == nil {
+			err = save(product)
+		}
+		if err != nil {
+			result.Failures = append(result.Failures, BatchFailure{URL: u, Error: err.Error()})
+			continue
+		}
+		result.Scraped++
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// stubScraper returns a product for every URL except those in fail
+type stubScraper struct {
+	fail map[string]bool
+}
+
+func (s *stubScraper) ScrapeProduct(url string) (*models.Product, error) {
+	if s.fail[url] {
+		return nil, errors.New("not found")
+	}
+	return models.NewProduct(url, "Product", url, "stub", models.NewMoney(100, "JPY")), nil
+}
+
+func (s *stubScraper) ScrapeSearch(keyword string, maxProducts int) ([]*models.Product, error) {
+	return nil, nil
+}
+
+func TestScrapeBatch(t *testing.T) {
+	s := &stubScraper{fail: map[string]bool{"b": true}}
+	var saved []string
+
+	result := ScrapeBatch(s, []string{"a", "b", "c"}, func(p *models.Product) error {
+		if p.ID == "c" {
+			return errors.New("disk full")
+		}
+		saved = append(saved, p.ID)
+		return nil
+	})
+
+	if result.Requested != 3 || result.Scraped != 1 || len(result.Failures) != 2 {
+		t.Errorf("Unexpected batch result: %+v", result)
+	}
+	if len(saved) != 1 || saved[0] != "a" {
+		t.Errorf("Expected only a to be saved, got %v", saved)
+	}
+}
+
+func TestRakutenSitemapConfig(t *testing.T) {
+	site, ok := NewScraperFactory().GetSiteConfig("rakuten")
+	if !ok || !site.Sitemap.Enabled() {
+		t.Fatal("Expected rakuten to have sitemap discovery configured")
+	}
+
+	tests := map[string]bool{
+		"https://item.rakuten.co.jp/book/14583459/":  true,
+		"https://books.rakuten.co.jp/rb/14583459/":   true,
+		"https://item.rakuten.co.jp/book/c/0000001/": false,
+		"https://www.rakuten.co.jp/book/":            false,
+	}
+	for loc, expected := range tests {
+		if got := site.Sitemap.IsProduct(loc); got != expected {
+			t.Errorf("IsProduct(%q) = %v; want %v", loc, got, expected)
+		}
+	}
+}
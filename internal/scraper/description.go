@@ -0,0 +1,319 @@
+package scraper
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Elements of descriptions that are dropped with their content
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Svg: true,
+	atom.Form: true, atom.Input: true, atom.Button: true, atom.Select: true, atom.Textarea: true,
+	atom.Link: true, atom.Meta: true, atom.Head: true, atom.Title: true,
+}
+
+// Elements kept, without attributes, in sanitized descriptions. Others are
+// replaced by their content.
+var keptTags = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Ul: true, atom.Ol: true, atom.Li: true,
+	atom.B: true, atom.Strong: true, atom.I: true, atom.Em: true, atom.U: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Table: true, atom.Thead: true, atom.Tbody: true, atom.Tr: true, atom.Th: true, atom.Td: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true,
+}
+
+// Block elements, which start a new paragraph in plain text unless they
+// are in lineTags
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Center: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Blockquote: true, atom.Pre: true, atom.Hr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Table: true, atom.Tr: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+}
+
+// Block elements that only start a new line in plain text, keeping tables,
+// lists and definition lists together
+var lineTags = map[atom.Atom]bool{
+	atom.Tr: true, atom.Li: true, atom.Dt: true, atom.Dd: true,
+}
+
+// Limits of spec table entries; longer cells are prose or page layout
+const (
+	maxAttributeKey   = 30  // runes
+	maxAttributeValue = 500 // runes
+)
+
+// attributesSource is the provenance of attributes read from spec tables
+var attributesSource = models.FieldSource{Rule: "table th/td, dl dt/dd", Confidence: models.ConfidenceMedium}
+
+// description is a product description cleaned of scripts, styles and
+// markup
+type description struct {
+	html string // sanitized HTML
+	text string // plain text, paragraphs separated by blank lines
+}
+
+// extractDescription cleans the first element matched by rules, in their
+// order of precedence, that has any text
+func extractDescription(doc *goquery.Selection, rules []fieldRule) (description, models.FieldSource, bool) {
+	for _, r := range rules {
+		var d description
+		doc.Find(r.selector).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			d = cleanDescription(s)
+			return d.text == ""
+		})
+		if d.text != "" {
+			return d, r.source(), true
+		}
+	}
+	return description{}, models.FieldSource{}, false
+}
+
+// cleanDescription cleans the first element of s
+func cleanDescription(s *goquery.Selection) description {
+	if s.Length() == 0 {
+		return description{}
+	}
+	var sanitized strings.Builder
+	var text textWriter
+	for c := s.Nodes[0].FirstChild; c != nil; c = c.NextSibling {
+		sanitizeNode(&sanitized, c)
+		text.write(c)
+	}
+	return description{html: strings.TrimSpace(sanitized.String()), text: text.String()}
+}
+
+// sanitizeNode writes n as sanitized HTML: kept elements without their
+// attributes, divs as paragraphs unless they hold blocks, and the content
+// of other elements. Empty elements are left out.
+func sanitizeNode(w *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		text := collapseSpaces(n.Data)
+		// Indentation between blocks and after line breaks
+		if text == " " && (isBlock(n.PrevSibling) || isBlock(n.NextSibling) || isBreak(n.PrevSibling)) {
+			return
+		}
+		w.WriteString(html.EscapeString(text))
+		return
+	case html.ElementNode:
+	default:
+		return // comments and doctypes
+	}
+	if droppedTags[n.DataAtom] {
+		return
+	}
+	if n.DataAtom == atom.Br {
+		w.WriteString("<br>")
+		return
+	}
+
+	var inner strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sanitizeNode(&inner, c)
+	}
+
+	tag := ""
+	switch {
+	case keptTags[n.DataAtom]:
+		tag = n.DataAtom.String()
+	case blockTags[n.DataAtom] && !hasBlockChild(n):
+		tag = "p"
+	}
+	content := inner.String()
+	if tag == "" {
+		w.WriteString(content)
+		return
+	}
+	if strings.TrimSpace(strings.ReplaceAll(content, "<br>", "")) == "" && n.DataAtom != atom.Td && n.DataAtom != atom.Th {
+		return
+	}
+	w.WriteString("<" + tag + ">" + strings.TrimSpace(content) + "</" + tag + ">")
+}
+
+// isBlock reports whether n is a block element
+func isBlock(n *html.Node) bool {
+	return n != nil && n.Type == html.ElementNode && blockTags[n.DataAtom]
+}
+
+// isBreak reports whether n is a line break
+func isBreak(n *html.Node) bool {
+	return n != nil && n.Type == html.ElementNode && n.DataAtom == atom.Br
+}
+
+// hasBlockChild reports whether an element contains block elements
+func hasBlockChild(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isBlock(c) || hasBlockChild(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// textWriter renders nodes as plain text: paragraphs separated by blank
+// lines, <br> as line breaks, whitespace collapsed and table cells of a
+// row on one line
+type textWriter struct {
+	paragraphs []string
+	lines      []string
+	line       strings.Builder
+}
+
+// write renders n and its content
+func (t *textWriter) write(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		t.line.WriteString(collapseSpaces(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if droppedTags[n.DataAtom] {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		t.endLine()
+		return
+	case atom.Td, atom.Th:
+		// Cells after the first of a row: "key: value" after a header cell
+		if prev := previousElement(n); prev != nil {
+			if prev.DataAtom == atom.Th && n.DataAtom == atom.Td {
+				t.line.WriteString(": ")
+			} else {
+				t.line.WriteString(" ")
+			}
+		}
+	}
+
+	end := func() {}
+	switch {
+	case lineTags[n.DataAtom]:
+		end = t.endLine
+	case blockTags[n.DataAtom]:
+		end = t.endParagraph
+	}
+	end()
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		t.write(c)
+	}
+	end()
+}
+
+// endLine ends the current line, if it has any text
+func (t *textWriter) endLine() {
+	if line := strings.TrimSpace(t.line.String()); line != "" {
+		t.lines = append(t.lines, line)
+	}
+	t.line.Reset()
+}
+
+// endParagraph ends the current paragraph, if it has any text
+func (t *textWriter) endParagraph() {
+	t.endLine()
+	if len(t.lines) > 0 {
+		t.paragraphs = append(t.paragraphs, strings.Join(t.lines, "\n"))
+	}
+	t.lines = nil
+}
+
+// String returns the rendered text
+func (t *textWriter) String() string {
+	t.endParagraph()
+	return strings.Join(t.paragraphs, "\n\n")
+}
+
+// previousElement returns the element before n among its siblings, or nil
+func previousElement(n *html.Node) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+// collapseSpaces replaces runs of whitespace, full-width spaces included,
+// by one space
+func collapseSpaces(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			return " "
+		}
+		return ""
+	}
+	collapsed := strings.Join(fields, " ")
+	if strings.TrimLeftFunc(s, unicode.IsSpace) != s {
+		collapsed = " " + collapsed
+	}
+	if strings.TrimRightFunc(s, unicode.IsSpace) != s {
+		collapsed += " "
+	}
+	return collapsed
+}
+
+// extractAttributes reads the key/value pairs of the spec tables in doc,
+// such as メーカー, 型番 or サイズ: table rows of header and data cells, rows
+// of two data cells, and definition lists. The first value of a key wins.
+// Rows of nested tables and overlong cells, which are page layout rather
+// than specs, are skipped.
+func extractAttributes(doc *goquery.Selection) map[string]string {
+	attributes := make(map[string]string)
+	add := func(key, value *goquery.Selection) {
+		if key.Find("table").Length() > 0 || value.Find("table").Length() > 0 {
+			return
+		}
+		k := strings.Trim(cellText(key), " :：・■●◆【】[]")
+		v := cellText(value)
+		if k == "" || v == "" || utf8.RuneCountInString(k) > maxAttributeKey || utf8.RuneCountInString(v) > maxAttributeValue {
+			return
+		}
+		if _, ok := attributes[k]; !ok {
+			attributes[k] = v
+		}
+	}
+
+	doc.Find("tr").Each(func(_ int, row *goquery.Selection) {
+		cells := row.ChildrenFiltered("th, td")
+		if cells.Filter("th").Length() == 0 {
+			if cells.Length() == 2 {
+				add(cells.Eq(0), cells.Eq(1))
+			}
+			return
+		}
+		// Rows may hold several header/data pairs
+		cells.Each(func(i int, cell *goquery.Selection) {
+			if goquery.NodeName(cell) == "th" && i+1 < cells.Length() && goquery.NodeName(cells.Eq(i+1)) == "td" {
+				add(cell, cells.Eq(i+1))
+			}
+		})
+	})
+
+	doc.Find("dt").Each(func(_ int, term *goquery.Selection) {
+		if def := term.NextFiltered("dd"); def.Length() > 0 {
+			add(term, def)
+		}
+	})
+
+	if len(attributes) == 0 {
+		return nil
+	}
+	return attributes
+}
+
+// cellText returns the plain text of a table cell on one line
+func cellText(s *goquery.Selection) string {
+	d := cleanDescription(s)
+	return strings.Join(strings.Fields(d.text), " ")
+}
@@ -0,0 +1,82 @@
+package scraper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const testDescriptionPage = `<html><body>
+<div id="itemCaption" style="color:red">
+  <script>var tracking = "do not show";</script>
+  <style>.x { color: blue; }</style>
+  <p onclick="alert(1)">7インチ<b>有機EL</b>ディスプレイ搭載。</p>
+  <p>　</p>
+  <div><font color="red">カラー：ホワイト</font><br>
+  <a href="https://example.com/">同梱物</a>：ドック、Joy-Con</div>
+  <table class="spec">
+    <tr><th>メーカー</th><td>任天堂</td></tr>
+    <tr><th>【型番】</th><td>HEG-S-KAAAA</td><th>サイズ</th><td>102×242×13.9mm</td></tr>
+    <tr><td>重量：</td><td>約420g<br>（Joy-Con装着時）</td></tr>
+    <tr><td>3</td><td>cells</td><td>ignored</td></tr>
+  </table>
+  <dl><dt>保証期間</dt><dd>1年</dd></dl>
+</div>
+<table><tr><td><table><tr><th>メーカー</th><td>Layout</td></tr></table></td><td>sidebar</td></tr></table>
+</body></html>`
+
+func TestCleanDescription(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testDescriptionPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, source, ok := extractDescription(doc.Selection, rakutenDescriptionRules)
+	if !ok || source.Rule != "#itemCaption" {
+		t.Fatalf("Expected the caption, got %v %+v", ok, source)
+	}
+
+	wantText := "7インチ有機ELディスプレイ搭載。\n\n" +
+		"カラー：ホワイト\n同梱物：ドック、Joy-Con\n\n" +
+		"メーカー: 任天堂\n【型番】: HEG-S-KAAAA サイズ: 102×242×13.9mm\n重量： 約420g\n（Joy-Con装着時）\n3 cells ignored\n\n" +
+		"保証期間\n1年"
+	if d.text != wantText {
+		t.Errorf("Unexpected text:\n%s\nwant:\n%s", d.text, wantText)
+	}
+
+	for _, unwanted := range []string{"tracking", "color", "onclick", "href", "<font", "<a", "<div", "<p></p>"} {
+		if strings.Contains(d.html, unwanted) {
+			t.Errorf("Expected %q removed from %s", unwanted, d.html)
+		}
+	}
+	for _, wanted := range []string{"<p>7インチ<b>有機EL</b>ディスプレイ搭載。</p>", "<p>カラー：ホワイト<br>同梱物：ドック、Joy-Con</p>", "<th>メーカー</th><td>任天堂</td>", "<dt>保証期間</dt><dd>1年</dd>"} {
+		if !strings.Contains(d.html, wanted) {
+			t.Errorf("Expected %q in %s", wanted, d.html)
+		}
+	}
+}
+
+func TestExtractAttributes(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testDescriptionPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"メーカー": "任天堂",
+		"型番":   "HEG-S-KAAAA",
+		"サイズ":  "102×242×13.9mm",
+		"重量":   "約420g （Joy-Con装着時）",
+		"保証期間": "1年",
+	}
+	if got := extractAttributes(doc.Selection); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected attributes: %v", got)
+	}
+
+	doc, _ = goquery.NewDocumentFromReader(strings.NewReader("<p>No specs</p>"))
+	if got := extractAttributes(doc.Selection); got != nil {
+		t.Errorf("Expected no attributes, got %v", got)
+	}
+}
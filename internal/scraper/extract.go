@@ -0,0 +1,87 @@
+package scraper
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// staticTransport answers every request with the same HTML page
+type staticTransport struct {
+	html []byte
+}
+
+// RoundTrip returns the page as a 200 response
+func (t staticTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:          io.NopCloser(bytes.NewReader(t.html)),
+		ContentLength: int64(len(t.html)),
+		Request:       req,
+	}, nil
+}
+
+// replayCollector returns a collector that serves html for any URL, so the
+// extraction callbacks can run over a page without fetching it. Archived
+// pages are stored after charset conversion, hence always UTF-8.
+func replayCollector(html []byte) *colly.Collector {
+	c := colly.NewCollector(colly.AllowURLRevisit())
+	c.WithTransport(staticTransport{html: html})
+	return c
+}
+
+// fieldRule is one way of extracting a field: the attribute attr, or the
+// text if attr is empty, of elements matching selector
+type fieldRule struct {
+	selector   string
+	attr       string
+	confidence string
+}
+
+// source describes the rule for provenance, e.g. "meta[property='og:image']@content"
+func (r fieldRule) source() models.FieldSource {
+	rule := r.selector
+	if r.attr != "" {
+		rule += "@" + r.attr
+	}
+	return models.FieldSource{Rule: rule, Confidence: r.confidence}
+}
+
+// value returns the trimmed attribute or text of an element
+func (r fieldRule) value(s *goquery.Selection) string {
+	if r.attr != "" {
+		return strings.TrimSpace(s.AttrOr(r.attr, ""))
+	}
+	return strings.TrimSpace(s.Text())
+}
+
+// applyRules tries rules in their order of precedence and returns the first
+// non-empty value accepted by accept (any value if nil) with the rule's
+// provenance. Within a rule, elements are tried in document order, so the
+// result doesn't depend on where the page places competing candidates.
+func applyRules(doc *goquery.Selection, rules []fieldRule, accept func(string) bool) (string, models.FieldSource, bool) {
+	for _, r := range rules {
+		var value string
+		doc.Find(r.selector).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if v := r.value(s); v != "" && (accept == nil || accept(v)) {
+				value = v
+				return false
+			}
+			return true
+		})
+		if value != "" {
+			return value, r.source(), true
+		}
+	}
+	return "", models.FieldSource{}, false
+}
@@ -0,0 +1,85 @@
+package scraper
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestParseProductPagePrecedence(t *testing.T) {
+	// Generic candidates come first in the document; the structured ones win
+	page := `<html><body>
+<div class="price">9,999円</div>
+<h1 class="item-name">Layout Name</h1>
+<div class="price-box"><span class="price">1,980円</span></div>
+<span itemprop="price" content="1780">1,780円</span>
+<div class="item-info">Specs</div>
+<div id="itemCaption">Caption</div>
+<div class="shipping">送料 660円</div>
+<div id="postageInfo">送料無料</div>
+</body></html>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := parseProductPage("https://item.rakuten.co.jp/shop/item/", doc.Selection)
+	if p == nil {
+		t.Fatal("Expected a product")
+	}
+
+	if p.CurrentPrice != models.NewMoney(1780, "JPY") || len(p.PriceHistory) != 1 {
+		t.Errorf("Expected the itemprop price only, got %s with %d points", p.CurrentPrice, len(p.PriceHistory))
+	}
+	if p.Description != "Caption" {
+		t.Errorf("Expected the caption over the generic info block, got %q", p.Description)
+	}
+	if p.ShippingFee == nil || !p.ShippingFee.IsZero() {
+		t.Errorf("Expected free shipping from #postageInfo, got %v", p.ShippingFee)
+	}
+
+	expected := map[string]models.FieldSource{
+		"name":         {Rule: "h1.item-name", Confidence: models.ConfidenceMedium},
+		"price":        {Rule: "[itemprop='price']@content", Confidence: models.ConfidenceHigh},
+		"description":  {Rule: "#itemCaption", Confidence: models.ConfidenceMedium},
+		"shipping_fee": {Rule: "#postageInfo", Confidence: models.ConfidenceMedium},
+	}
+	for field, want := range expected {
+		if got := p.Provenance[field]; got != want {
+			t.Errorf("Provenance of %s: got %+v, want %+v", field, got, want)
+		}
+	}
+	if _, ok := p.Provenance["gtin"]; ok {
+		t.Error("Expected no provenance for a field that wasn't extracted")
+	}
+}
+
+func TestParseProductPageWithoutName(t *testing.T) {
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(`<html><body><span class="price">1,980円</span></body></html>`))
+	if p := parseProductPage("https://item.rakuten.co.jp/shop/item/", doc.Selection); p != nil {
+		t.Errorf("Expected nil without a name, got %+v", p)
+	}
+}
+
+func TestExtractSearchFromHTML(t *testing.T) {
+	html, err := os.ReadFile("testdata/synthetic/fixtures/search.rakuten.co.jp_search_mall_switch-65eb39df.body")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	products, err := NewRakutenScraper().ExtractSearch("https://search.rakuten.co.jp/search/mall/switch/", html)
+	if err != nil {
+		t.Fatalf("ExtractSearch failed: %v", err)
+	}
+
+	if len(products) != 4 {
+		t.Fatalf("Expected every card of the page, got %d products", len(products))
+	}
+	if p := products[0]; p.ID != "switch-oled" || p.Provenance["name"].Rule == "" {
+		t.Errorf("Unexpected first product %s with provenance %v", p.ID, p.Provenance)
+	}
+}
@@ -0,0 +1,380 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+	"github.com/tedjuang/go-scrapy/internal/jsonpath"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// JSONRequest is a follow-up request a scraper sends after a product page
+// for data the page loads by script, such as price and stock, instead of
+// rendering the page. It is sent by the crawl's collector, under the same
+// crawl policy, rate limits, retries and block detection as the page, and
+// the values it maps override those extracted from the page.
+type JSONRequest struct {
+	Name string // identifies the request in logs and provenance
+
+	// URL is a template whose {name} placeholders are replaced by the
+	// query-escaped value of a variable: {url} and {id} of the page and its
+	// product, or one of Vars. Its host must be one the scraper may visit.
+	URL string
+
+	// Vars are read from the page: "selector" for the text of the first
+	// matching element, "selector@attr" for its attribute
+	Vars map[string]string
+
+	// Fields maps product fields, by JSON name, to the JSONPath of their
+	// value in the response. See jsonFields for the supported fields.
+	Fields map[string]string
+}
+
+// jsonFields are the product fields a JSONRequest can set, in the order they
+// are applied, with the JSON values they accept
+var jsonFields = []string{
+	"name",         // string
+	"currency",     // ISO code of price and shipping_fee, defaulting to the product's
+	"price",        // number in major units, or a displayed price such as "1,980円"
+	"shipping_fee", // number in major units
+	"points",       // number
+	"availability", // string such as "in_stock" or "在庫あり"
+	"in_stock",     // boolean
+	"stock",        // number of items left, 0 meaning out of stock
+	"rating",       // number, 0-5
+	"review_count", // number
+	"image_url",    // string
+	"gtin",         // JAN/EAN/ISBN string or number
+}
+
+// placeholderPattern matches the {name} placeholders of a URL template
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// compiledJSONRequest is a validated JSONRequest
+type compiledJSONRequest struct {
+	JSONRequest
+	vars   map[string]fieldRule
+	fields map[string]*jsonpath.Path
+}
+
+// compileJSONRequests validates requests. Invalid ones are logged and left
+// out, like other bad site settings.
+func compileJSONRequests(requests []JSONRequest) []compiledJSONRequest {
+	var compiled []compiledJSONRequest
+	for _, r := range requests {
+		c, err := compileJSONRequest(r)
+		if err != nil {
+			log.Printf("Warning: JSON request %q ignored: %v", r.Name, err)
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+// compileJSONRequest parses the variables and paths of r and checks that
+// its template and fields are known
+func compileJSONRequest(r JSONRequest) (compiledJSONRequest, error) {
+	c := compiledJSONRequest{JSONRequest: r, vars: make(map[string]fieldRule), fields: make(map[string]*jsonpath.Path)}
+	if u, err := neturl.Parse(placeholderPattern.ReplaceAllString(r.URL, "x")); err != nil || u.Host == "" {
+		return c, fmt.Errorf("invalid URL template: %s", r.URL)
+	}
+	if len(r.Fields) == 0 {
+		return c, errors.New("no fields")
+	}
+
+	for name, spec := range r.Vars {
+		rule := fieldRule{selector: spec, confidence: models.ConfidenceHigh}
+		if i := strings.LastIndex(spec, "@"); i > 0 && !strings.ContainsAny(spec[i+1:], " ]") {
+			rule.selector, rule.attr = spec[:i], spec[i+1:]
+		}
+		c.vars[name] = rule
+	}
+	for _, m := range placeholderPattern.FindAllStringSubmatch(r.URL, -1) {
+		if _, ok := c.vars[m[1]]; !ok && m[1] != "url" && m[1] != "id" {
+			return c, fmt.Errorf("unknown variable {%s}", m[1])
+		}
+	}
+
+	for field, expr := range r.Fields {
+		if !slices.Contains(jsonFields, field) {
+			return c, fmt.Errorf("unsupported field %q", field)
+		}
+		path, err := jsonpath.Compile(expr)
+		if err != nil {
+			return c, err
+		}
+		c.fields[field] = path
+	}
+	return c, nil
+}
+
+// requestURL fills the URL template with the values of product and page.
+// It fails if a variable isn't on the page.
+func (r compiledJSONRequest) requestURL(product *models.Product, page *goquery.Selection) (string, error) {
+	var missing string
+	url := placeholderPattern.ReplaceAllStringFunc(r.URL, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		var value string
+		switch rule, ok := r.vars[name]; {
+		case ok:
+			value, _, _ = applyRules(page, []fieldRule{rule}, nil)
+		case name == "url":
+			value = product.URL
+		case name == "id":
+			value = product.ID
+		}
+		if value == "" && missing == "" {
+			missing = name
+		}
+		return neturl.QueryEscape(value)
+	})
+	if missing != "" {
+		return "", fmt.Errorf("variable {%s} not found on the page", missing)
+	}
+	return url, nil
+}
+
+// followUp sends the JSON requests of a product page with collector c and
+// merges their results into product. A request that fails is logged and
+// leaves the values of the page.
+func followUp(c *colly.Collector, product *models.Product, page *goquery.Selection, requests []compiledJSONRequest) {
+	c.OnRequest(func(r *colly.Request) {
+		if r.Ctx.Get("json_request") != "" {
+			r.Headers.Set("Accept", "application/json, text/javascript, */*; q=0.01")
+		}
+	})
+	bodies := make(map[string][]byte)
+	c.OnResponse(func(r *colly.Response) {
+		if name := r.Ctx.Get("json_request"); name != "" {
+			bodies[name] = r.Body
+		}
+	})
+
+	for _, r := range requests {
+		url, err := r.requestURL(product, page)
+		if err != nil {
+			log.Printf("Warning: JSON request %q of %s skipped: %v", r.Name, product.URL, err)
+			continue
+		}
+
+		ctx := colly.NewContext()
+		ctx.Put("json_request", r.Name)
+		hdr := http.Header{}
+		hdr.Set("Referer", product.URL)
+		hdr.Set("X-Requested-With", "XMLHttpRequest")
+		if err := c.Request(http.MethodGet, url, nil, ctx, hdr); err != nil {
+			log.Printf("Warning: JSON request %q of %s failed: %v", r.Name, product.URL, err)
+			continue
+		}
+		c.Wait()
+
+		body, ok := bodies[r.Name]
+		if !ok {
+			continue // the failure was logged by the crawl's error callback
+		}
+		if err := r.apply(product, body); err != nil {
+			log.Printf("Warning: JSON request %q of %s: %v", r.Name, product.URL, err)
+		}
+	}
+}
+
+// apply merges the fields mapped by r from a response body into product.
+// Every field is parsed before any is set, so a response that fails leaves
+// product as it was.
+func (r compiledJSONRequest) apply(product *models.Product, body []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	currency := product.CurrentPrice.Currency
+	if currency == "" {
+		currency = "JPY"
+	}
+	details := product.OfferDetails
+	rating, reviewCount := product.Rating, product.ReviewCount
+	var name, imageURL, gtin *string
+	var price *models.Money
+	sources := make(map[string]models.FieldSource)
+
+	for _, field := range jsonFields {
+		path, ok := r.fields[field]
+		if !ok {
+			continue
+		}
+		value, ok := path.Get(doc)
+		if !ok || value == nil {
+			continue
+		}
+
+		var err error
+		switch field {
+		case "name":
+			var s string
+			if s, err = jsonString(value); err == nil {
+				name = &s
+			}
+		case "currency":
+			currency, err = jsonString(value)
+			currency = strings.ToUpper(currency)
+		case "price":
+			var m models.Money
+			if m, err = jsonMoney(value, currency); err == nil {
+				price = &m
+			}
+		case "shipping_fee":
+			var fee models.Money
+			if fee, err = jsonMoney(value, currency); err == nil {
+				details.ShippingFee = &fee
+			}
+		case "points":
+			details.Points, err = jsonInt(value)
+		case "availability":
+			var s string
+			if s, err = jsonString(value); err == nil {
+				details.Availability = extractAvailability(strings.ReplaceAll(s, "_", ""))
+			}
+		case "in_stock":
+			inStock, isBool := value.(bool)
+			if !isBool {
+				err = fmt.Errorf("%v is not a boolean", value)
+			} else if inStock {
+				details.Availability = models.AvailabilityInStock
+			} else {
+				details.Availability = models.AvailabilityOutOfStock
+			}
+		case "stock":
+			var n int64
+			if n, err = jsonInt(value); err == nil {
+				details.Availability = models.AvailabilityInStock
+				if n <= 0 {
+					details.Availability = models.AvailabilityOutOfStock
+				}
+			}
+		case "rating":
+			var n json.Number
+			if n, err = jsonNumber(value); err == nil {
+				rating, err = n.Float64()
+			}
+		case "review_count":
+			var n int64
+			n, err = jsonInt(value)
+			reviewCount = int(n)
+		case "image_url":
+			var s string
+			if s, err = jsonString(value); err == nil {
+				imageURL = &s
+			}
+		case "gtin":
+			var code string
+			if code, err = jsonString(value); err == nil {
+				if models.NormalizeGTIN(code) == "" {
+					err = fmt.Errorf("invalid code %q", code)
+				} else {
+					gtin = &code
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s at %s: %w", field, path, err)
+		}
+
+		// The field names match the JSON names, except for the stock fields
+		provenanceField := field
+		if field == "in_stock" || field == "stock" {
+			provenanceField = "availability"
+		}
+		sources[provenanceField] = models.FieldSource{
+			Rule:       fmt.Sprintf("json %s %s", r.Name, path),
+			Confidence: models.ConfidenceHigh,
+		}
+	}
+
+	if name != nil {
+		product.Name = *name
+	}
+	if price != nil {
+		product.CurrentPrice = *price
+		if n := len(product.PriceHistory); n > 0 {
+			product.PriceHistory[n-1].Price = *price
+		}
+	}
+	if imageURL != nil {
+		product.ImageURL = *imageURL
+	}
+	if gtin != nil {
+		product.SetIdentifier(*gtin)
+	}
+	for field, source := range sources {
+		product.Provenance.Set(field, source)
+	}
+
+	product.SetOfferDetails(details)
+	if rating != product.Rating || reviewCount != product.ReviewCount {
+		// Replaces the point the page recorded in this scrape
+		if n := len(product.ReviewHistory); n > 0 {
+			product.ReviewHistory = product.ReviewHistory[:n-1]
+		}
+		product.UpdateReviews(rating, reviewCount)
+	}
+	return nil
+}
+
+// jsonString returns a JSON string, or a number as written
+func jsonString(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v), nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("%v is not a string", v)
+}
+
+// jsonNumber returns a JSON number, or a string holding one
+func jsonNumber(v any) (json.Number, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v, nil
+	case string:
+		if _, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return json.Number(strings.TrimSpace(v)), nil
+		}
+	}
+	return "", fmt.Errorf("%v is not a number", v)
+}
+
+// jsonInt returns a whole JSON number
+func jsonInt(v any) (int64, error) {
+	n, err := jsonNumber(v)
+	if err != nil {
+		return 0, err
+	}
+	return n.Int64()
+}
+
+// jsonMoney returns a number in major units, or a displayed price, as Money
+func jsonMoney(v any, currency string) (models.Money, error) {
+	if n, err := jsonNumber(v); err == nil {
+		return models.ParseDecimal(n.String(), currency)
+	}
+	s, err := jsonString(v)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return models.ParseMoney(s, currency)
+}
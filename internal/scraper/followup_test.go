@@ -0,0 +1,154 @@
+package scraper
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tedjuang/go-scrapy/internal/crawlpolicy"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// routeTransport answers requests by URL from a map of bodies, 404 for
+// others, and records the headers of each request by URL
+type routeTransport struct {
+	bodies  map[string]string
+	headers map[string]http.Header
+}
+
+func (t *routeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.headers[req.URL.String()] = req.Header.Clone()
+	body, ok := t.bodies[req.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Request: req}, nil
+	}
+	contentType := "text/html; charset=utf-8"
+	if strings.HasPrefix(body, "{") {
+		contentType = "application/json"
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestScrapeProductJSONRequests(t *testing.T) {
+	const pageURL = "https://item.rakuten.co.jp/shop/item-1/"
+	const stockURL = "https://item.rakuten.co.jp/api/stock?item=123&shop=shop"
+	page := `<html><body>
+		<h1 class="item-name">Nintendo Switch</h1>
+		<span class="price">価格を読み込み中</span>
+		<input type="hidden" name="item_id" value="123">
+		` + strings.Repeat("<p>商品説明</p>", 50) + `</body></html>`
+	transport := &routeTransport{
+		bodies: map[string]string{
+			pageURL:  page,
+			stockURL: `{"data": {"price": {"value": 37980}, "stock": 0, "points": 379}, "reviews": [{"avg": "4.5", "count": 12}]}`,
+		},
+		headers: make(map[string]http.Header),
+	}
+
+	s := NewRakutenScraperWithOptions(CollectorOptions{
+		Limits:    crawlpolicy.SiteLimits{Parallelism: 1},
+		Policy:    crawlpolicy.New(crawlpolicy.Config{IgnoreRobotsTxt: true}),
+		Transport: transport,
+		JSONRequests: []JSONRequest{
+			{
+				Name: "stock",
+				URL:  "https://item.rakuten.co.jp/api/stock?item={item}&shop=shop",
+				Vars: map[string]string{"item": "input[name='item_id']@value"},
+				Fields: map[string]string{
+					"price":        "$.data.price.value",
+					"stock":        "$.data.stock",
+					"points":       "$.data.points",
+					"rating":       "$.reviews[0].avg",
+					"review_count": "$.reviews[0].count",
+				},
+			},
+			// Skipped: the variable isn't on the page
+			{Name: "missing", URL: "https://item.rakuten.co.jp/api/x?v={v}", Vars: map[string]string{"v": "#none"}, Fields: map[string]string{"name": "$.name"}},
+		},
+	})
+
+	product, err := s.ScrapeProduct(pageURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.CurrentPrice != models.NewMoney(37980, "JPY") || product.PriceHistory[0].Price != product.CurrentPrice {
+		t.Errorf("Expected the price of the JSON response, got %s", product.CurrentPrice)
+	}
+	if product.Availability != models.AvailabilityOutOfStock || product.Points != 379 {
+		t.Errorf("Unexpected offer details: %+v", product.OfferDetails)
+	}
+	if product.Rating != 4.5 || product.ReviewCount != 12 || len(product.ReviewHistory) != 1 {
+		t.Errorf("Unexpected reviews: %v %d %v", product.Rating, product.ReviewCount, product.ReviewHistory)
+	}
+	if source := product.Provenance["availability"]; source.Rule != "json stock $.data.stock" {
+		t.Errorf("Unexpected provenance: %+v", source)
+	}
+
+	h := transport.headers[stockURL]
+	if h.Get("Referer") != pageURL || h.Get("X-Requested-With") != "XMLHttpRequest" || !strings.HasPrefix(h.Get("Accept"), "application/json") {
+		t.Errorf("Expected the request headers of an XHR, got %v", h)
+	}
+	if len(transport.headers) != 2 {
+		t.Errorf("Expected only the page and the stock request, got %d requests", len(transport.headers))
+	}
+}
+
+func TestCompileJSONRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request JSONRequest
+		valid   bool
+	}{
+		{"valid", JSONRequest{URL: "https://item.rakuten.co.jp/api?id={id}", Fields: map[string]string{"price": "$.price"}}, true},
+		{"no fields", JSONRequest{URL: "https://item.rakuten.co.jp/api"}, false},
+		{"unknown variable", JSONRequest{URL: "https://item.rakuten.co.jp/api?x={sku}", Fields: map[string]string{"price": "$.price"}}, false},
+		{"unsupported field", JSONRequest{URL: "https://item.rakuten.co.jp/api", Fields: map[string]string{"colour": "$.colour"}}, false},
+		{"invalid path", JSONRequest{URL: "https://item.rakuten.co.jp/api", Fields: map[string]string{"price": "price"}}, false},
+		{"relative URL", JSONRequest{URL: "/api", Fields: map[string]string{"price": "$.price"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileJSONRequest(tt.request); (err == nil) != tt.valid {
+				t.Errorf("Expected valid=%t, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestApplyJSONRequestFailureLeavesProduct(t *testing.T) {
+	r, err := compileJSONRequest(JSONRequest{
+		Name: "item",
+		URL:  "https://item.rakuten.co.jp/api?id={id}",
+		Fields: map[string]string{
+			"name":      "$.name",
+			"price":     "$.price",
+			"image_url": "$.image",
+			"gtin":      "$.jan",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	product := models.NewProduct("shop:item", "Page name", "https://item.rakuten.co.jp/shop/item/", "rakuten", models.NewMoney(1980, "JPY"))
+	product.ImageURL = "https://image.rakuten.co.jp/page.jpg"
+
+	// The invalid code comes after fields that parse
+	body := `{"name": "JSON name", "price": 2480, "image": "https://image.rakuten.co.jp/json.jpg", "jan": "123"}`
+	if err := r.apply(product, []byte(body)); err == nil {
+		t.Fatal("Expected the invalid code to fail")
+	}
+	if product.Name != "Page name" || product.CurrentPrice != models.NewMoney(1980, "JPY") ||
+		product.PriceHistory[0].Price != product.CurrentPrice || product.ImageURL != "https://image.rakuten.co.jp/page.jpg" {
+		t.Errorf("Expected the product unchanged, got %+v", product)
+	}
+	if len(product.Provenance) != 0 || product.GTIN != "" {
+		t.Errorf("Expected no fields recorded, got %v %q", product.Provenance, product.GTIN)
+	}
+}
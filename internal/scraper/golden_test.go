@@ -0,0 +1,198 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/config"
+	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/replay"
+)
+
+var (
+	record = flag.Bool("record", false, "Record fixtures from the live sites instead of replaying them")
+	update = flag.Bool("update", false, "Rewrite the golden files with the extracted products")
+)
+
+// Fixtures and golden files of the golden tests. The live pages are
+// recorded under fixtureDir with -record. Pages written by hand, shaped
+// after the live ones, are kept apart under syntheticDir; they can't catch
+// selectors that no longer match the live pages.
+const (
+	fixtureDir   = "testdata/fixtures"
+	goldenDir    = "testdata/golden"
+	syntheticDir = "testdata/synthetic" // with fixtures/ and golden/ alike
+)
+
+// goldenCase is one scrape whose result is compared with
+// testdata/golden/<website>/<name>.json
+type goldenCase struct {
+	name   string
+	scrape func(s Scraper) ([]*models.Product, error)
+}
+
+// productCase scrapes a single product page
+func productCase(name, url string) goldenCase {
+	return goldenCase{name, func(s Scraper) ([]*models.Product, error) {
+		p, err := s.ScrapeProduct(url)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.Product{p}, nil
+	}}
+}
+
+// goldenCases lists the golden scrapes of every registered website. Limits
+// stay within the recorded pages so no further page is requested.
+var goldenCases = map[string][]goldenCase{
+	"rakuten": {
+		productCase("product", "https://item.rakuten.co.jp/gamestore/switch-oled/"),
+		productCase("product_books", "https://books.rakuten.co.jp/rb/17163574/"),
+		{"search", func(s Scraper) ([]*models.Product, error) {
+			return s.ScrapeSearch("switch", 4)
+		}},
+		{"ranking", func(s Scraper) ([]*models.Product, error) {
+			return s.(RankingScraper).ScrapeRanking("daily", "100227", 4)
+		}},
+	},
+}
+
+// goldenFactory creates a factory whose scrapers replay the fixtures in
+// dir, or record them if record is set
+func goldenFactory(dir string, record bool) *ScraperFactory {
+	mode := replay.Replay
+	if record {
+		mode = replay.Record
+	}
+
+	cfg := &config.Config{}
+	cfg.Scraping.IgnoreRobotsTxt = !record
+	if !record {
+		// Replayed pages need no politeness delay
+		cfg.Scraping.Sites = map[string]config.SiteScraping{}
+		for website := range goldenCases {
+			cfg.Scraping.Sites[website] = config.SiteScraping{DelayMs: 1}
+		}
+	}
+	return newScraperFactory(cfg, replay.NewTransport(dir, mode, nil))
+}
+
+func TestGoldenScrapes(t *testing.T) {
+	if _, err := os.Stat(fixtureDir); !*record && errors.Is(err, fs.ErrNotExist) {
+		t.Skipf("No fixtures recorded in %s; record them with -record", fixtureDir)
+	}
+	runGolden(t, fixtureDir, goldenDir, *record)
+}
+
+func TestSyntheticGoldenScrapes(t *testing.T) {
+	if *record {
+		t.Skip("Synthetic fixtures are written by hand, not recorded")
+	}
+	runGolden(t, filepath.Join(syntheticDir, "fixtures"), filepath.Join(syntheticDir, "golden"), false)
+}
+
+// runGolden runs the golden cases of every registered website against the
+// fixtures in fixtures, comparing the products with the files in golden
+func runGolden(t *testing.T, fixtures, golden string, record bool) {
+	for website, s := range goldenFactory(fixtures, record).GetAllScrapers() {
+		cases, ok := goldenCases[website]
+		if !ok {
+			t.Errorf("No golden cases for registered scraper %s", website)
+			continue
+		}
+
+		for _, gc := range cases {
+			t.Run(website+"/"+gc.name, func(t *testing.T) {
+				products, err := gc.scrape(s)
+				if err != nil {
+					t.Fatalf("Scrape failed: %v", err)
+				}
+				checkPriceHistory(t, products)
+
+				got, err := json.MarshalIndent(normalizeGolden(products), "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, '\n')
+
+				path := filepath.Join(golden, website, gc.name+".json")
+				if *update || record {
+					if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(path, got, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				want, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("Missing golden file, run with -update: %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("Extracted products differ from %s (run with -update to accept):\n%s", path, got)
+				}
+			})
+		}
+	}
+}
+
+// checkPriceHistory asserts that a single scrape records exactly one price
+// point per product, carrying its current price and offer details. The
+// golden files would otherwise accept stray points from the extraction.
+func checkPriceHistory(t *testing.T, products []*models.Product) {
+	t.Helper()
+	for _, p := range products {
+		if len(p.PriceHistory) != 1 {
+			t.Errorf("Expected one price point for %s, got %+v", p.ID, p.PriceHistory)
+			continue
+		}
+		pp := p.PriceHistory[0]
+		if !pp.Price.IsPositive() || pp.Price != p.CurrentPrice {
+			t.Errorf("Expected the price point of %s at its current price %s, got %s", p.ID, p.CurrentPrice, pp.Price)
+		}
+		if !reflect.DeepEqual(pp.OfferDetails, p.OfferDetails) {
+			t.Errorf("Expected the price point of %s with its offer details %+v, got %+v", p.ID, p.OfferDetails, pp.OfferDetails)
+		}
+	}
+}
+
+// goldenProduct is a product as stored, plus the provenance of its fields
+type goldenProduct struct {
+	Product    *models.Product   `json:"product"`
+	Provenance models.Provenance `json:"provenance,omitempty"`
+}
+
+// normalizeGolden clears the scrape times so results are comparable
+func normalizeGolden(products []*models.Product) []goldenProduct {
+	golden := make([]goldenProduct, len(products))
+	for i, p := range products {
+		p.LastUpdated = time.Time{}
+		clearPriceTimes(p.PriceHistory)
+		for i := range p.ReviewHistory {
+			p.ReviewHistory[i].Timestamp = time.Time{}
+		}
+		for i := range p.RankHistory {
+			p.RankHistory[i].Timestamp = time.Time{}
+		}
+		for i := range p.Variants {
+			clearPriceTimes(p.Variants[i].PriceHistory)
+		}
+		golden[i] = goldenProduct{Product: p, Provenance: p.Provenance}
+	}
+	return golden
+}
+
+func clearPriceTimes(points []models.PricePoint) {
+	for i := range points {
+		points[i].Timestamp = time.Time{}
+	}
+}
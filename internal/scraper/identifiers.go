@@ -0,0 +1,22 @@
+package scraper
+
+import (
+	"regexp"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// identifierPattern matches labelled product codes such as "ISBN：978-4-08-882015-0"
+// or "JANコード 4902370548495" in free page text
+var identifierPattern = regexp.MustCompile(`(?i)(ISBN(?:-1[03])?|JAN(?:コード)?|EAN|GTIN)[\s:：]*([0-9][0-9\- ]{6,16}[0-9X])`)
+
+// extractIdentifiers finds the first valid JAN/EAN/ISBN code in text and
+// returns it normalized to GTIN-13, or an empty string if none is found
+func extractIdentifiers(text string) string {
+	for _, m := range identifierPattern.FindAllStringSubmatch(text, -1) {
+		if gtin := models.NormalizeGTIN(m[2]); gtin != "" {
+			return gtin
+		}
+	}
+	return ""
+}
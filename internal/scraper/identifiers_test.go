@@ -0,0 +1,26 @@
+package scraper
+
+import (
+	"testing"
+)
+
+func TestExtractIdentifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "Books spec table", text: "発売日：2023年11月02日 ISBN：978-0-306-40615-7 ページ数：200p", expected: "9780306406157"},
+		{name: "JAN code label", text: "JANコード 4006381333931", expected: "4006381333931"},
+		{name: "Skips invalid codes", text: "JAN: 1234567890123 EAN: 4006381333931", expected: "4006381333931"},
+		{name: "No code", text: "商品番号：abc-123", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractIdentifiers(tt.text); got != tt.expected {
+				t.Errorf("extractIdentifiers(%q) = %q, expected %q", tt.text, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+var (
+	// shippingFeePattern matches "送料 660円", "送料：¥550" or "送料別 880円"
+	shippingFeePattern = regexp.MustCompile(`送料(?:別)?[\s:：]*[¥￥]?\s*([0-9,，０-９]+)\s*円?`)
+
+	// pointsPattern matches an explicit point count such as "190ポイント" or "19pt"
+	pointsPattern = regexp.MustCompile(`([0-9,]+)\s*(?:ポイント|pt)(?:[^倍]|$)`)
+
+	// pointsMultiplierPattern matches a point rate such as "ポイント10倍" or "P10倍"
+	pointsMultiplierPattern = regexp.MustCompile(`(?:ポイント|P)\s*([0-9]+)\s*倍`)
+)
+
+// basePointRate is Rakuten's standard point rate: 1 point per 100 yen
+const basePointRate = 100
+
+// extractAvailability maps schema.org availability URLs and Japanese stock
+// labels to a models.Availability* value
+func extractAvailability(text string) string {
+	lower := strings.ToLower(text)
+	switch {
+	case strings.Contains(lower, "outofstock"), strings.Contains(lower, "soldout"), strings.Contains(lower, "sold out"),
+		strings.Contains(text, "売り切れ"), strings.Contains(text, "在庫切れ"), strings.Contains(text, "品切れ"),
+		strings.Contains(text, "在庫なし"), strings.Contains(text, "販売終了"):
+		return models.AvailabilityOutOfStock
+	case strings.Contains(lower, "preorder"), strings.Contains(text, "予約受付"), strings.Contains(text, "予約商品"):
+		return models.AvailabilityPreorder
+	case strings.Contains(lower, "instock"), strings.Contains(lower, "limitedavailability"),
+		strings.Contains(text, "在庫あり"), strings.Contains(text, "買い物かごに入れる"), strings.Contains(text, "かごに追加"):
+		return models.AvailabilityInStock
+	}
+	return models.AvailabilityUnknown
+}
+
+// extractShippingFee returns the shipping fee stated in text: zero for
+// "送料無料"/"送料込", the amount for "送料 660円", or nil if not stated
+func extractShippingFee(text string) *models.Money {
+	if strings.Contains(text, "送料無料") || strings.Contains(text, "送料込") {
+		free := models.NewMoney(0, "JPY")
+		return &free
+	}
+
+	m := shippingFeePattern.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+	fee, err := models.ParseMoney(m[1], "JPY")
+	if err != nil {
+		return nil
+	}
+	return &fee
+}
+
+// extractPoints returns the loyalty points stated in text. An explicit
+// count ("190ポイント") wins; otherwise a rate ("ポイント10倍") is applied to
+// the price at the base rate of 1 point per 100 yen.
+func extractPoints(text string, price models.Money) int64 {
+	if m := pointsPattern.FindStringSubmatch(text); m != nil {
+		if n, err := strconv.ParseInt(strings.ReplaceAll(m[1], ",", ""), 10, 64); err == nil {
+			return n
+		}
+	}
+
+	if m := pointsMultiplierPattern.FindStringSubmatch(text); m != nil && price.Currency == "JPY" {
+		if rate, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+			return price.Amount / basePointRate * rate
+		}
+	}
+	return 0
+}
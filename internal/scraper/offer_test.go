@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"testing"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestExtractAvailability(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{text: "http://schema.org/InStock", expected: models.AvailabilityInStock},
+		{text: "https://schema.org/OutOfStock", expected: models.AvailabilityOutOfStock},
+		{text: "http://schema.org/PreOrder", expected: models.AvailabilityPreorder},
+		{text: "申し訳ございません。売り切れました", expected: models.AvailabilityOutOfStock},
+		{text: "買い物かごに入れる", expected: models.AvailabilityInStock},
+		{text: "商品説明", expected: models.AvailabilityUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := extractAvailability(tt.text); got != tt.expected {
+			t.Errorf("extractAvailability(%q) = %q, expected %q", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestExtractShippingFee(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected *models.Money
+	}{
+		{text: "送料無料", expected: &models.Money{Amount: 0, Currency: "JPY"}},
+		{text: "税込 送料込", expected: &models.Money{Amount: 0, Currency: "JPY"}},
+		{text: "送料別 660円", expected: &models.Money{Amount: 660, Currency: "JPY"}},
+		{text: "送料：¥1,100", expected: &models.Money{Amount: 1100, Currency: "JPY"}},
+		{text: "1,980円", expected: nil},
+	}
+
+	for _, tt := range tests {
+		got := extractShippingFee(tt.text)
+		switch {
+		case tt.expected == nil && got != nil:
+			t.Errorf("extractShippingFee(%q) = %s, expected nil", tt.text, got)
+		case tt.expected != nil && (got == nil || *got != *tt.expected):
+			t.Errorf("extractShippingFee(%q) = %v, expected %s", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestExtractPoints(t *testing.T) {
+	price := models.NewMoney(1980, "JPY")
+
+	tests := []struct {
+		text     string
+		expected int64
+	}{
+		{text: "19ポイント(1倍)", expected: 19},
+		{text: "獲得ポイント 1,900pt", expected: 1900},
+		{text: "ポイント10倍", expected: 190},
+		{text: "P5倍", expected: 95},
+		{text: "送料無料", expected: 0},
+	}
+
+	for _, tt := range tests {
+		if got := extractPoints(tt.text, price); got != tt.expected {
+			t.Errorf("extractPoints(%q) = %d, expected %d", tt.text, got, tt.expected)
+		}
+	}
+}
@@ -1,200 +1,692 @@
 package scraper
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"regexp"
-	"strconv"
+	"net/http"
+	neturl "net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly"
+	"github.com/tedjuang/go-scrapy/internal/block"
+	"github.com/tedjuang/go-scrapy/internal/crawlpolicy"
+	"github.com/tedjuang/go-scrapy/internal/fetcher"
+	"github.com/tedjuang/go-scrapy/internal/headers"
 	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/session"
+	"github.com/tedjuang/go-scrapy/internal/snapshot"
 )
 
 // RakutenScraper implements scraper for Rakuten JP
 type RakutenScraper struct {
 	collector *colly.Collector
+	policy    *crawlpolicy.Policy
+	archive   *snapshot.Store
+	headers   *headers.Rotator
+	guard     block.Guard
+
+	jar         *session.Jar
+	warmUpEvery time.Duration
+	warmUpURLs  []string
+
+	jsonRequests []compiledJSONRequest
+}
+
+// defaultUserAgent is sent when no user agent is configured
+const defaultUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36"
+
+// DefaultRakutenLimits are the request limits used for Rakuten unless
+// configured otherwise
+var DefaultRakutenLimits = crawlpolicy.SiteLimits{
+	Parallelism: 2,
+	Delay:       2 * time.Second,
 }
 
-// NewRakutenScraper creates a new instance of RakutenScraper
+// rakutenDomain is the registrable domain of all Rakuten hosts
+const rakutenDomain = "rakuten.co.jp"
+
+// rakutenDetector recognizes the block, captcha and overload pages Rakuten
+// serves instead of a page. Real pages are far larger than MinBodySize.
+var rakutenDetector = block.Detector{
+	Statuses: block.DefaultStatuses,
+	Markers: []string{
+		"g-recaptcha",
+		"アクセスが集中しています",
+		"不正なアクセス",
+		"unusual traffic from your computer",
+	},
+	MinBodySize: 512,
+}
+
+// rakutenJSONRequests are the follow-up requests of Rakuten product pages.
+// Item pages carry price and stock in their HTML, so none are needed yet;
+// shops loading them by script are covered by sites.rakuten.jsonRequests.
+var rakutenJSONRequests []JSONRequest
+
+// rakutenWarmUpURLs are visited before crawling with a new session, so
+// that it carries the cookies of the top page like a returning visitor's
+var rakutenWarmUpURLs = []string{"https://www.rakuten.co.jp/"}
+
+// NewRakutenScraper creates a new instance of RakutenScraper with the default
+// limits, a crawl policy of its own and no cache
 func NewRakutenScraper() *RakutenScraper {
+	return NewRakutenScraperWithOptions(CollectorOptions{
+		Limits: DefaultRakutenLimits,
+		Policy: crawlpolicy.New(crawlpolicy.Config{UserAgent: defaultUserAgent}),
+	})
+}
+
+// NewRakutenScraperWithOptions creates a RakutenScraper whose collector uses
+// the given limits, crawl policy, cache, header profiles, fetcher, circuit
+// breaker and session
+func NewRakutenScraperWithOptions(opts CollectorOptions) *RakutenScraper {
+	if opts.Headers == nil {
+		opts.Headers = headers.Single(defaultUserAgent)
+	}
+	if opts.Fetcher == nil {
+		opts.Fetcher = fetcher.Colly{}
+	}
+	if opts.Breaker == nil {
+		opts.Breaker = block.NewBreaker(block.Config{})
+	}
+	detector := rakutenDetector
+	detector.Markers = append(slices.Clip(detector.Markers), opts.BlockMarkers...)
+
 	// Create a new collector with custom settings
 	c := colly.NewCollector(
 		// Update: Allow more domains including search domain and books domain
-		colly.AllowedDomains("www.rakuten.co.jp", "item.rakuten.co.jp", "search.rakuten.co.jp", "books.rakuten.co.jp"),
-		colly.UserAgent("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36"),
+		colly.AllowedDomains("www.rakuten.co.jp", "item.rakuten.co.jp", "search.rakuten.co.jp", "books.rakuten.co.jp",
+			"ranking.rakuten.co.jp"),
 		colly.MaxDepth(2),
 		// Allow redirects to other rakuten subdomains
 		colly.AllowURLRevisit(),
 	)
 
 	// Set rate limiting to be respectful
-	c.Limit(&colly.LimitRule{
-		DomainGlob:  "*rakuten.*",
-		Parallelism: 2,
-		Delay:       2 * time.Second,
-	})
+	c.Limit(opts.Limits.LimitRule("*rakuten.*"))
+	opts.Policy.Attach(c)
+	// Pages fetched in a session depend on its cookies, which the cache
+	// doesn't key on, so sessions bypass it
+	cache := opts.Cache
+	if opts.Jar != nil {
+		cache = nil
+	}
+	var transport http.RoundTripper
+	if cache != nil {
+		if opts.CacheTTL > 0 {
+			cache.SetTTL(rakutenDomain, opts.CacheTTL)
+		}
+		// Block pages served with status 200 must not be replayed
+		cache.SetFilter(rakutenDomain, func(status int, header http.Header, body []byte) bool {
+			_, blocked := detector.Detect(status, header.Get("Content-Type"), body)
+			return !blocked
+		})
+		transport = cache
+	} else if opts.Transport != nil {
+		transport = opts.Transport
+	}
+	transport = opts.Fetcher.Transport(transport)
+	if opts.Jar != nil {
+		// The session's jar replaces the collector's, seeing every redirect
+		c.DisableCookies()
+		transport = opts.Jar.Transport(transport)
+	}
+	if transport != nil {
+		c.WithTransport(transport)
+	}
+	warmUpURLs := rakutenWarmUpURLs
+	if len(opts.WarmUpURLs) > 0 {
+		warmUpURLs = opts.WarmUpURLs
+	}
+	if opts.Proxies != nil && len(opts.SiteProxies) > 0 {
+		if err := opts.Proxies.Assign(rakutenDomain, opts.SiteProxies); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	guard := block.Guard{Domain: rakutenDomain, Detector: detector, Breaker: opts.Breaker}
+	if opts.Proxies != nil {
+		guard.Proxies = opts.Proxies
+	}
+	if cache != nil {
+		guard.Cache = cache
+	}
 
 	return &RakutenScraper{
 		collector: c,
+		policy:    opts.Policy,
+		archive:   opts.Archive,
+		headers:   opts.Headers,
+		guard:     guard,
+
+		jar:         opts.Jar,
+		warmUpEvery: opts.WarmUpEvery,
+		warmUpURLs:  warmUpURLs,
+
+		jsonRequests: compileJSONRequests(append(slices.Clip(rakutenJSONRequests), opts.JSONRequests...)),
 	}
 }
 
-// ScrapeProduct scrapes a product from Rakuten JP based on its URL
-func (rs *RakutenScraper) ScrapeProduct(url string) (*models.Product, error) {
-	var product *models.Product
-	var err error
-
-	// Update: Use more selectors for product name to handle different page structures
-	rs.collector.OnHTML("h1.item-name, h1#item-name, h1[itemprop='name'], span.item-name, h1.booksTitle", func(e *colly.HTMLElement) {
-		productName := strings.TrimSpace(e.Text)
-		// Create a temporary product, we'll populate it with more data as we scrape
-		id := extractProductID(url)
-		product = models.NewProduct(id, productName, url, "rakuten", 0, "JPY")
-	})
+// newCollector returns a collector for a single crawl, sharing the scraper's
+// limits, crawl policy, circuit breaker and cookies but none of its
+// callbacks. Each crawl sends the headers of the next profile, warming up
+// the session with them first when it is due. The watch reports the first
+// block page of the crawl.
+func (rs *RakutenScraper) newCollector() (*colly.Collector, *block.Watch) {
+	profile := rs.headers.Next()
+	rs.warmUp(profile)
+
+	c := rs.collector.Clone()
+	rs.policy.Attach(c)
+	profile.Attach(c)
+	return c, rs.guard.Attach(c)
+}
+
+// warmUp visits the warm-up pages with profile if the session is new or
+// due for a refresh. A failed warm-up is logged and retried by the next
+// crawl; block pages count against the circuit like any other.
+func (rs *RakutenScraper) warmUp(profile headers.Profile) {
+	if rs.jar == nil || len(rs.warmUpURLs) == 0 {
+		return
+	}
+
+	_, err := rs.jar.WarmUp(rs.warmUpEvery, func() error {
+		c := rs.collector.Clone()
+		rs.policy.Attach(c)
+		profile.Attach(c)
+		watch := rs.guard.Attach(c)
 
-	// Update: More price selectors to handle different page structures
-	rs.collector.OnHTML(".price-box, #priceCalculationConfig, span[itemprop='price'], .price, .itemPrice, #priceAmount", func(e *colly.HTMLElement) {
-		priceText := e.ChildText(".price")
-		if priceText == "" {
-			priceText = e.ChildText(".price-value")
+		var visitErr error
+		c.OnError(func(r *colly.Response, err error) {
+			if visitErr == nil {
+				visitErr = fmt.Errorf("%s: %w", r.Request.URL, err)
+			}
+		})
+		for _, url := range rs.warmUpURLs {
+			if err := c.Visit(url); err != nil {
+				return fmt.Errorf("%s: %w", url, err)
+			}
+			c.Wait()
 		}
-		if priceText == "" {
-			priceText = e.Text
+		if blocked := watch.Err(); blocked != nil {
+			return blocked
 		}
+		return visitErr
+	})
+	if err != nil {
+		log.Printf("Warning: session warm-up failed: %v", err)
+	}
+}
 
-		// Check if there's a data attribute for price
-		if priceText == "" && e.Attr("data-price") != "" {
-			priceText = e.Attr("data-price")
+// ScrapeProduct scrapes a product from Rakuten JP based on its URL, merging
+// in the results of the JSON requests of the page. With an archive
+// configured, the fetched HTML is kept and linked to the new price point.
+func (rs *RakutenScraper) ScrapeProduct(url string) (*models.Product, error) {
+	// Refused URLs would otherwise just yield no product. Robots.txt rules
+	// are those of the user agent of the crawl's header profile.
+	c, watch := rs.newCollector()
+	if u, parseErr := neturl.Parse(url); parseErr == nil {
+		if err := rs.policy.CheckAgent(u, c.UserAgent); err != nil {
+			return nil, err
 		}
+	}
 
-		// Clean up the price text and extract just the numbers
-		price := extractPrice(priceText)
-		if price > 0 && product != nil {
-			product.UpdatePrice(price, "JPY")
-		}
-	})
+	var html []byte
+	if rs.archive != nil {
+		c.OnResponse(func(r *colly.Response) {
+			if html == nil {
+				html = r.Body
+			}
+		})
+	}
 
-	// Update: More image selectors
-	rs.collector.OnHTML("meta[property='og:image'], img.rakuten-main-product-image, img#imageURL", func(e *colly.HTMLElement) {
-		if product != nil {
-			imageURL := e.Attr("content")
-			if imageURL == "" {
-				imageURL = e.Attr("src")
+	var page *goquery.Selection
+	if len(rs.jsonRequests) > 0 {
+		c.OnHTML("html", func(e *colly.HTMLElement) {
+			if page == nil {
+				page = e.DOM
 			}
-			product.ImageURL = imageURL
+		})
+	}
+
+	product, err := extractProduct(c, url)
+	if err == nil && page != nil {
+		followUp(c, product, page, rs.jsonRequests)
+	}
+	if blocked := watch.Err(); blocked != nil {
+		return nil, blocked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if html != nil {
+		if id, err := rs.archive.Save(url, "rakuten", html); err != nil {
+			log.Printf("Warning: failed to archive %s: %v", url, err)
+		} else {
+			product.LinkSnapshot(id)
 		}
-	})
+	}
+	return product, nil
+}
+
+// ExtractProduct runs the product extraction over HTML fetched earlier from
+// url, without any network access
+func (rs *RakutenScraper) ExtractProduct(url string, html []byte) (*models.Product, error) {
+	return extractProduct(replayCollector(html), url)
+}
+
+// extractProduct visits url with c and extracts the product from the page
+func extractProduct(c *colly.Collector, url string) (*models.Product, error) {
+	var product *models.Product
 
-	// Update: More description selectors
-	rs.collector.OnHTML("#item-description, .item-description, .item-details, .item-info, [itemprop='description'], #itemCaption", func(e *colly.HTMLElement) {
-		if product != nil {
-			doc := goquery.NewDocumentFromNode(e.DOM.Nodes[0])
-			product.Description = strings.TrimSpace(doc.Text())
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		if product == nil {
+			product = parseProductPage(url, e.DOM)
 		}
 	})
 
-	rs.collector.OnError(func(r *colly.Response, err error) {
+	c.OnError(func(r *colly.Response, err error) {
 		log.Printf("Error scraping %s: %v", r.Request.URL, err)
 	})
 
 	// Start the scraping
-	rs.collector.Visit(url)
-	rs.collector.Wait()
+	c.Visit(url)
+	c.Wait()
 
 	if product == nil {
 		return nil, fmt.Errorf("failed to scrape product from URL: %s", url)
 	}
-
-	return product, err
+	return product, nil
 }
 
-// ScrapeSearch scrapes search results from Rakuten
-func (rs *RakutenScraper) ScrapeSearch(keyword string, maxProducts int) ([]*models.Product, error) {
-	var products []*models.Product
-	count := 0
+// Extraction rules of Rakuten product pages, in order of precedence:
+// schema.org markup first, then selectors of the item and books layouts,
+// then generic classes
+var (
+	rakutenNameRules = []fieldRule{
+		{"h1[itemprop='name']", "", models.ConfidenceHigh},
+		{"h1.item-name", "", models.ConfidenceMedium},
+		{"h1#item-name", "", models.ConfidenceMedium},
+		{"h1.booksTitle", "", models.ConfidenceMedium},
+		{"span.item-name", "", models.ConfidenceMedium},
+	}
 
-	searchURL := fmt.Sprintf("https://search.rakuten.co.jp/search/mall/%s/", keyword)
-	searchCollector := rs.collector.Clone()
+	rakutenPriceRules = []fieldRule{
+		{"[itemprop='price']", "content", models.ConfidenceHigh},
+		{"[itemprop='price']", "", models.ConfidenceHigh},
+		{"#priceCalculationConfig", "data-price", models.ConfidenceHigh},
+		{".price-box .price", "", models.ConfidenceMedium},
+		{".price-box .price-value", "", models.ConfidenceMedium},
+		{".itemPrice", "", models.ConfidenceMedium},
+		{"#priceAmount", "", models.ConfidenceMedium},
+		{".price", "", models.ConfidenceLow},
+		{".price-box", "", models.ConfidenceLow},
+		{".price-box, .itemPrice, #priceAmount", "data-price", models.ConfidenceLow},
+	}
 
-	// Update: Broader selector for search result items
-	searchCollector.OnHTML("div.searchresultitem, div.dui-card.searchresultitem, .g-category-item", func(e *colly.HTMLElement) {
-		if count >= maxProducts {
-			return
-		}
+	rakutenImageRules = []fieldRule{
+		{"meta[property='og:image']", "content", models.ConfidenceHigh},
+		{"img.rakuten-main-product-image", "src", models.ConfidenceMedium},
+		{"img#imageURL", "src", models.ConfidenceMedium},
+	}
 
-		// Update: More specific selectors for different page structures
-		name := e.ChildText(".title, .g-category-item-name")
+	rakutenDescriptionRules = []fieldRule{
+		{"[itemprop='description']", "", models.ConfidenceHigh},
+		{"#item-description", "", models.ConfidenceMedium},
+		{".item-description", "", models.ConfidenceMedium},
+		{"#itemCaption", "", models.ConfidenceMedium},
+		{".item-details", "", models.ConfidenceLow},
+		{".item-info", "", models.ConfidenceLow},
+	}
 
-		// Try different price selectors
-		priceText := e.ChildText(".important, .price, .g-category-item-price")
-		price := extractPrice(priceText)
+	// Books pages also list "ISBN：..." in the spec table, found in the page
+	// text as a last resort
+	rakutenIdentifierRules = []fieldRule{
+		{"[itemprop='gtin13']", "content", models.ConfidenceHigh},
+		{"[itemprop='gtin13']", "", models.ConfidenceHigh},
+		{"[itemprop='isbn']", "content", models.ConfidenceHigh},
+		{"[itemprop='isbn']", "", models.ConfidenceHigh},
+	}
 
-		// If price extraction failed, try data attribute
-		if price == 0 {
-			priceAttr := e.Attr("data-price")
-			if priceAttr != "" {
-				price = extractPrice(priceAttr)
-			}
+	rakutenAvailabilityRules = []fieldRule{
+		{"link[itemprop='availability']", "href", models.ConfidenceHigh},
+		{"meta[itemprop='availability']", "content", models.ConfidenceHigh},
+	}
+
+	// Present only on sold-out pages, whatever their text
+	rakutenSoldOutRule = fieldRule{".soldout, .sold-out, .item-soldout, #soldout, .status-soldout", "", models.ConfidenceMedium}
+
+	// Present on buyable pages; its label may still say sold out or preorder
+	rakutenCartRule = fieldRule{"#cartButton, .cart-button, button[name='cart'], input[name='cart'], .normal_reserve_cart", "", models.ConfidenceLow}
+
+	rakutenShippingRules = []fieldRule{
+		{"#postageInfo", "", models.ConfidenceMedium},
+		{".item-postage", "", models.ConfidenceMedium},
+		{".shipping-fee", "", models.ConfidenceMedium},
+		{".postage", "", models.ConfidenceMedium},
+		{".dui-tag--shipping", "", models.ConfidenceMedium},
+		{".shipping", "", models.ConfidenceLow},
+	}
+
+	rakutenPointsRules = []fieldRule{
+		{"#pointInfo", "", models.ConfidenceMedium},
+		{".item-point", "", models.ConfidenceMedium},
+		{".point-summary", "", models.ConfidenceMedium},
+		{".dui-tag--point", "", models.ConfidenceMedium},
+		{".point", "", models.ConfidenceLow},
+	}
+
+	rakutenRatingRules = []fieldRule{
+		{"[itemprop='ratingValue']", "content", models.ConfidenceHigh},
+		{"[itemprop='ratingValue']", "", models.ConfidenceHigh},
+		{".revRvwScore", "", models.ConfidenceMedium},
+		{".review-score", "", models.ConfidenceMedium},
+		{".review span.score", "", models.ConfidenceLow},
+	}
+
+	rakutenReviewCountRules = []fieldRule{
+		{"[itemprop='reviewCount']", "content", models.ConfidenceHigh},
+		{"[itemprop='reviewCount']", "", models.ConfidenceHigh},
+		{".revRvwCount", "", models.ConfidenceMedium},
+		{".review-count", "", models.ConfidenceMedium},
+		{".review span.legend", "", models.ConfidenceLow},
+	}
+
+	// Embedded SKU data is preferred over option selectors, which carry no
+	// combined SKU data, so only the first selector is used
+	rakutenVariantScriptRule  = fieldRule{"script", "", models.ConfidenceHigh}
+	rakutenVariantOptionsRule = fieldRule{"select.inventory_choice, select[name^='inventory'], .item-variant-selector select, select[data-variant]", "", models.ConfidenceMedium}
+)
+
+// parseProductPage extracts a product from a Rakuten product page, recording
+// the rule behind each field in its Provenance. It returns nil if the page
+// has no product name.
+func parseProductPage(url string, doc *goquery.Selection) *models.Product {
+	var provenance models.Provenance
+
+	name, source, ok := applyRules(doc, rakutenNameRules, nil)
+	if !ok {
+		return nil
+	}
+	provenance.Set("name", source)
+
+	price := models.NewMoney(0, "JPY")
+	if text, source, ok := applyRules(doc, rakutenPriceRules, func(v string) bool { return extractPrice(v).IsPositive() }); ok {
+		price = extractPrice(text)
+		provenance.Set("price", source)
+	}
+
+	product := models.NewProduct(extractProductID(url), name, url, "rakuten", price)
+	product.ShopCode = extractShopCode(url)
+
+	if image, source, ok := applyRules(doc, rakutenImageRules, nil); ok {
+		product.ImageURL = image
+		provenance.Set("image_url", source)
+	}
+
+	if description, source, ok := extractDescription(doc, rakutenDescriptionRules); ok {
+		product.Description = description.text
+		product.DescriptionHTML = description.html
+		provenance.Set("description", source)
+	}
+	if attributes := extractAttributes(doc); attributes != nil {
+		product.Attributes = attributes
+		provenance.Set("attributes", attributesSource)
+	}
+
+	validCode := func(v string) bool { return models.NormalizeGTIN(v) != "" }
+	if code, source, ok := applyRules(doc, rakutenIdentifierRules, validCode); ok {
+		product.SetIdentifier(code)
+		provenance.Set("gtin", source)
+	} else if gtin := extractIdentifiers(doc.Find("body").Text()); gtin != "" {
+		product.SetIdentifier(gtin)
+		provenance.Set("gtin", models.FieldSource{Rule: "body text (ISBN/JAN label)", Confidence: models.ConfidenceLow})
+	}
+
+	var details models.OfferDetails
+	known := func(v string) bool { return extractAvailability(v) != models.AvailabilityUnknown }
+	if text, source, ok := applyRules(doc, rakutenAvailabilityRules, known); ok {
+		details.Availability = extractAvailability(text)
+		provenance.Set("availability", source)
+	} else if doc.Find(rakutenSoldOutRule.selector).Length() > 0 {
+		details.Availability = models.AvailabilityOutOfStock
+		provenance.Set("availability", rakutenSoldOutRule.source())
+	} else if cart := doc.Find(rakutenCartRule.selector).First(); cart.Length() > 0 {
+		details.Availability = extractAvailability(cart.Text() + " " + cart.AttrOr("value", ""))
+		if details.Availability == models.AvailabilityUnknown {
+			details.Availability = models.AvailabilityInStock
 		}
+		provenance.Set("availability", rakutenCartRule.source())
+	}
+
+	if text, source, ok := applyRules(doc, rakutenShippingRules, func(v string) bool { return extractShippingFee(v) != nil }); ok {
+		details.ShippingFee = extractShippingFee(text)
+		provenance.Set("shipping_fee", source)
+	}
+
+	hasPoints := func(v string) bool { return extractPoints(v, price) > 0 }
+	if text, source, ok := applyRules(doc, rakutenPointsRules, hasPoints); ok {
+		details.Points = extractPoints(text, price)
+		provenance.Set("points", source)
+	}
+	product.SetOfferDetails(details)
 
-		// Update: More selectors for product URL
-		productURL := e.ChildAttr("a.title, a.g-category-item-name, a[data-url]", "href")
-		if productURL == "" {
-			productURL = e.ChildAttr("a", "href")
+	var rating float64
+	var reviewCount int
+	if text, source, ok := applyRules(doc, rakutenRatingRules, func(v string) bool { return extractRating(v) > 0 }); ok {
+		rating = extractRating(text)
+		provenance.Set("rating", source)
+	}
+	if text, source, ok := applyRules(doc, rakutenReviewCountRules, func(v string) bool { return extractReviewCount(v) > 0 }); ok {
+		reviewCount = extractReviewCount(text)
+		provenance.Set("review_count", source)
+	}
+	if rating > 0 || reviewCount > 0 {
+		product.UpdateReviews(rating, reviewCount)
+	}
+
+	var variants []models.Variant
+	doc.Find(rakutenVariantScriptRule.selector).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if strings.Contains(s.Text(), `"variantId"`) {
+			variants = parseVariantJSON(s.Text(), "JPY")
 		}
-		if productURL == "" {
-			// Try data attribute
-			productURL = e.Attr("data-url")
+		return len(variants) == 0
+	})
+	if len(variants) > 0 {
+		provenance.Set("variants", models.FieldSource{Rule: `script ("variantId" JSON)`, Confidence: rakutenVariantScriptRule.confidence})
+	} else if sel := doc.Find(rakutenVariantOptionsRule.selector).First(); sel.Length() > 0 {
+		if variants = parseVariantOptions(sel, product.CurrentPrice); len(variants) > 0 {
+			provenance.Set("variants", rakutenVariantOptionsRule.source())
 		}
+	}
+	if len(variants) > 0 {
+		product.SetVariants(variants)
+	}
+
+	product.Provenance = provenance
+	return product
+}
+
+// ScrapeSearch scrapes search results from Rakuten
+func (rs *RakutenScraper) ScrapeSearch(keyword string, maxProducts int) ([]*models.Product, error) {
+	searchURL := fmt.Sprintf("https://search.rakuten.co.jp/search/mall/%s/", keyword)
+	c, watch := rs.newCollector()
+	products := extractSearch(c, searchURL, maxProducts)
+	if err := watch.Err(); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// ExtractSearch runs the search card extraction over a search or listing
+// page fetched earlier from url, without any network access
+func (rs *RakutenScraper) ExtractSearch(url string, html []byte) ([]*models.Product, error) {
+	return extractSearch(replayCollector(html), url, 0), nil
+}
 
-		if productURL != "" && name != "" {
-			id := extractProductID(productURL)
-			product := models.NewProduct(id, name, productURL, "rakuten", price, "JPY")
+// extractSearch visits url with c and extracts up to maxProducts products
+// from its result cards, or all of them if maxProducts is 0
+func extractSearch(c *colly.Collector, url string, maxProducts int) []*models.Product {
+	var products []*models.Product
 
-			// Update: More selectors for image
-			product.ImageURL = e.ChildAttr(".image img, .g-category-item-image img", "src")
+	// Update: Broader selector for search result items
+	c.OnHTML(searchCardSelector, func(e *colly.HTMLElement) {
+		if maxProducts > 0 && len(products) >= maxProducts {
+			return
+		}
+
+		if product := parseSearchCard(e); product != nil {
 			products = append(products, product)
-			count++
 
 			// Debug info
-			log.Printf("Found product: %s, URL: %s, Price: %.2f", name, productURL, price)
+			log.Printf("Found product: %s, URL: %s, Price: %s", product.Name, product.URL, product.CurrentPrice)
 		}
 	})
 
-	searchCollector.OnError(func(r *colly.Response, err error) {
+	c.OnError(func(r *colly.Response, err error) {
 		log.Printf("Error scraping search %s: %v", r.Request.URL, err)
 	})
 
 	// Start the search scraping
-	searchCollector.Visit(searchURL)
-	searchCollector.Wait()
+	c.Visit(url)
+	c.Wait()
 
-	return products, nil
+	return products
 }
 
-// extractPrice extracts a numerical price from text containing Japanese price formatting
-func extractPrice(priceText string) float64 {
-	// Clean up the price text
-	priceText = strings.TrimSpace(priceText)
-	priceText = strings.ReplaceAll(priceText, "円", "")
-	priceText = strings.ReplaceAll(priceText, "¥", "")
-	priceText = strings.ReplaceAll(priceText, ",", "")
+// searchCardSelector matches a product card on search and genre listing pages
+const searchCardSelector = "div.searchresultitem, div.dui-card.searchresultitem, .g-category-item"
 
-	// Extract only the numbers using regex
-	re := regexp.MustCompile(`\d+`)
-	matches := re.FindString(priceText)
+// Extraction rules of search and genre listing cards, in order of precedence
+var (
+	rakutenCardNameRules = []fieldRule{
+		{"a.title", "", models.ConfidenceMedium},
+		{".g-category-item-name", "", models.ConfidenceMedium},
+		{".title", "", models.ConfidenceLow},
+	}
 
-	if matches == "" {
-		return 0
+	rakutenCardPriceRules = []fieldRule{
+		{".important", "", models.ConfidenceMedium},
+		{".g-category-item-price", "", models.ConfidenceMedium},
+		{".price", "", models.ConfidenceLow},
 	}
 
-	price, err := strconv.ParseFloat(matches, 64)
+	rakutenCardURLRules = []fieldRule{
+		{"a.title", "href", models.ConfidenceMedium},
+		{"a.g-category-item-name", "href", models.ConfidenceMedium},
+		{"a[data-url]", "href", models.ConfidenceMedium},
+		{"a", "href", models.ConfidenceLow},
+	}
+
+	rakutenCardImageRules = []fieldRule{
+		{".image img", "src", models.ConfidenceMedium},
+		{".g-category-item-image img", "src", models.ConfidenceMedium},
+	}
+
+	rakutenCardRatingRules = []fieldRule{
+		{".review .score", "", models.ConfidenceMedium},
+		{".review-score", "", models.ConfidenceMedium},
+	}
+
+	rakutenCardReviewCountRules = []fieldRule{
+		{".review .legend", "", models.ConfidenceMedium},
+		{".review-count", "", models.ConfidenceMedium},
+	}
+)
+
+// cardTextSource is the provenance of fields found in a card's free text
+var cardTextSource = models.FieldSource{Rule: "card text", Confidence: models.ConfidenceLow}
+
+// parseSearchCard builds a product from a search result card, or returns nil
+// if the card has no name or link
+func parseSearchCard(e *colly.HTMLElement) *models.Product {
+	var provenance models.Provenance
+	card := e.DOM
+
+	name, source, ok := applyRules(card, rakutenCardNameRules, nil)
+	if !ok {
+		return nil
+	}
+	provenance.Set("name", source)
+
+	productURL, source, ok := applyRules(card, rakutenCardURLRules, nil)
+	if !ok {
+		// The card itself may carry the link
+		if productURL = e.Attr("data-url"); productURL == "" {
+			return nil
+		}
+		source = models.FieldSource{Rule: "@data-url", Confidence: models.ConfidenceLow}
+	}
+	provenance.Set("url", source)
+
+	price := models.NewMoney(0, "JPY")
+	if text, source, ok := applyRules(card, rakutenCardPriceRules, func(v string) bool { return extractPrice(v).IsPositive() }); ok {
+		price = extractPrice(text)
+		provenance.Set("price", source)
+	} else if attr := e.Attr("data-price"); extractPrice(attr).IsPositive() {
+		price = extractPrice(attr)
+		provenance.Set("price", models.FieldSource{Rule: "@data-price", Confidence: models.ConfidenceLow})
+	}
+
+	product := models.NewProduct(extractProductID(productURL), name, productURL, "rakuten", price)
+	product.ShopCode = extractShopCode(productURL)
+
+	if image, source, ok := applyRules(card, rakutenCardImageRules, nil); ok {
+		product.ImageURL = image
+		provenance.Set("image_url", source)
+	}
+
+	// Search cards show shipping ("送料無料"), points ("19ポイント(1倍)") and sold-out labels
+	details := models.OfferDetails{
+		Availability: extractAvailability(e.Text),
+		ShippingFee:  extractShippingFee(e.Text),
+		Points:       extractPoints(e.Text, price),
+	}
+	if details.Availability != models.AvailabilityUnknown {
+		provenance.Set("availability", cardTextSource)
+	}
+	if details.ShippingFee != nil {
+		provenance.Set("shipping_fee", cardTextSource)
+	}
+	if details.Points > 0 {
+		provenance.Set("points", cardTextSource)
+	}
+	product.SetOfferDetails(details)
+
+	// Search cards show the rating as ".score" and the count as ".legend" ("(1,234件)") in their review block
+	var rating float64
+	var reviewCount int
+	if text, source, ok := applyRules(card, rakutenCardRatingRules, func(v string) bool { return extractRating(v) > 0 }); ok {
+		rating = extractRating(text)
+		provenance.Set("rating", source)
+	}
+	if text, source, ok := applyRules(card, rakutenCardReviewCountRules, func(v string) bool { return extractReviewCount(v) > 0 }); ok {
+		reviewCount = extractReviewCount(text)
+		provenance.Set("review_count", source)
+	}
+	if rating > 0 || reviewCount > 0 {
+		product.UpdateReviews(rating, reviewCount)
+	}
+
+	product.Provenance = provenance
+	return product
+}
+
+// extractPrice parses a displayed price such as "1,980円" or "¥1,980". Yen is
+// assumed when the text carries no currency symbol; a zero JPY amount is
+// returned if no price can be found.
+func extractPrice(priceText string) models.Money {
+	price, err := models.ParseMoney(priceText, "JPY")
 	if err != nil {
-		log.Printf("Failed to parse price from text '%s' (extracted '%s'): %v", priceText, matches, err)
-		return 0
+		if !errors.Is(err, models.ErrNoPrice) {
+			log.Printf("Failed to parse price from text '%s': %v", priceText, err)
+		}
+		return models.NewMoney(0, "JPY")
 	}
 
 	return price
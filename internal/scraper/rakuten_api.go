@@ -0,0 +1,354 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// DefaultRakutenAPIEndpoint is the Ichiba Item Search API
+const DefaultRakutenAPIEndpoint = "https://app.rakuten.co.jp/services/api/IchibaItem/Search/20220601"
+
+// DefaultRakutenAPIInterval is the pause between API requests; the API
+// allows about one request per second per application ID
+const DefaultRakutenAPIInterval = time.Second
+
+// Limits of the Item Search API
+const (
+	rakutenAPIMaxHits  = 30  // items per page
+	rakutenAPIMaxPages = 100 // pages per query
+)
+
+// rakutenAPITimeout bounds an API request
+const rakutenAPITimeout = 30 * time.Second
+
+// RakutenAPIConfig configures a RakutenAPIScraper
+type RakutenAPIConfig struct {
+	ApplicationID string        // issued by the Rakuten Web Service
+	AffiliateID   string        // optional; item URLs become affiliate links if set
+	Endpoint      string        // defaults to DefaultRakutenAPIEndpoint
+	Interval      time.Duration // between requests; defaults to DefaultRakutenAPIInterval
+
+	// Transport replaces the default HTTP transport, e.g. with the proxies
+	Transport http.RoundTripper
+}
+
+// RakutenAPIError is an error answered by the API, e.g. "wrong_parameter"
+// or "too_many_requests"
+type RakutenAPIError struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+// Error describes the API error
+func (e *RakutenAPIError) Error() string {
+	return fmt.Sprintf("rakuten API: %d %s: %s", e.Status, e.Code, e.Description)
+}
+
+// RakutenAPIScraper scrapes Rakuten Ichiba through the official Item Search
+// API, falling back to the HTML scraper it wraps whenever the API fails or
+// can't answer, e.g. for Rakuten Books pages. Rankings, snapshot extraction
+// and everything else the API doesn't cover are left to the HTML scraper.
+type RakutenAPIScraper struct {
+	*RakutenScraper
+
+	cfg    RakutenAPIConfig
+	client *http.Client
+
+	mutex sync.Mutex
+	next  time.Time // earliest next API request
+	sleep func(time.Duration)
+}
+
+// NewRakutenAPIScraper creates a scraper using the API with cfg, falling
+// back to html
+func NewRakutenAPIScraper(cfg RakutenAPIConfig, html *RakutenScraper) (*RakutenAPIScraper, error) {
+	if cfg.ApplicationID == "" {
+		return nil, errors.New("rakuten API: application ID is required")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultRakutenAPIEndpoint
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultRakutenAPIInterval
+	}
+	return &RakutenAPIScraper{
+		RakutenScraper: html,
+		cfg:            cfg,
+		client:         &http.Client{Timeout: rakutenAPITimeout, Transport: cfg.Transport},
+		sleep:          time.Sleep,
+	}, nil
+}
+
+// ScrapeProduct looks up an item page's product by its item code, scraping
+// the page if the API can't. The API has no variants, codes, cleaned
+// description or attributes, and no page is archived or followed up with
+// JSON requests; saving keeps those of the stored copy.
+func (as *RakutenAPIScraper) ScrapeProduct(url string) (*models.Product, error) {
+	shopCode := extractShopCode(url)
+	if shopCode == "" {
+		return as.RakutenScraper.ScrapeProduct(url)
+	}
+
+	params := neturl.Values{"itemCode": {shopCode + ":" + extractProductID(url)}}
+	resp, err := as.search(params)
+	if err == nil && len(resp.Items) == 0 {
+		err = errors.New("item not found")
+	}
+	if err != nil {
+		log.Printf("Rakuten API lookup of %s failed, scraping the page: %v", url, err)
+		return as.RakutenScraper.ScrapeProduct(url)
+	}
+	return resp.Items[0].product(), nil
+}
+
+// ScrapeSearch searches items by keyword through the API, up to maxProducts
+// or one page of 30 if 0, falling back to the search page
+func (as *RakutenAPIScraper) ScrapeSearch(keyword string, maxProducts int) ([]*models.Product, error) {
+	products, _, err := as.searchAll(neturl.Values{"keyword": {keyword}}, maxProducts)
+	if err != nil {
+		log.Printf("Rakuten API search for %q failed, scraping the search page: %v", keyword, err)
+		return as.RakutenScraper.ScrapeSearch(keyword, maxProducts)
+	}
+	return products, nil
+}
+
+// CrawlShop lists up to limit items of a shop through the API, or one page
+// of 30 if 0, falling back to crawling the shop's pages
+func (as *RakutenAPIScraper) CrawlShop(shopCode string, limit int) (*models.Shop, []*models.Product, error) {
+	if shopCode == "" {
+		return nil, nil, errors.New("shop code is required")
+	}
+
+	products, items, err := as.searchAll(neturl.Values{"shopCode": {shopCode}}, limit)
+	if err == nil && len(items) == 0 {
+		err = errors.New("shop not found")
+	}
+	if err != nil {
+		log.Printf("Rakuten API listing of shop %s failed, crawling the shop: %v", shopCode, err)
+		return as.RakutenScraper.CrawlShop(shopCode, limit)
+	}
+
+	shop := models.NewShop(shopCode, items[0].ShopName, stripQuery(items[0].ShopURL), "rakuten")
+	if shop.URL == "" {
+		shop.URL = fmt.Sprintf("https://www.rakuten.co.jp/%s/", shopCode)
+	}
+	shop.ProductCount = len(products)
+	return shop, products, nil
+}
+
+// searchAll pages through the results of a search for up to limit items,
+// or one page if limit is 0
+func (as *RakutenAPIScraper) searchAll(params neturl.Values, limit int) ([]*models.Product, []rakutenAPIItem, error) {
+	if limit <= 0 {
+		limit = rakutenAPIMaxHits
+	}
+
+	var items []rakutenAPIItem
+	for page := 1; page <= rakutenAPIMaxPages && len(items) < limit; page++ {
+		params.Set("hits", strconv.Itoa(min(limit-len(items), rakutenAPIMaxHits)))
+		params.Set("page", strconv.Itoa(page))
+		resp, err := as.search(params)
+		if err != nil {
+			// Items of earlier pages are kept rather than scraped again
+			if len(items) > 0 {
+				log.Printf("Rakuten API page %d failed, keeping %d items: %v", page, len(items), err)
+				break
+			}
+			return nil, nil, err
+		}
+		items = append(items, resp.Items...)
+		if page >= resp.PageCount || len(resp.Items) == 0 {
+			break
+		}
+	}
+	if len(items) > limit {
+		items = items[:limit]
+	}
+
+	products := make([]*models.Product, len(items))
+	for i, item := range items {
+		products[i] = item.product()
+	}
+	return products, items, nil
+}
+
+// search sends one Item Search request, spaced by the configured interval
+// and the crawl policy's request budget
+func (as *RakutenAPIScraper) search(params neturl.Values) (*rakutenAPIResponse, error) {
+	query := neturl.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("applicationId", as.cfg.ApplicationID)
+	if as.cfg.AffiliateID != "" {
+		query.Set("affiliateId", as.cfg.AffiliateID)
+	}
+	query.Set("format", "json")
+	query.Set("formatVersion", "2")
+
+	u, err := neturl.Parse(as.cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("rakuten API: invalid endpoint: %w", err)
+	}
+	u.RawQuery = query.Encode()
+
+	as.wait()
+	as.policy.Wait(u)
+	resp, err := as.client.Get(u.String())
+	if err != nil {
+		// The URL carries the application ID
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("rakuten API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, fmt.Errorf("rakuten API: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &RakutenAPIError{Status: resp.StatusCode}
+		var e struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &e) == nil {
+			apiErr.Code, apiErr.Description = e.Error, e.Description
+		}
+		return nil, apiErr
+	}
+
+	var result rakutenAPIResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("rakuten API: invalid response: %w", err)
+	}
+	return &result, nil
+}
+
+// wait blocks until the next API request may be sent
+func (as *RakutenAPIScraper) wait() {
+	as.mutex.Lock()
+	now := time.Now()
+	at := later(now, as.next)
+	as.next = at.Add(as.cfg.Interval)
+	as.mutex.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		as.sleep(d)
+	}
+}
+
+// later returns the later of two times
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// rakutenAPIResponse is an Item Search response in format version 2
+type rakutenAPIResponse struct {
+	Count     int              `json:"count"`
+	Page      int              `json:"page"`
+	PageCount int              `json:"pageCount"`
+	Items     []rakutenAPIItem `json:"Items"`
+}
+
+// rakutenAPIItem is an item of an Item Search response
+type rakutenAPIItem struct {
+	ItemName        string   `json:"itemName"`
+	ItemCode        string   `json:"itemCode"` // "<shop code>:<item ID>"
+	ItemPrice       int64    `json:"itemPrice"`
+	ItemCaption     string   `json:"itemCaption"`
+	ItemURL         string   `json:"itemUrl"`
+	AffiliateURL    string   `json:"affiliateUrl"`
+	ShopName        string   `json:"shopName"`
+	ShopCode        string   `json:"shopCode"`
+	ShopURL         string   `json:"shopUrl"`
+	MediumImageURLs []string `json:"mediumImageUrls"`
+	Availability    int      `json:"availability"` // 1 if it can be bought
+	PostageFlag     int      `json:"postageFlag"`  // 0 if shipping is included
+	PointRate       int64    `json:"pointRate"`    // point multiplier, 1 being the base rate
+	ReviewCount     int      `json:"reviewCount"`
+	ReviewAverage   float64  `json:"reviewAverage"`
+}
+
+// rakutenAPISource is the provenance of the fields of an API item
+func rakutenAPISource(field string) models.FieldSource {
+	return models.FieldSource{Rule: "rakuten API " + field, Confidence: models.ConfidenceHigh}
+}
+
+// product maps the item to a product, with the same ID as when scraped
+// from its page
+func (item rakutenAPIItem) product() *models.Product {
+	var provenance models.Provenance
+
+	// The item URL is an affiliate link if an affiliate ID is set, so the
+	// page URL is built from the item code instead
+	shopCode, id, _ := strings.Cut(item.ItemCode, ":")
+	url := fmt.Sprintf("https://item.rakuten.co.jp/%s/%s/", shopCode, id)
+
+	price := models.NewMoney(item.ItemPrice, "JPY")
+	product := models.NewProduct(id, item.ItemName, url, "rakuten", price)
+	product.ShopCode = item.ShopCode
+	product.Description = item.ItemCaption
+	provenance.Set("name", rakutenAPISource("itemName"))
+	provenance.Set("price", rakutenAPISource("itemPrice"))
+	provenance.Set("description", rakutenAPISource("itemCaption"))
+
+	if len(item.MediumImageURLs) > 0 {
+		// Thumbnails are resized with ?_ex=128x128; the original is larger
+		product.ImageURL = stripQuery(item.MediumImageURLs[0])
+		provenance.Set("image_url", rakutenAPISource("mediumImageUrls"))
+	}
+
+	details := models.OfferDetails{Availability: models.AvailabilityOutOfStock}
+	if item.Availability == 1 {
+		details.Availability = models.AvailabilityInStock
+	}
+	provenance.Set("availability", rakutenAPISource("availability"))
+	if item.PostageFlag == 0 {
+		free := models.NewMoney(0, "JPY")
+		details.ShippingFee = &free
+		provenance.Set("shipping_fee", rakutenAPISource("postageFlag"))
+	}
+	if item.PointRate > 0 {
+		details.Points = item.ItemPrice / basePointRate * item.PointRate
+		provenance.Set("points", rakutenAPISource("pointRate"))
+	}
+	product.SetOfferDetails(details)
+
+	if item.ReviewCount > 0 {
+		product.UpdateReviews(item.ReviewAverage, item.ReviewCount)
+		provenance.Set("rating", rakutenAPISource("reviewAverage"))
+		provenance.Set("review_count", rakutenAPISource("reviewCount"))
+	}
+
+	product.Provenance = provenance
+	return product
+}
+
+// stripQuery removes the query of a URL
+func stripQuery(raw string) string {
+	u, err := neturl.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.RawQuery = ""
+	return u.String()
+}
@@ -0,0 +1,211 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/config"
+	"github.com/tedjuang/go-scrapy/internal/crawlpolicy"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// rakutenAPIStandIn serves canned Item Search responses, answering
+// wrong_parameter without the application ID, and records the queries
+func rakutenAPIStandIn(t *testing.T, body string) (*httptest.Server, *[]url.Values) {
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.URL.Query().Get("applicationId") != "test-app" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "wrong_parameter", "error_description": "specify valid applicationId"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &queries
+}
+
+// newTestAPIScraper creates an API scraper of endpoint falling back to
+// pages served by transport, without pauses between requests
+func newTestAPIScraper(t *testing.T, endpoint, appID string, transport http.RoundTripper) *RakutenAPIScraper {
+	html := NewRakutenScraperWithOptions(CollectorOptions{
+		Limits:    crawlpolicy.SiteLimits{Parallelism: 1},
+		Policy:    crawlpolicy.New(crawlpolicy.Config{IgnoreRobotsTxt: true}),
+		Transport: transport,
+	})
+	s, err := NewRakutenAPIScraper(RakutenAPIConfig{ApplicationID: appID, Endpoint: endpoint}, html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.sleep = func(time.Duration) {}
+	return s
+}
+
+func TestRakutenAPISearch(t *testing.T) {
+	body, err := os.ReadFile("testdata/rakuten_api_search.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, queries := rakutenAPIStandIn(t, string(body))
+	s := newTestAPIScraper(t, server.URL, "test-app", nil)
+
+	products, err := s.ScrapeSearch("switch", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 2 {
+		t.Fatalf("Expected 2 products, got %d", len(products))
+	}
+
+	q := (*queries)[0]
+	if q.Get("keyword") != "switch" || q.Get("hits") != "10" || q.Get("page") != "1" || q.Get("formatVersion") != "2" {
+		t.Errorf("Unexpected query: %v", q)
+	}
+
+	p := products[0]
+	if p.ID != "switch-oled" || p.URL != "https://item.rakuten.co.jp/gamestore/switch-oled/" || p.ShopCode != "gamestore" {
+		t.Errorf("Unexpected product: %s %s %s", p.ID, p.URL, p.ShopCode)
+	}
+	if p.Name != "Nintendo Switch 有機ELモデル" || p.CurrentPrice != models.NewMoney(37980, "JPY") {
+		t.Errorf("Unexpected name or price: %s %s", p.Name, p.CurrentPrice)
+	}
+	if p.ImageURL != "https://thumbnail.image.rakuten.co.jp/@0_mall/gamestore/cabinet/oled.jpg" {
+		t.Errorf("Expected the full-size image, got %s", p.ImageURL)
+	}
+	if p.Availability != models.AvailabilityInStock || p.ShippingFee == nil || !p.ShippingFee.IsZero() || p.Points != 758 {
+		t.Errorf("Unexpected offer details: %+v", p.OfferDetails)
+	}
+	if p.Rating != 4.72 || p.ReviewCount != 1234 {
+		t.Errorf("Unexpected reviews: %v %d", p.Rating, p.ReviewCount)
+	}
+	if source := p.Provenance["points"]; source.Rule != "rakuten API pointRate" {
+		t.Errorf("Unexpected provenance: %+v", source)
+	}
+
+	lite := products[1]
+	if lite.Availability != models.AvailabilityOutOfStock || lite.ShippingFee != nil || lite.ImageURL != "" || lite.ReviewCount != 0 {
+		t.Errorf("Unexpected second product: %+v", lite)
+	}
+}
+
+func TestRakutenAPIProductAndShop(t *testing.T) {
+	body, err := os.ReadFile("testdata/rakuten_api_search.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, queries := rakutenAPIStandIn(t, string(body))
+	s := newTestAPIScraper(t, server.URL, "test-app", nil)
+
+	product, err := s.ScrapeProduct("https://item.rakuten.co.jp/gamestore/switch-oled/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.ID != "switch-oled" {
+		t.Errorf("Expected switch-oled, got %s", product.ID)
+	}
+	if code := (*queries)[0].Get("itemCode"); code != "gamestore:switch-oled" {
+		t.Errorf("Expected the item code gamestore:switch-oled, got %q", code)
+	}
+
+	shop, products, err := s.CrawlShop("gamestore", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shop.Name != "ゲームストア" || shop.URL != "https://www.rakuten.co.jp/gamestore/" || shop.ProductCount != 2 || len(products) != 2 {
+		t.Errorf("Unexpected shop: %+v", shop)
+	}
+	if q := (*queries)[1]; q.Get("shopCode") != "gamestore" || q.Get("hits") != "30" {
+		t.Errorf("Unexpected query: %v", q)
+	}
+}
+
+func TestRakutenAPIItemURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		itemURL string
+	}{
+		{"without affiliate ID", "https://item.rakuten.co.jp/gamestore/switch-oled/?rafcid=wsc_i_is_1"},
+		{"with affiliate ID", "https://hb.afl.rakuten.co.jp/hgc/g00q0724.2bqan2a8.g00q0724.2bqaod1e/?pc=https%3A%2F%2Fitem.rakuten.co.jp%2Fgamestore%2Fswitch-oled%2F"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := rakutenAPIItem{ItemCode: "gamestore:switch-oled", ItemURL: tt.itemURL, ShopCode: "gamestore", ItemPrice: 37980}
+			p := item.product()
+			if p.ID != "switch-oled" || p.URL != "https://item.rakuten.co.jp/gamestore/switch-oled/" {
+				t.Errorf("Expected the page of the item code, got %s %s", p.ID, p.URL)
+			}
+		})
+	}
+}
+
+func TestRakutenAPIFallsBackToPages(t *testing.T) {
+	html, err := os.ReadFile("testdata/rakuten_item.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, queries := rakutenAPIStandIn(t, "{}")
+	s := newTestAPIScraper(t, server.URL, "wrong-app", pageTransport{html: string(html)})
+
+	_, err = s.search(url.Values{"keyword": {"switch"}})
+	if apiErr, ok := err.(*RakutenAPIError); !ok || apiErr.Status != http.StatusBadRequest || apiErr.Code != "wrong_parameter" {
+		t.Errorf("Expected a wrong_parameter error, got %v", err)
+	}
+
+	product, err := s.ScrapeProduct(testItemURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Name != "Nintendo Switch 有機ELモデル" || product.Provenance["name"].Rule == "rakuten API itemName" {
+		t.Errorf("Expected the product of the page, got %s %+v", product.Name, product.Provenance["name"])
+	}
+	if len(*queries) != 2 {
+		t.Errorf("Expected 2 API requests, got %d", len(*queries))
+	}
+}
+
+func TestRakutenAPIInterval(t *testing.T) {
+	s := newTestAPIScraper(t, "", "test-app", nil)
+	var waits []time.Duration
+	s.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	s.wait()
+	s.wait()
+	if len(waits) != 1 || waits[0] <= DefaultRakutenAPIInterval/2 {
+		t.Errorf("Expected one pause of about %s, got %v", DefaultRakutenAPIInterval, waits)
+	}
+}
+
+func TestFactoryRakutenAPI(t *testing.T) {
+	cfg := &config.Config{}
+	if _, ok := NewScraperFactoryWithConfig(cfg).scrapers["rakuten"].(*RakutenScraper); !ok {
+		t.Error("Expected the HTML scraper without an application ID")
+	}
+
+	cfg.Scraping.RakutenAPI.ApplicationID = "test-app"
+	s, ok := NewScraperFactoryWithConfig(cfg).scrapers["rakuten"].(*RakutenAPIScraper)
+	if !ok {
+		t.Fatal("Expected the API scraper with an application ID")
+	}
+	if s.cfg.Endpoint != DefaultRakutenAPIEndpoint || s.cfg.Interval != DefaultRakutenAPIInterval {
+		t.Errorf("Expected the default endpoint and interval, got %+v", s.cfg)
+	}
+
+	// The API is called through the factory's transport
+	cfg.Scraping.IgnoreRobotsTxt = true
+	cfg.Scraping.Sites = map[string]config.SiteScraping{"rakuten": {DelayMs: 1}}
+	transport := &sessionTransport{}
+	s = newScraperFactory(cfg, transport).scrapers["rakuten"].(*RakutenAPIScraper)
+	s.sleep = func(time.Duration) {}
+	s.ScrapeSearch("switch", 1)
+	if len(transport.urls) == 0 || !strings.HasPrefix(transport.urls[0], DefaultRakutenAPIEndpoint+"?") {
+		t.Errorf("Expected the API request sent through the transport, got %v", transport.urls)
+	}
+}
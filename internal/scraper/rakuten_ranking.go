@@ -0,0 +1,176 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// maxRankingPages bounds the number of pages visited for one ranked list
+const maxRankingPages = 10
+
+// rankingPeriods are the ranking periods published on ranking.rakuten.co.jp
+var rankingPeriods = map[string]bool{
+	"realtime": true,
+	"daily":    true,
+	"weekly":   true,
+	"monthly":  true,
+}
+
+// ScrapeRanking scrapes the best-seller ranking of a genre for a period
+// (realtime, daily, weekly or monthly; default daily) from
+// ranking.rakuten.co.jp. An empty genreID selects the all-genre ranking.
+// Every returned product carries a RankSnapshot for the ranking.
+func (rs *RakutenScraper) ScrapeRanking(period, genreID string, limit int) ([]*models.Product, error) {
+	if period == "" {
+		period = "daily"
+	}
+	if !rankingPeriods[period] {
+		return nil, fmt.Errorf("invalid ranking period: %s", period)
+	}
+
+	source := models.RankingSource(period, genreID)
+	now := time.Now()
+	var products []*models.Product
+
+	rankingCollector, watch := rs.newCollector()
+
+	rankingCollector.OnHTML(".rnkRanking_top3box, .rnkRanking_after4box", func(e *colly.HTMLElement) {
+		if len(products) >= limit {
+			return
+		}
+
+		name := strings.TrimSpace(e.ChildText(".rnkRanking_itemName a"))
+		productURL := e.ChildAttr(".rnkRanking_itemName a", "href")
+		if name == "" || productURL == "" {
+			return
+		}
+
+		// Ranks are shown as "1位"; fall back to the position on the list
+		rank := parseRank(e.ChildText(".rnkRanking_dispRank"))
+		if rank == 0 {
+			rank = len(products) + 1
+		}
+
+		product := models.NewProduct(extractProductID(productURL), name, productURL, "rakuten",
+			extractPrice(e.ChildText(".rnkRanking_price")))
+		product.ShopCode = extractShopCode(productURL)
+		product.ImageURL = e.ChildAttr(".rnkRanking_image img", "src")
+		product.RecordRank(source, rank, now)
+		products = append(products, product)
+	})
+
+	rankingCollector.OnError(func(r *colly.Response, err error) {
+		log.Printf("Error scraping ranking %s: %v", r.Request.URL, err)
+	})
+
+	visitPages(rankingCollector, func(page int) string {
+		return rankingPageURL(period, genreID, page)
+	}, func() int { return len(products) }, limit)
+
+	if err := watch.Err(); err != nil {
+		return nil, err
+	}
+	if len(products) == 0 {
+		return nil, fmt.Errorf("no ranked products found for %s", source)
+	}
+	return products, nil
+}
+
+// ScrapeGenre scrapes a genre listing on search.rakuten.co.jp in the site's
+// default order. Every returned product carries a RankSnapshot with its
+// position in the listing.
+func (rs *RakutenScraper) ScrapeGenre(genreID string, limit int) ([]*models.Product, error) {
+	if genreID == "" {
+		return nil, errors.New("genre ID is required")
+	}
+
+	source := models.GenreSource(genreID)
+	now := time.Now()
+	var products []*models.Product
+
+	genreCollector, watch := rs.newCollector()
+
+	genreCollector.OnHTML(searchCardSelector, func(e *colly.HTMLElement) {
+		if len(products) >= limit {
+			return
+		}
+
+		if product := parseSearchCard(e); product != nil {
+			product.RecordRank(source, len(products)+1, now)
+			products = append(products, product)
+		}
+	})
+
+	genreCollector.OnError(func(r *colly.Response, err error) {
+		log.Printf("Error scraping genre %s: %v", r.Request.URL, err)
+	})
+
+	visitPages(genreCollector, func(page int) string {
+		return genrePageURL(genreID, page)
+	}, func() int { return len(products) }, limit)
+
+	if err := watch.Err(); err != nil {
+		return nil, err
+	}
+	if len(products) == 0 {
+		return nil, fmt.Errorf("no products found for %s", source)
+	}
+	return products, nil
+}
+
+// visitPages visits consecutive pages until limit items have been collected,
+// a page adds nothing, or maxRankingPages is reached
+func visitPages(c *colly.Collector, pageURL func(page int) string, collected func() int, limit int) {
+	for page := 1; page <= maxRankingPages && collected() < limit; page++ {
+		before := collected()
+		if err := c.Visit(pageURL(page)); err != nil {
+			log.Printf("Failed to visit %s: %v", pageURL(page), err)
+			return
+		}
+		c.Wait()
+
+		if collected() == before {
+			return
+		}
+	}
+}
+
+// rankingPageURL returns the URL of a ranking page, e.g.
+// https://ranking.rakuten.co.jp/daily/100227/p=2/
+func rankingPageURL(period, genreID string, page int) string {
+	u := "https://ranking.rakuten.co.jp/" + period + "/"
+	if genreID != "" {
+		u += genreID + "/"
+	}
+	if page > 1 {
+		u += fmt.Sprintf("p=%d/", page)
+	}
+	return u
+}
+
+// genrePageURL returns the URL of a genre listing page, e.g.
+// https://search.rakuten.co.jp/search/mall/-/100227/?p=2
+func genrePageURL(genreID string, page int) string {
+	u := "https://search.rakuten.co.jp/search/mall/-/" + genreID + "/"
+	if page > 1 {
+		u += fmt.Sprintf("?p=%d", page)
+	}
+	return u
+}
+
+// parseRank parses a displayed rank such as "1位" or "12"
+func parseRank(text string) int {
+	digits := strings.TrimFunc(strings.TrimSpace(text), func(r rune) bool { return r < '0' || r > '9' })
+	rank, err := strconv.Atoi(digits)
+	if err != nil || rank < 0 {
+		return 0
+	}
+	return rank
+}
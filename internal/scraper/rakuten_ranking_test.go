@@ -0,0 +1,41 @@
+package scraper
+
+import "testing"
+
+func TestParseRank(t *testing.T) {
+	tests := map[string]int{
+		"1位":    1,
+		" 12位 ": 12,
+		"80":    80,
+		"":      0,
+		"圏外":    0,
+	}
+
+	for text, expected := range tests {
+		if got := parseRank(text); got != expected {
+			t.Errorf("parseRank(%q) = %d; want %d", text, got, expected)
+		}
+	}
+}
+
+func TestRankingPageURLs(t *testing.T) {
+	tests := map[string]string{
+		rankingPageURL("daily", "", 1):        "https://ranking.rakuten.co.jp/daily/",
+		rankingPageURL("daily", "100227", 1):  "https://ranking.rakuten.co.jp/daily/100227/",
+		rankingPageURL("weekly", "100227", 2): "https://ranking.rakuten.co.jp/weekly/100227/p=2/",
+		genrePageURL("100227", 1):             "https://search.rakuten.co.jp/search/mall/-/100227/",
+		genrePageURL("100227", 3):             "https://search.rakuten.co.jp/search/mall/-/100227/?p=3",
+	}
+
+	for got, expected := range tests {
+		if got != expected {
+			t.Errorf("got %q; want %q", got, expected)
+		}
+	}
+}
+
+func TestScrapeRankingRejectsUnknownPeriod(t *testing.T) {
+	if _, err := NewRakutenScraper().ScrapeRanking("hourly", "", 10); err == nil {
+		t.Error("Expected error for unknown ranking period")
+	}
+}
@@ -0,0 +1,177 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/gocolly/colly"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// maxShopPages bounds the number of listing pages visited by CrawlShop
+const maxShopPages = 50
+
+// Link kinds found while crawling a shop
+const (
+	shopLinkOther = iota
+	shopLinkItem
+	shopLinkListing
+)
+
+// CrawlShop walks a Rakuten shop's top page and category listings
+// (item.rakuten.co.jp/<shop>/c/...) and returns the shop with up to limit of
+// its products. Products carry the name and price shown in the listings; use
+// ScrapeProduct for full details.
+func (rs *RakutenScraper) CrawlShop(shopCode string, limit int) (*models.Shop, []*models.Product, error) {
+	if shopCode == "" {
+		return nil, nil, errors.New("shop code is required")
+	}
+
+	shopURL := fmt.Sprintf("https://www.rakuten.co.jp/%s/", shopCode)
+	shop := models.NewShop(shopCode, "", shopURL, "rakuten")
+
+	var products []*models.Product
+	byID := make(map[string]*models.Product)
+	visited := make(map[string]bool)
+	pending := []string{shopURL}
+
+	shopCollector, watch := rs.newCollector()
+
+	// Shop name and rating from the shop top page
+	shopCollector.OnHTML(".shop-name, #shopName, title", func(e *colly.HTMLElement) {
+		if shop.Name == "" && e.Request.URL.String() == shopURL {
+			shop.Name = cleanShopName(e.Text)
+		}
+	})
+
+	shopCollector.OnHTML(".shop-review-score, .shopReview .score, [itemprop='ratingValue']", func(e *colly.HTMLElement) {
+		if shop.Rating == 0 {
+			shop.Rating = extractRating(firstNonEmpty(e.Attr("content"), e.Text))
+		}
+	})
+
+	shopCollector.OnHTML(".shop-review-count, .shopReview .count, [itemprop='reviewCount']", func(e *colly.HTMLElement) {
+		if shop.ReviewCount == 0 {
+			shop.ReviewCount = extractReviewCount(firstNonEmpty(e.Attr("content"), e.Text))
+		}
+	})
+
+	shopCollector.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		link := e.Request.AbsoluteURL(e.Attr("href"))
+		kind, normalized := classifyShopLink(link, shopCode)
+
+		switch kind {
+		case shopLinkListing:
+			if !visited[normalized] {
+				pending = append(pending, normalized)
+			}
+
+		case shopLinkItem:
+			name := strings.TrimSpace(firstNonEmpty(e.Attr("title"), e.Text, e.ChildAttr("img", "alt")))
+			id := extractProductID(normalized)
+
+			// Image links usually come before the text link for the same item
+			if p, ok := byID[id]; ok {
+				if p.Name == "" {
+					p.Name = name
+				}
+				return
+			}
+			if len(products) >= limit {
+				return
+			}
+
+			container := e.DOM.Closest("li, tr, .item, .category_item, .risFil")
+			price := extractPrice(container.Find(".price, .category_itemprice, .itemPrice").First().Text())
+
+			product := models.NewProduct(id, name, normalized, "rakuten", price)
+			product.ShopCode = shopCode
+			byID[id] = product
+			products = append(products, product)
+		}
+	})
+
+	shopCollector.OnError(func(r *colly.Response, err error) {
+		log.Printf("Error crawling shop %s at %s: %v", shopCode, r.Request.URL, err)
+	})
+
+	pages := 0
+	for len(pending) > 0 && len(products) < limit && pages < maxShopPages && watch.Err() == nil {
+		next := pending[0]
+		pending = pending[1:]
+		if visited[next] {
+			continue
+		}
+		visited[next] = true
+		pages++
+
+		if err := shopCollector.Visit(next); err != nil {
+			log.Printf("Failed to visit %s: %v", next, err)
+		}
+		shopCollector.Wait()
+	}
+
+	if err := watch.Err(); err != nil {
+		return nil, nil, err
+	}
+	if pages == 1 && len(products) == 0 && shop.Name == "" {
+		return nil, nil, fmt.Errorf("failed to crawl shop: %s", shopCode)
+	}
+
+	shop.ProductCount = len(products)
+	return shop, products, nil
+}
+
+// classifyShopLink reports whether link is an item page or a category listing
+// of the given shop, and returns it normalized (no fragment, trailing slash
+// on item pages)
+func classifyShopLink(link, shopCode string) (int, string) {
+	u, err := url.Parse(link)
+	if err != nil || u.Host != "item.rakuten.co.jp" {
+		return shopLinkOther, ""
+	}
+	u.Fragment = ""
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || segments[0] != shopCode {
+		return shopLinkOther, ""
+	}
+
+	// Category listings: /<shop>/c/<category>/ with optional ?p=<page>
+	if segments[1] == "c" {
+		return shopLinkListing, u.String()
+	}
+
+	if len(segments) == 2 {
+		u.RawQuery = ""
+		u.Path = "/" + segments[0] + "/" + segments[1] + "/"
+		return shopLinkItem, u.String()
+	}
+	return shopLinkOther, ""
+}
+
+// extractShopCode returns the shop code from an item.rakuten.co.jp URL
+func extractShopCode(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host != "item.rakuten.co.jp" {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 {
+		return ""
+	}
+	return segments[0]
+}
+
+// cleanShopName strips the marketplace decoration from a shop page title,
+// e.g. "【楽天市場】楽天ブックス：本・雑誌..." becomes "楽天ブックス"
+func cleanShopName(title string) string {
+	title = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(title), "【楽天市場】"))
+	if i := strings.IndexAny(title, "：|"); i > 0 {
+		title = title[:i]
+	}
+	return strings.TrimSpace(title)
+}
@@ -0,0 +1,47 @@
+package scraper
+
+import "testing"
+
+func TestClassifyShopLink(t *testing.T) {
+	tests := []struct {
+		link     string
+		kind     int
+		expected string
+	}{
+		{"https://item.rakuten.co.jp/book/14583459/", shopLinkItem, "https://item.rakuten.co.jp/book/14583459/"},
+		{"https://item.rakuten.co.jp/book/14583459?scid=af#reviews", shopLinkItem, "https://item.rakuten.co.jp/book/14583459/"},
+		{"https://item.rakuten.co.jp/book/c/0000000123/", shopLinkListing, "https://item.rakuten.co.jp/book/c/0000000123/"},
+		{"https://item.rakuten.co.jp/book/c/0000000123/?p=2", shopLinkListing, "https://item.rakuten.co.jp/book/c/0000000123/?p=2"},
+		{"https://item.rakuten.co.jp/othershop/123/", shopLinkOther, ""},
+		{"https://www.rakuten.co.jp/book/info.html", shopLinkOther, ""},
+		{"https://item.rakuten.co.jp/book/", shopLinkOther, ""},
+	}
+
+	for _, tt := range tests {
+		kind, normalized := classifyShopLink(tt.link, "book")
+		if kind != tt.kind || normalized != tt.expected {
+			t.Errorf("classifyShopLink(%q) = %d, %q; want %d, %q", tt.link, kind, normalized, tt.kind, tt.expected)
+		}
+	}
+}
+
+func TestExtractShopCode(t *testing.T) {
+	tests := map[string]string{
+		"https://item.rakuten.co.jp/book/14583459/": "book",
+		"https://item.rakuten.co.jp/book/":          "",
+		"https://www.rakuten.co.jp/book/":           "",
+		"https://books.rakuten.co.jp/rb/14583459/":  "",
+	}
+
+	for link, expected := range tests {
+		if got := extractShopCode(link); got != expected {
+			t.Errorf("extractShopCode(%q) = %q; want %q", link, got, expected)
+		}
+	}
+}
+
+func TestCleanShopName(t *testing.T) {
+	if got := cleanShopName(" 【楽天市場】楽天ブックス：本・雑誌・DVD "); got != "楽天ブックス" {
+		t.Errorf("cleanShopName = %q; want 楽天ブックス", got)
+	}
+}
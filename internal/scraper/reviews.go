@@ -0,0 +1,43 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ratingPattern matches an average rating such as "4.52" or "★4.5": a
+	// whole number token with one integer digit, so that digits of prices
+	// or counts such as "1,234" or "12.5" don't match
+	ratingPattern = regexp.MustCompile(`(?:^|[^0-9.,])([0-9](?:\.[0-9]+)?)(?:[^0-9.,]|$)`)
+
+	// reviewCountPattern matches a review count such as "(1,234件)" or "レビュー 56件"
+	reviewCountPattern = regexp.MustCompile(`([0-9][0-9,]*)\s*件`)
+)
+
+// extractRating parses the first average review rating in the range 0-5,
+// returning 0 if none is found
+func extractRating(text string) float64 {
+	for _, m := range ratingPattern.FindAllStringSubmatch(strings.TrimSpace(text), -1) {
+		if rating, err := strconv.ParseFloat(m[1], 64); err == nil && rating <= 5 {
+			return rating
+		}
+	}
+	return 0
+}
+
+// extractReviewCount parses a review count such as "(1,234件)". A bare number
+// is accepted for structured data like itemprop="reviewCount".
+func extractReviewCount(text string) int {
+	text = strings.TrimSpace(text)
+	raw := text
+	if m := reviewCountPattern.FindStringSubmatch(text); m != nil {
+		raw = m[1]
+	}
+	count, err := strconv.Atoi(strings.Trim(strings.ReplaceAll(raw, ",", ""), "()（） "))
+	if err != nil {
+		return 0
+	}
+	return count
+}
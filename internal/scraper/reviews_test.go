@@ -0,0 +1,70 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExtractRating(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected float64
+	}{
+		{text: "4.52", expected: 4.52},
+		{text: " ★4.5 ", expected: 4.5},
+		{text: "5", expected: 5},
+		{text: "9.8", expected: 0},
+		{text: "4.65(2,318件)", expected: 4.65},
+		{text: "(1,234件)", expected: 0},
+		{text: "12.5% OFF 評価 4.2", expected: 4.2},
+		{text: "送料 660円", expected: 0},
+		{text: "", expected: 0},
+	}
+
+	for _, tt := range tests {
+		if got := extractRating(tt.text); got != tt.expected {
+			t.Errorf("extractRating(%q) = %v, expected %v", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestExtractReviewCount(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{text: "(1,234件)", expected: 1234},
+		{text: "レビュー 56件", expected: 56},
+		{text: "789", expected: 789},
+		{text: "レビューなし", expected: 0},
+	}
+
+	for _, tt := range tests {
+		if got := extractReviewCount(tt.text); got != tt.expected {
+			t.Errorf("extractReviewCount(%q) = %d, expected %d", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestReviewSelectorsScopedToReviewBlock(t *testing.T) {
+	page := `<html><body>
+<h1 class="item-name">Item</h1>
+<span itemprop="price" content="1980">1,980円</span>
+<div class="shop-ranking"><span class="score">3</span><span class="legend">(5件)</span></div>
+</body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parseProductPage("https://item.rakuten.co.jp/shop/item/", doc.Selection)
+	if p == nil || p.Rating != 0 || p.ReviewCount != 0 {
+		t.Errorf("Expected no reviews from a widget outside the review block, got %+v", p)
+	}
+
+	doc, _ = goquery.NewDocumentFromReader(strings.NewReader(strings.Replace(page, "shop-ranking", "review", 1)))
+	if p := parseProductPage("https://item.rakuten.co.jp/shop/item/", doc.Selection); p == nil || p.Rating != 3 || p.ReviewCount != 5 {
+		t.Errorf("Expected reviews from the review block, got %+v", p)
+	}
+}
@@ -1,7 +1,25 @@
 package scraper
 
 import (
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/block"
+	"github.com/tedjuang/go-scrapy/internal/config"
+	"github.com/tedjuang/go-scrapy/internal/crawlpolicy"
+	"github.com/tedjuang/go-scrapy/internal/fetcher"
+	"github.com/tedjuang/go-scrapy/internal/headers"
+	"github.com/tedjuang/go-scrapy/internal/httpcache"
+	"github.com/tedjuang/go-scrapy/internal/media"
 	"github.com/tedjuang/go-scrapy/internal/models"
+	"github.com/tedjuang/go-scrapy/internal/proxy"
+	"github.com/tedjuang/go-scrapy/internal/session"
+	"github.com/tedjuang/go-scrapy/internal/sitemap"
+	"github.com/tedjuang/go-scrapy/internal/snapshot"
 )
 
 // Scraper defines the interface for product scrapers
@@ -13,23 +31,522 @@ type Scraper interface {
 	ScrapeSearch(keyword string, maxProducts int) ([]*models.Product, error)
 }
 
-// ScraperFactory creates a new scraper for a given website
+// Extractor is implemented by scrapers that can extract products from HTML
+// fetched earlier, e.g. an archived snapshot
+type Extractor interface {
+	// ExtractProduct extracts the product of the page at url from its HTML
+	ExtractProduct(url string, html []byte) (*models.Product, error)
+	// ExtractSearch extracts the products listed on the search or listing
+	// page at url from its HTML
+	ExtractSearch(url string, html []byte) ([]*models.Product, error)
+}
+
+// ShopCrawler is implemented by scrapers that can enumerate a shop's catalog
+type ShopCrawler interface {
+	// CrawlShop walks a shop's listing pages and returns the shop with up to
+	// limit of its products
+	CrawlShop(shopCode string, limit int) (*models.Shop, []*models.Product, error)
+}
+
+// RankingScraper is implemented by scrapers that can read ranked product
+// lists. Returned products carry a models.RankSnapshot for the list.
+type RankingScraper interface {
+	// ScrapeRanking scrapes a best-seller ranking of a genre for a period
+	ScrapeRanking(period, genreID string, limit int) ([]*models.Product, error)
+	// ScrapeGenre scrapes a genre listing in the site's default order
+	ScrapeGenre(genreID string, limit int) ([]*models.Product, error)
+}
+
+// CollectorOptions configure the collector of a site scraper
+type CollectorOptions struct {
+	Limits   crawlpolicy.SiteLimits
+	Policy   *crawlpolicy.Policy
+	Cache    *httpcache.Cache // nil disables caching
+	CacheTTL time.Duration    // freshness of the site's cached pages; 0 uses the cache default
+	Archive  *snapshot.Store  // nil disables HTML snapshots
+	Headers  *headers.Rotator // header profiles of the site; nil sends the default user agent
+	Fetcher  fetcher.Fetcher  // how pages are fetched; nil fetches them with colly
+
+	// Breaker pauses the site after repeated block pages (nil gives the
+	// scraper a breaker of its own); BlockMarkers add to the text by which
+	// the scraper recognizes them
+	Breaker      *block.Breaker
+	BlockMarkers []string
+
+	// Jar keeps the site's cookies across crawls and runs (nil keeps them in
+	// memory). Its warm-up steps run for a new session and, unless
+	// WarmUpEvery is 0, again once it is older than that; WarmUpURLs replace
+	// the scraper's own steps.
+	Jar         *session.Jar
+	WarmUpEvery time.Duration
+	WarmUpURLs  []string
+
+	// JSONRequests are sent after each product page, following the
+	// scraper's own
+	JSONRequests []JSONRequest
+
+	// Proxies is the pool requests go through (nil fetches directly);
+	// SiteProxies restricts the site to some of its proxies
+	Proxies     *proxy.Pool
+	SiteProxies []string
+
+	// Transport replaces the default HTTP transport, e.g. to replay
+	// recorded fixtures in tests; the cache fetches through it as well
+	Transport http.RoundTripper
+}
+
+// SiteConfig holds per-site settings registered alongside a scraper
+type SiteConfig struct {
+	Sitemap SitemapConfig
+}
+
+// SitemapConfig describes where a site's sitemaps are and which of the
+// listed pages are products. A site without sitemaps leaves it empty.
+type SitemapConfig struct {
+	RobotsURLs     []string       // robots.txt files whose Sitemap: lines are followed
+	SitemapURLs    []string       // sitemaps read in addition to those in robots.txt
+	ProductPattern *regexp.Regexp // product page URLs
+}
+
+// Enabled reports whether sitemap discovery is configured
+func (c SitemapConfig) Enabled() bool {
+	return len(c.RobotsURLs) > 0 || len(c.SitemapURLs) > 0
+}
+
+// IsProduct reports whether a sitemap URL is a product page
+func (c SitemapConfig) IsProduct(loc string) bool {
+	return c.ProductPattern == nil || c.ProductPattern.MatchString(loc)
+}
+
+// rakutenProductPattern matches item pages and Rakuten Books product pages
+var rakutenProductPattern = regexp.MustCompile(`^https://(item\.rakuten\.co\.jp/[^/?#]+/[^/?#]+|books\.rakuten\.co\.jp/rb/[0-9]+)/?$`)
+
+// ScraperFactory creates a new scraper for a given website. The subsystems
+// its scrapers share are built from the config; one that can't be set up is
+// logged and left out, or replaced by its default, rather than failing the
+// scrapers.
 type ScraperFactory struct {
 	scrapers map[string]Scraper
+	sites    map[string]SiteConfig
+	policy   *crawlpolicy.Policy
+	cache    *httpcache.Cache
+	archive  *snapshot.Store
+	images   *media.Downloader
+	proxies  *proxy.Pool
+	breaker  *block.Breaker
+
+	transport http.RoundTripper
+	userAgent string
+	stop      func() // stops the proxy health checks
 }
 
 // NewScraperFactory creates a new scraper factory with all supported scrapers
+// and the default crawl settings
 func NewScraperFactory() *ScraperFactory {
+	return NewScraperFactoryWithConfig(nil)
+}
+
+// NewScraperFactoryWithConfig creates a scraper factory whose scrapers share
+// one crawl policy built from cfg.Scraping; a nil cfg uses the defaults
+func NewScraperFactoryWithConfig(cfg *config.Config) *ScraperFactory {
+	return newScraperFactory(cfg, nil)
+}
+
+// newScraperFactory creates the factory with every scraper fetching through
+// transport, or through the configured proxies or the default transport if
+// nil
+func newScraperFactory(cfg *config.Config, transport http.RoundTripper) *ScraperFactory {
+	proxies, stop := newProxyPool(cfg)
+	if transport == nil && proxies != nil {
+		transport = proxies
+	}
+
+	policyConfig := crawlpolicy.Config{UserAgent: defaultUserAgent, Transport: transport}
+	if cfg != nil {
+		if cfg.Scraping.UserAgent != "" {
+			policyConfig.UserAgent = cfg.Scraping.UserAgent
+		}
+		policyConfig.IgnoreRobotsTxt = cfg.Scraping.IgnoreRobotsTxt
+		policyConfig.RequestsPerMinute = cfg.Scraping.RequestsPerMinute
+		policyConfig.Retries = cfg.Scraping.Retries
+	}
+
 	factory := &ScraperFactory{
 		scrapers: make(map[string]Scraper),
+		sites:    make(map[string]SiteConfig),
+		policy:   crawlpolicy.New(policyConfig),
+		cache:    newCache(cfg, transport),
+		archive:  newArchive(cfg),
+		proxies:  proxies,
+		breaker:  newBreaker(cfg),
+
+		transport: transport,
+		userAgent: policyConfig.UserAgent,
+		stop:      stop,
 	}
+	factory.images = newImages(cfg, transport, factory.policy)
 
 	// Register all supported scrapers
-	factory.scrapers["rakuten"] = NewRakutenScraper()
+	factory.Register("rakuten", newRakuten(cfg, transport, NewRakutenScraperWithOptions(factory.collectorOptions(cfg, "rakuten", DefaultRakutenLimits))), SiteConfig{
+		Sitemap: SitemapConfig{
+			RobotsURLs:     []string{"https://www.rakuten.co.jp/robots.txt", "https://books.rakuten.co.jp/robots.txt"},
+			ProductPattern: rakutenProductPattern,
+		},
+	})
 
 	return factory
 }
 
+// newRakuten wraps the Rakuten scraper in one using the Ichiba API, through
+// transport, if an application ID is configured in cfg
+func newRakuten(cfg *config.Config, transport http.RoundTripper, html *RakutenScraper) Scraper {
+	if cfg == nil || cfg.Scraping.RakutenAPI.ApplicationID == "" {
+		return html
+	}
+
+	api := cfg.Scraping.RakutenAPI
+	s, err := NewRakutenAPIScraper(RakutenAPIConfig{
+		ApplicationID: api.ApplicationID,
+		AffiliateID:   api.AffiliateID,
+		Endpoint:      api.Endpoint,
+		Interval:      time.Duration(api.IntervalMs) * time.Millisecond,
+		Transport:     transport,
+	}, html)
+	if err != nil {
+		log.Printf("Warning: Rakuten API disabled: %v", err)
+		return html
+	}
+	return s
+}
+
+// newCache creates the HTTP cache if enabled in cfg, or returns nil
+func newCache(cfg *config.Config, transport http.RoundTripper) *httpcache.Cache {
+	if cfg == nil || !cfg.Scraping.Cache.Enabled {
+		return nil
+	}
+
+	dir := cfg.Scraping.Cache.Dir
+	if dir == "" {
+		dir = filepath.Join(cfg.Data.Dir, "cache")
+	}
+	cache, err := httpcache.New(dir, time.Duration(cfg.Scraping.Cache.TTLSeconds)*time.Second, transport)
+	if err != nil {
+		log.Printf("Warning: HTTP cache disabled: %v", err)
+		return nil
+	}
+	return cache
+}
+
+// newArchive opens the snapshot archive if enabled in cfg, or returns nil,
+// and prunes snapshots older than the retention, now and as new ones are
+// saved
+func newArchive(cfg *config.Config) *snapshot.Store {
+	if cfg == nil || !cfg.Scraping.Archive.Enabled {
+		return nil
+	}
+
+	dir := cfg.Scraping.Archive.Dir
+	if dir == "" {
+		dir = filepath.Join(cfg.Data.Dir, "snapshots")
+	}
+	archive, err := snapshot.NewStore(dir)
+	if err != nil {
+		log.Printf("Warning: snapshot archive disabled: %v", err)
+		return nil
+	}
+
+	if days := cfg.Scraping.Archive.RetentionDays; days > 0 {
+		retention := time.Duration(days) * 24 * time.Hour
+		if n, err := archive.Prune(retention); err != nil {
+			log.Printf("Warning: failed to prune snapshots: %v", err)
+		} else if n > 0 {
+			log.Printf("Pruned %d snapshots older than %d days", n, days)
+		}
+		archive.SetRetention(retention)
+	}
+	return archive
+}
+
+// newImages creates the image downloader if enabled in cfg, or returns nil.
+// Images are fetched like pages, through transport and under the crawl
+// policy.
+func newImages(cfg *config.Config, transport http.RoundTripper, policy *crawlpolicy.Policy) *media.Downloader {
+	if cfg == nil || !cfg.Scraping.Images.Enabled {
+		return nil
+	}
+
+	images := cfg.Scraping.Images
+	dir := images.Dir
+	if dir == "" {
+		dir = filepath.Join(cfg.Data.Dir, "images")
+	}
+	store, err := media.NewStore(dir, images.ThumbnailSize)
+	if err != nil {
+		log.Printf("Warning: image downloads disabled: %v", err)
+		return nil
+	}
+
+	userAgent := cfg.Scraping.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	return media.NewDownloader(store, media.Config{
+		Transport: transport,
+		Policy:    policy,
+		UserAgent: userAgent,
+		MaxBytes:  images.MaxBytes,
+	})
+}
+
+// newProxyPool creates the proxy pool if proxies are configured in cfg and
+// starts its health checks, returning the function stopping them. Without a
+// pool it returns nil and a no-op.
+func newProxyPool(cfg *config.Config) (*proxy.Pool, func()) {
+	stop := func() {}
+	if cfg == nil || len(cfg.Scraping.Proxies.URLs) == 0 {
+		return nil, stop
+	}
+
+	proxies := cfg.Scraping.Proxies
+	pool, err := proxy.NewPool(proxy.Config{
+		URLs:        proxies.URLs,
+		Strategy:    proxies.Strategy,
+		MaxFailures: proxies.MaxFailures,
+		EjectFor:    time.Duration(proxies.EjectSeconds) * time.Second,
+		CheckURL:    proxies.CheckURL,
+	})
+	if err != nil {
+		log.Printf("Warning: proxies disabled: %v", err)
+		return nil, stop
+	}
+
+	if proxies.CheckURL != "" && proxies.CheckIntervalSeconds > 0 {
+		stop = pool.StartChecks(time.Duration(proxies.CheckIntervalSeconds) * time.Second)
+	}
+	return pool, stop
+}
+
+// newBreaker creates the circuit breaker with the settings of cfg
+func newBreaker(cfg *config.Config) *block.Breaker {
+	if cfg == nil {
+		return block.NewBreaker(block.Config{})
+	}
+	circuit := cfg.Scraping.Circuit
+	return block.NewBreaker(block.Config{
+		Threshold:   circuit.Threshold,
+		CoolDown:    time.Duration(circuit.CoolDownSeconds) * time.Second,
+		MaxCoolDown: time.Duration(circuit.MaxCoolDownSeconds) * time.Second,
+	})
+}
+
+// collectorOptions builds the collector options of a site from cfg
+func (sf *ScraperFactory) collectorOptions(cfg *config.Config, website string, defaults crawlpolicy.SiteLimits) CollectorOptions {
+	opts := CollectorOptions{
+		Limits:  siteLimits(cfg, website, defaults),
+		Policy:  sf.policy,
+		Cache:   sf.cache,
+		Archive: sf.archive,
+		Proxies: sf.proxies,
+		Headers: siteHeaders(cfg, website),
+		Fetcher: siteFetcher(cfg, website),
+		Breaker: sf.breaker,
+
+		Transport: sf.transport,
+	}
+	if cfg != nil {
+		opts.CacheTTL = time.Duration(cfg.Scraping.Sites[website].CacheTTLSeconds) * time.Second
+		opts.SiteProxies = cfg.Scraping.Sites[website].Proxies
+		opts.BlockMarkers = cfg.Scraping.Sites[website].BlockMarkers
+		opts.Jar = newJar(cfg, website)
+		opts.WarmUpEvery = time.Duration(cfg.Scraping.Session.WarmUpHours) * time.Hour
+		opts.WarmUpURLs = cfg.Scraping.Sites[website].WarmUpURLs
+		for _, r := range cfg.Scraping.Sites[website].JSONRequests {
+			opts.JSONRequests = append(opts.JSONRequests, JSONRequest{Name: r.Name, URL: r.URL, Vars: r.Vars, Fields: r.Fields})
+		}
+	}
+	return opts
+}
+
+// newJar opens the session of a site under the session directory if
+// sessions are enabled in cfg, and seeds it with the site's configured
+// cookies. Seeds without sessions go into a jar kept in memory. Without
+// either it returns nil.
+func newJar(cfg *config.Config, website string) *session.Jar {
+	site := cfg.Scraping.Sites[website]
+	if !cfg.Scraping.Session.Enabled && len(site.Cookies) == 0 {
+		return nil
+	}
+
+	path := ""
+	if cfg.Scraping.Session.Enabled {
+		dir := cfg.Scraping.Session.Dir
+		if dir == "" {
+			dir = filepath.Join(cfg.Data.Dir, "sessions")
+		}
+		path = filepath.Join(dir, website+".json")
+	}
+	jar, err := session.NewJar(path)
+	if err != nil {
+		log.Printf("Warning: session of %s disabled: %v", website, err)
+		return nil
+	}
+
+	for _, c := range site.Cookies {
+		cookie := &http.Cookie{Name: c.Name, Value: c.Value, Path: c.Path}
+		if err := jar.Seed(c.Domain, []*http.Cookie{cookie}); err != nil {
+			log.Printf("Warning: cookie %s of %s not seeded: %v", c.Name, website, err)
+		}
+	}
+	return jar
+}
+
+// siteFetcher creates the fetcher configured for a site, falling back to
+// colly's own requests
+func siteFetcher(cfg *config.Config, website string) fetcher.Fetcher {
+	if cfg == nil {
+		return fetcher.Colly{}
+	}
+
+	render := cfg.Scraping.Render
+	f, err := fetcher.New(cfg.Scraping.Sites[website].Fetcher, fetcher.RenderConfig{
+		URL:     render.URL,
+		Timeout: time.Duration(render.TimeoutSeconds) * time.Second,
+		Wait:    time.Duration(render.WaitMs) * time.Millisecond,
+	})
+	if err != nil {
+		log.Printf("Warning: %s fetched with colly: %v", website, err)
+		return fetcher.Colly{}
+	}
+	return f
+}
+
+// siteHeaders builds the header rotator of a site from the configured
+// profiles, restricted to those named for the site. Profiles without a user
+// agent send scraping.userAgent, and without any profile every request does.
+func siteHeaders(cfg *config.Config, website string) *headers.Rotator {
+	userAgent := defaultUserAgent
+	if cfg != nil && cfg.Scraping.UserAgent != "" {
+		userAgent = cfg.Scraping.UserAgent
+	}
+	if cfg == nil || len(cfg.Scraping.Headers.Profiles) == 0 {
+		return headers.Single(userAgent)
+	}
+
+	site := cfg.Scraping.Sites[website]
+	rotation := cfg.Scraping.Headers.Rotation
+	if site.HeaderRotation != "" {
+		rotation = site.HeaderRotation
+	}
+
+	var profiles []headers.Profile
+	for _, p := range cfg.Scraping.Headers.Profiles {
+		if len(site.HeaderProfiles) > 0 && !slices.Contains(site.HeaderProfiles, p.Name) {
+			continue
+		}
+		profile := headers.Profile{Name: p.Name, UserAgent: p.UserAgent, AcceptLanguage: p.AcceptLanguage, Accept: p.Accept}
+		if profile.UserAgent == "" {
+			profile.UserAgent = userAgent
+		}
+		profiles = append(profiles, profile)
+	}
+
+	rotator, err := headers.NewRotator(profiles, rotation)
+	if err != nil {
+		log.Printf("Warning: header profiles of %s ignored: %v", website, err)
+		return headers.Single(userAgent)
+	}
+	return rotator
+}
+
+// siteLimits overlays the configured limits of a site on its defaults
+func siteLimits(cfg *config.Config, website string, defaults crawlpolicy.SiteLimits) crawlpolicy.SiteLimits {
+	if cfg == nil {
+		return defaults
+	}
+	site, ok := cfg.Scraping.Sites[website]
+	if !ok {
+		return defaults
+	}
+
+	limits := defaults
+	if site.Parallelism > 0 {
+		limits.Parallelism = site.Parallelism
+	}
+	if site.DelayMs > 0 {
+		limits.Delay = time.Duration(site.DelayMs) * time.Millisecond
+	}
+	if site.RandomDelayMs > 0 {
+		limits.RandomDelay = time.Duration(site.RandomDelayMs) * time.Millisecond
+	}
+	return limits
+}
+
+// Policy returns the crawl policy shared by the factory's scrapers
+func (sf *ScraperFactory) Policy() *crawlpolicy.Policy {
+	return sf.policy
+}
+
+// Cache returns the HTTP cache shared by the factory's scrapers, or nil if
+// caching is disabled
+func (sf *ScraperFactory) Cache() *httpcache.Cache {
+	return sf.cache
+}
+
+// Archive returns the snapshot archive shared by the factory's scrapers, or
+// nil if archiving is disabled
+func (sf *ScraperFactory) Archive() *snapshot.Store {
+	return sf.archive
+}
+
+// Images returns the downloader of product images, or nil if image
+// downloads are disabled
+func (sf *ScraperFactory) Images() *media.Downloader {
+	return sf.images
+}
+
+// Proxies returns the proxy pool shared by the factory's scrapers, or nil
+// if requests are sent directly
+func (sf *ScraperFactory) Proxies() *proxy.Pool {
+	return sf.proxies
+}
+
+// Breaker returns the circuit breaker shared by the factory's scrapers
+func (sf *ScraperFactory) Breaker() *block.Breaker {
+	return sf.breaker
+}
+
+// SitemapCrawler returns a crawler fetching sitemaps like the factory's
+// scrapers fetch pages: under the crawl policy, with the configured user
+// agent and through the cache and proxies
+func (sf *ScraperFactory) SitemapCrawler() *sitemap.Crawler {
+	var transport http.RoundTripper = sf.cache
+	if sf.cache == nil {
+		transport = sf.transport
+	}
+	return sitemap.NewCrawler(sitemap.Config{
+		Transport: transport,
+		Policy:    sf.policy,
+		UserAgent: sf.userAgent,
+	})
+}
+
+// Close stops the background work of the factory, the proxy health checks
+func (sf *ScraperFactory) Close() {
+	sf.stop()
+}
+
+// Register adds a scraper and its site settings under a website name
+func (sf *ScraperFactory) Register(website string, s Scraper, site SiteConfig) {
+	sf.scrapers[website] = s
+	sf.sites[website] = site
+}
+
+// GetSiteConfig returns the settings registered for a website
+func (sf *ScraperFactory) GetSiteConfig(website string) (SiteConfig, bool) {
+	site, exists := sf.sites[website]
+	return site, exists
+}
+
 // GetScraper returns a scraper for a given website
 func (sf *ScraperFactory) GetScraper(website string) (Scraper, bool) {
 	scraper, exists := sf.scrapers[website]
@@ -1,7 +1,21 @@
 package scraper
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/block"
+	"github.com/tedjuang/go-scrapy/internal/config"
+	"github.com/tedjuang/go-scrapy/internal/fetcher"
+	"github.com/tedjuang/go-scrapy/internal/models"
 )
 
 func TestNewScraperFactory(t *testing.T) {
@@ -84,3 +98,298 @@ func TestGetAllScrapers(t *testing.T) {
 		t.Error("Expected Rakuten scraper to be in the map")
 	}
 }
+
+func TestSiteLimitsFromConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Scraping.Sites = map[string]config.SiteScraping{
+		"rakuten": {DelayMs: 5000, RandomDelayMs: 1500},
+	}
+
+	limits := siteLimits(cfg, "rakuten", DefaultRakutenLimits)
+	if limits.Parallelism != DefaultRakutenLimits.Parallelism {
+		t.Errorf("Expected default parallelism to be kept, got %d", limits.Parallelism)
+	}
+	if limits.Delay != 5*time.Second || limits.RandomDelay != 1500*time.Millisecond {
+		t.Errorf("Expected configured delays, got %+v", limits)
+	}
+
+	if got := siteLimits(nil, "rakuten", DefaultRakutenLimits); got != DefaultRakutenLimits {
+		t.Errorf("Expected defaults without config, got %+v", got)
+	}
+
+	if NewScraperFactoryWithConfig(cfg).Policy() == nil {
+		t.Error("Expected factory to have a crawl policy")
+	}
+}
+
+func TestFactoryCacheFromConfig(t *testing.T) {
+	if NewScraperFactory().Cache() != nil {
+		t.Error("Expected no cache by default")
+	}
+
+	cfg := &config.Config{}
+	cfg.Data.Dir = t.TempDir()
+	cfg.Scraping.Cache.Enabled = true
+	if NewScraperFactoryWithConfig(cfg).Cache() == nil {
+		t.Error("Expected cache when enabled in config")
+	}
+}
+
+func TestFactoryArchiveFromConfig(t *testing.T) {
+	if NewScraperFactory().Archive() != nil {
+		t.Error("Expected no archive by default")
+	}
+
+	cfg := &config.Config{}
+	cfg.Data.Dir = t.TempDir()
+	cfg.Scraping.Archive.Enabled = true
+	if NewScraperFactoryWithConfig(cfg).Archive() == nil {
+		t.Error("Expected archive when enabled in config")
+	}
+}
+
+func TestFactoryProxiesFromConfig(t *testing.T) {
+	if NewScraperFactory().Proxies() != nil {
+		t.Error("Expected no proxies by default")
+	}
+
+	// A stand-in proxy refusing every tunnel
+	var connects atomic.Int32
+	standIn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			connects.Add(1)
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer standIn.Close()
+
+	cfg := &config.Config{}
+	cfg.Scraping.IgnoreRobotsTxt = true
+	cfg.Scraping.Proxies.URLs = []string{"http://10.0.0.1:3128", standIn.URL}
+	cfg.Scraping.Sites = map[string]config.SiteScraping{
+		"rakuten": {DelayMs: 1, Proxies: []string{standIn.URL}},
+	}
+	factory := NewScraperFactoryWithConfig(cfg)
+	if factory.Proxies() == nil {
+		t.Fatal("Expected proxies when configured")
+	}
+
+	s, _ := factory.GetScraper("rakuten")
+	if _, err := s.ScrapeProduct("https://item.rakuten.co.jp/shop/item/"); err == nil {
+		t.Error("Expected the scrape to fail through the refusing proxy")
+	}
+	if connects.Load() == 0 {
+		t.Error("Expected the request to go through the site's proxy")
+	}
+
+	stats := factory.Proxies().Stats()
+	if stats[0].Requests != 0 || stats[1].Sites["rakuten.co.jp"] == 0 {
+		t.Errorf("Expected only the assigned proxy used for rakuten, got %+v", stats)
+	}
+
+	cfg.Scraping.Proxies.Strategy = "random"
+	if NewScraperFactoryWithConfig(cfg).Proxies() != nil {
+		t.Error("Expected proxies disabled by an invalid config")
+	}
+}
+
+// headerRecorder is a transport recording the headers of each request and
+// answering 404
+type headerRecorder struct {
+	headers []http.Header
+}
+
+func (r *headerRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.headers = append(r.headers, req.Header.Clone())
+	return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Request: req}, nil
+}
+
+func TestFactoryHeadersFromConfig(t *testing.T) {
+	scrape := func(cfg *config.Config) []http.Header {
+		recorder := &headerRecorder{}
+		s, _ := newScraperFactory(cfg, recorder).GetScraper("rakuten")
+		s.ScrapeProduct("https://item.rakuten.co.jp/shop/a/")
+		s.ScrapeProduct("https://item.rakuten.co.jp/shop/b/")
+		return recorder.headers
+	}
+
+	cfg := &config.Config{}
+	cfg.Scraping.IgnoreRobotsTxt = true
+	cfg.Scraping.UserAgent = "go-scrapy/1.0"
+	cfg.Scraping.Sites = map[string]config.SiteScraping{"rakuten": {DelayMs: 1}}
+
+	// The configured user agent replaces the built-in one
+	for _, h := range scrape(cfg) {
+		if h.Get("User-Agent") != "go-scrapy/1.0" || h.Get("Accept-Language") == "" {
+			t.Errorf("Expected the configured user agent, got %v", h)
+		}
+	}
+
+	// Profiles rotate per crawl, restricted to those of the site
+	cfg.Scraping.Headers.Profiles = []config.HeaderProfile{
+		{Name: "desktop", UserAgent: "Desktop"},
+		{Name: "plain"},
+		{Name: "mobile", UserAgent: "Mobile", AcceptLanguage: "ja-JP"},
+	}
+	cfg.Scraping.Headers.Rotation = "random"
+	cfg.Scraping.Sites["rakuten"] = config.SiteScraping{
+		DelayMs:        1,
+		HeaderProfiles: []string{"plain", "mobile"},
+		HeaderRotation: "round-robin",
+	}
+	got := scrape(cfg)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(got))
+	}
+	if got[0].Get("User-Agent") != "go-scrapy/1.0" || got[1].Get("User-Agent") != "Mobile" || got[1].Get("Accept-Language") != "ja-JP" {
+		t.Errorf("Unexpected rotation: %v", got)
+	}
+}
+
+// pageTransport answers every request with a 200 HTML page
+type pageTransport struct {
+	html string
+}
+
+func (t pageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(t.html)),
+		Request:    req,
+	}, nil
+}
+
+func TestFactoryBlockDetection(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Scraping.IgnoreRobotsTxt = true
+	cfg.Scraping.Circuit.Threshold = 2
+	cfg.Scraping.Sites = map[string]config.SiteScraping{
+		"rakuten": {DelayMs: 1, BlockMarkers: []string{"ただいまメンテナンス中"}},
+	}
+	page := "<html><body><h1 itemprop='name'>メンテナンス</h1><p>ただいまメンテナンス中です</p>" + strings.Repeat("<p>お知らせ</p>", 50) + "</body></html>"
+	factory := newScraperFactory(cfg, pageTransport{html: page})
+	s, _ := factory.GetScraper("rakuten")
+
+	for i := 0; i < 2; i++ {
+		_, err := s.ScrapeProduct("https://item.rakuten.co.jp/shop/item/")
+		if !errors.Is(err, block.ErrBlocked) {
+			t.Fatalf("Expected ErrBlocked for the configured marker, got %v", err)
+		}
+	}
+
+	// The circuit is open now and the search isn't sent
+	_, err := s.ScrapeSearch("switch", 5)
+	var blocked *block.BlockedError
+	if !errors.As(err, &blocked) || blocked.Reason != "circuit open" {
+		t.Errorf("Expected the open circuit to stop the search, got %v", err)
+	}
+
+	states := factory.Breaker().States()
+	if len(states) != 1 || states[0].Domain != "rakuten.co.jp" || states[0].State != block.StateOpen {
+		t.Errorf("Unexpected circuit states: %+v", states)
+	}
+}
+
+// sessionTransport records the URL and cookies of each request, answering
+// the top page with a session cookie and anything else with 404
+type sessionTransport struct {
+	urls    []string
+	cookies []string
+}
+
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.urls = append(t.urls, req.URL.String())
+	t.cookies = append(t.cookies, req.Header.Get("Cookie"))
+	if req.URL.Host != "www.rakuten.co.jp" {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Request: req}, nil
+	}
+	resp, err := pageTransport{html: "<html><body>" + strings.Repeat("<p>楽天市場</p>", 50) + "</body></html>"}.RoundTrip(req)
+	resp.Header.Set("Set-Cookie", "Rp=abc; Domain=.rakuten.co.jp; Path=/")
+	return resp, err
+}
+
+func TestFactorySessionFromConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Data.Dir = t.TempDir()
+	cfg.Scraping.IgnoreRobotsTxt = true
+	cfg.Scraping.Session.Enabled = true
+	cfg.Scraping.Cache.Enabled = true
+	cfg.Scraping.Sites = map[string]config.SiteScraping{
+		"rakuten": {DelayMs: 1, Cookies: []config.Cookie{{Name: "age_check", Value: "1", Domain: ".rakuten.co.jp"}}},
+	}
+
+	// The first crawl warms up the new session, later ones reuse it
+	transport := &sessionTransport{}
+	factory := newScraperFactory(cfg, transport)
+	s, _ := factory.GetScraper("rakuten")
+	s.ScrapeProduct("https://item.rakuten.co.jp/shop/a/")
+	s.ScrapeProduct("https://item.rakuten.co.jp/shop/b/")
+	if len(transport.urls) != 3 || transport.urls[0] != "https://www.rakuten.co.jp/" {
+		t.Fatalf("Expected one warm-up before the crawls, got %v", transport.urls)
+	}
+	if transport.cookies[0] != "age_check=1" {
+		t.Errorf("Expected the seeded cookie on the warm-up, got %q", transport.cookies[0])
+	}
+	for _, cookie := range transport.cookies[1:] {
+		if !strings.Contains(cookie, "age_check=1") || !strings.Contains(cookie, "Rp=abc") {
+			t.Errorf("Expected the session cookies on the crawl, got %q", cookie)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(cfg.Data.Dir, "sessions", "rakuten.json")); err != nil {
+		t.Fatalf("Expected the session saved: %v", err)
+	}
+
+	// The next run picks up the saved session without warming up again
+	transport = &sessionTransport{}
+	s, _ = newScraperFactory(cfg, transport).GetScraper("rakuten")
+	s.ScrapeProduct("https://item.rakuten.co.jp/shop/a/")
+	if len(transport.urls) != 1 || !strings.Contains(transport.cookies[0], "Rp=abc") {
+		t.Errorf("Expected the saved session reused, got %v %v", transport.urls, transport.cookies)
+	}
+
+	// Pages fetched in a session bypass the cache
+	if stats := factory.Cache().Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Expected the cache bypassed with a session, got %+v", stats)
+	}
+}
+
+func TestFactoryRenderFetcher(t *testing.T) {
+	rendered, err := os.ReadFile("testdata/rakuten_item.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const url = "https://item.rakuten.co.jp/gamestore/switch-oled/"
+	stub := &fetcher.StubRenderer{Pages: map[string]string{url: string(rendered)}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cfg.Scraping.IgnoreRobotsTxt = true
+	cfg.Scraping.UserAgent = "go-scrapy/1.0"
+	cfg.Scraping.Render.URL = server.URL
+	cfg.Scraping.Sites = map[string]config.SiteScraping{"rakuten": {DelayMs: 1, Fetcher: "render"}}
+
+	// Without rendering the price loaded by scripts is missing
+	page := "<html><body><h1 class='item-name'>Nintendo Switch</h1>" + strings.Repeat("<p>読み込み中</p>", 50) + "</body></html>"
+	s, _ := newScraperFactory(cfg, pageTransport{html: page}).GetScraper("rakuten")
+	product, err := s.ScrapeProduct(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.CurrentPrice != models.NewMoney(37980, "JPY") {
+		t.Errorf("Expected the rendered price, got %s", product.CurrentPrice)
+	}
+	requests := stub.Requests()
+	if len(requests) != 1 || requests[0].URL != url || requests[0].Headers["User-Agent"] != "go-scrapy/1.0" {
+		t.Errorf("Unexpected render requests: %+v", requests)
+	}
+
+	// A renderer without a service falls back to colly
+	cfg.Scraping.Render.URL = ""
+	s, _ = newScraperFactory(cfg, pageTransport{html: page}).GetScraper("rakuten")
+	if product, err := s.ScrapeProduct(url); err != nil || !product.CurrentPrice.IsZero() {
+		t.Errorf("Expected the unrendered page, got %v (%v)", product, err)
+	}
+}
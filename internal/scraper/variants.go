@@ -0,0 +1,162 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// skuData is the per-variant data embedded in Rakuten item pages
+type skuData struct {
+	VariantID        string      `json:"variantId"`
+	SelectorValues   []string    `json:"selectorValues"`
+	TaxIncludedPrice json.Number `json:"taxIncludedPrice"`
+	Price            json.Number `json:"price"`
+	StockCount       *int        `json:"stockCount"`
+	Inventory        *struct {
+		Quantity int `json:"quantity"`
+	} `json:"inventory"`
+}
+
+// variantSelectorData names the option axes (e.g. カラー, サイズ) in the same order
+// as skuData.SelectorValues
+type variantSelectorData struct {
+	Label string `json:"label"`
+}
+
+// parseVariantJSON extracts variants from a script embedding "sku" and
+// "variantSelectors" arrays, as found in Rakuten's item page state
+func parseVariantJSON(script, currency string) []models.Variant {
+	var skus []skuData
+	if !decodeEmbeddedArray(script, `"sku"`, &skus) || len(skus) == 0 {
+		return nil
+	}
+
+	var selectors []variantSelectorData
+	decodeEmbeddedArray(script, `"variantSelectors"`, &selectors)
+
+	variants := make([]models.Variant, 0, len(skus))
+	for _, sku := range skus {
+		if sku.VariantID == "" {
+			continue
+		}
+
+		attributes := make(map[string]string, len(sku.SelectorValues))
+		for i, value := range sku.SelectorValues {
+			name := fmt.Sprintf("option%d", i+1)
+			if i < len(selectors) && selectors[i].Label != "" {
+				name = selectors[i].Label
+			}
+			attributes[name] = value
+		}
+
+		priceText := sku.TaxIncludedPrice.String()
+		if priceText == "" {
+			priceText = sku.Price.String()
+		}
+		price, err := models.ParseDecimal(priceText, currency)
+		if err != nil {
+			continue
+		}
+
+		availability := models.AvailabilityUnknown
+		switch {
+		case sku.StockCount != nil && *sku.StockCount == 0, sku.Inventory != nil && sku.Inventory.Quantity == 0:
+			availability = models.AvailabilityOutOfStock
+		case sku.StockCount != nil, sku.Inventory != nil:
+			availability = models.AvailabilityInStock
+		}
+
+		variants = append(variants, models.Variant{
+			SKU:          sku.VariantID,
+			Attributes:   attributes,
+			Price:        price,
+			Availability: availability,
+		})
+	}
+	return variants
+}
+
+// decodeEmbeddedArray finds `key: [...]` in script and decodes the array into v
+func decodeEmbeddedArray(script, key string, v any) bool {
+	i := strings.Index(script, key)
+	if i < 0 {
+		return false
+	}
+	rest := script[i+len(key):]
+	start := strings.Index(rest, "[")
+	if start < 0 || strings.TrimLeft(rest[:start], " \t\r\n:") != "" {
+		return false
+	}
+	// json.Decoder stops after the first complete value, ignoring the rest of the script
+	return json.NewDecoder(strings.NewReader(rest[start:])).Decode(v) == nil
+}
+
+// parseVariantOptions builds one variant per option of an option selector,
+// e.g. <select name="サイズ"><option value="s1">S ¥1,980</option>...</select>.
+// Options without a price of their own inherit basePrice.
+func parseVariantOptions(sel *goquery.Selection, basePrice models.Money) []models.Variant {
+	name := firstNonEmpty(sel.AttrOr("data-label", ""), sel.AttrOr("aria-label", ""), sel.AttrOr("name", ""), "option")
+
+	var variants []models.Variant
+	sel.Find("option").Each(func(_ int, opt *goquery.Selection) {
+		value := strings.TrimSpace(opt.AttrOr("value", ""))
+		text := strings.TrimSpace(opt.Text())
+		// Skip placeholders such as "選択してください"
+		if value == "" || value == "0" || strings.Contains(text, "選択") {
+			return
+		}
+
+		label, priceText := splitOptionText(text)
+		price := basePrice
+		if priceText != "" {
+			if p, err := models.ParseMoney(priceText, basePrice.Currency); err == nil {
+				price = p
+			}
+		}
+
+		availability := extractAvailability(text)
+		if _, disabled := opt.Attr("disabled"); disabled {
+			availability = models.AvailabilityOutOfStock
+		}
+
+		variants = append(variants, models.Variant{
+			SKU:          value,
+			Attributes:   map[string]string{name: label},
+			Price:        price,
+			Availability: availability,
+		})
+	})
+	return variants
+}
+
+// splitOptionText separates an option label from a trailing price, e.g.
+// "ブラック / M ¥1,980 (在庫あり)" becomes "ブラック / M" and "¥1,980"
+func splitOptionText(text string) (label, price string) {
+	label = text
+	if i := strings.IndexAny(label, "(（"); i > 0 {
+		label = strings.TrimSpace(label[:i])
+	}
+	if i := strings.IndexAny(label, "¥￥"); i > 0 {
+		return strings.TrimSpace(label[:i]), label[i:]
+	}
+	if strings.HasSuffix(label, "円") {
+		if i := strings.LastIndex(label, " "); i > 0 {
+			return strings.TrimSpace(label[:i]), label[i+1:]
+		}
+	}
+	return label, ""
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestParseVariantJSON(t *testing.T) {
+	script := `window.__INITIAL_STATE__ = {"itemInfo": {"variantSelectors": [{"label": "カラー"}, {"label": "サイズ"}],
+		"sku": [
+			{"variantId": "v-black-m", "selectorValues": ["ブラック", "M"], "taxIncludedPrice": 1980, "stockCount": 3},
+			{"variantId": "v-black-l", "selectorValues": ["ブラック", "L"], "taxIncludedPrice": 2180, "stockCount": 0}
+		]}};`
+
+	variants := parseVariantJSON(script, "JPY")
+	if len(variants) != 2 {
+		t.Fatalf("Expected 2 variants, got %d", len(variants))
+	}
+
+	v := variants[1]
+	if v.SKU != "v-black-l" || v.Attributes["カラー"] != "ブラック" || v.Attributes["サイズ"] != "L" {
+		t.Errorf("Unexpected variant: %+v", v)
+	}
+	if v.Price != models.NewMoney(2180, "JPY") {
+		t.Errorf("Expected 2180 JPY, got %s", v.Price)
+	}
+	if v.Availability != models.AvailabilityOutOfStock || variants[0].Availability != models.AvailabilityInStock {
+		t.Errorf("Expected stock counts to map to availability, got %s and %s", variants[0].Availability, v.Availability)
+	}
+
+	if parseVariantJSON(`var config = {"sku": "abc"};`, "JPY") != nil {
+		t.Error("Expected no variants when sku is not an array")
+	}
+}
+
+func TestParseVariantOptions(t *testing.T) {
+	html := `<select name="サイズ" class="inventory_choice">
+		<option value="">選択してください</option>
+		<option value="s">S</option>
+		<option value="xl">XL ¥2,480</option>
+		<option value="xxl" disabled>XXL (×品切れ)</option>
+	</select>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+
+	variants := parseVariantOptions(doc.Find("select"), models.NewMoney(1980, "JPY"))
+	if len(variants) != 3 {
+		t.Fatalf("Expected 3 variants, got %d", len(variants))
+	}
+
+	if variants[0].Price != models.NewMoney(1980, "JPY") || variants[0].Attributes["サイズ"] != "S" {
+		t.Errorf("Expected S to inherit base price, got %+v", variants[0])
+	}
+	if variants[1].Price != models.NewMoney(2480, "JPY") || variants[1].Attributes["サイズ"] != "XL" {
+		t.Errorf("Expected XL at its own price, got %+v", variants[1])
+	}
+	if variants[2].Availability != models.AvailabilityOutOfStock || variants[2].Attributes["サイズ"] != "XXL" {
+		t.Errorf("Expected disabled XXL to be out of stock, got %+v", variants[2])
+	}
+}
@@ -0,0 +1,369 @@
+// Package session keeps the cookies of a site across crawls and runs, so
+// scheduled scrapes reuse one realistic session instead of arriving as a
+// new visitor every time. Consent, region and age-confirmation cookies can
+// be seeded from the configuration, and a scraper's warm-up steps run
+// whenever the session is due for a refresh.
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// storedCookie is a cookie as kept in the session file, with the URL that
+// set it so it can be replayed into the jar
+type storedCookie struct {
+	URL      string     `json:"url"`
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Domain   string     `json:"domain,omitempty"`
+	Path     string     `json:"path,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"` // nil for session cookies, which are kept as well
+	Secure   bool       `json:"secure,omitempty"`
+	HttpOnly bool       `json:"http_only,omitempty"`
+}
+
+// file is the content of a session file
+type file struct {
+	WarmedUp *time.Time     `json:"warmed_up,omitempty"`
+	Cookies  []storedCookie `json:"cookies"`
+}
+
+// Jar is a cookie jar saved to a file whenever its cookies change. It is
+// safe for concurrent use.
+type Jar struct {
+	path string // empty keeps the jar in memory
+
+	mutex    sync.Mutex
+	jar      *cookiejar.Jar
+	cookies  map[string]storedCookie // keyed by domain, path and name
+	warmedUp time.Time
+
+	// warmMutex serializes warm-ups, which fetch pages and so can't hold
+	// mutex
+	warmMutex sync.Mutex
+
+	now func() time.Time
+}
+
+// NewJar creates a jar saved to path, loading the cookies saved there
+// before. An empty path keeps the jar in memory.
+func NewJar(path string) (*Jar, error) {
+	inner, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	j := &Jar{
+		path:    path,
+		jar:     inner,
+		cookies: make(map[string]storedCookie),
+		now:     time.Now,
+	}
+	if path == "" {
+		return j, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", path, err)
+	}
+	if f.WarmedUp != nil {
+		j.warmedUp = *f.WarmedUp
+	}
+	for _, sc := range f.Cookies {
+		u, err := url.Parse(sc.URL)
+		if err != nil || (sc.Expires != nil && !sc.Expires.After(j.now())) {
+			continue
+		}
+		j.store(u, sc.cookie())
+	}
+	return j, nil
+}
+
+// Cookies returns the cookies to send with a request to u
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// SetCookies stores the cookies of a response from u and saves the jar if
+// they changed it
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if len(cookies) == 0 {
+		return
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	changed := false
+	for _, c := range cookies {
+		if j.store(u, c) {
+			changed = true
+		}
+	}
+	if changed {
+		if err := j.save(); err != nil {
+			// The cookies are still kept in memory for this run
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// Seed sets cookies configured for a domain, e.g. ".example.com", as if a
+// page of the domain had set them. Seeds replace saved cookies of the same
+// name.
+func (j *Jar) Seed(domain string, cookies []*http.Cookie) error {
+	host := strings.TrimPrefix(domain, ".")
+	if host == "" {
+		return errors.New("seed cookies need a domain")
+	}
+	u := &url.URL{Scheme: "https", Host: host, Path: "/"}
+
+	seeded := make([]*http.Cookie, len(cookies))
+	for i, c := range cookies {
+		c := *c
+		if c.Domain == "" {
+			c.Domain = domain
+		}
+		if c.Path == "" {
+			c.Path = "/"
+		}
+		seeded[i] = &c
+	}
+	j.SetCookies(u, seeded)
+	return nil
+}
+
+// Len returns the number of cookies in the jar
+func (j *Jar) Len() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	n := 0
+	now := j.now()
+	for _, sc := range j.cookies {
+		if sc.Expires == nil || sc.Expires.After(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// WarmedUp returns when the warm-up steps last succeeded, zero if never
+func (j *Jar) WarmedUp() time.Time {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.warmedUp
+}
+
+// WarmUp runs warm unless it already succeeded within every, or ever if
+// every is 0, and records its success in the session file. Concurrent
+// crawls wait for one warm-up instead of each running their own. It reports
+// whether warm ran.
+func (j *Jar) WarmUp(every time.Duration, warm func() error) (bool, error) {
+	j.warmMutex.Lock()
+	defer j.warmMutex.Unlock()
+
+	if last := j.WarmedUp(); !last.IsZero() && (every <= 0 || j.now().Sub(last) < every) {
+		return false, nil
+	}
+	if err := warm(); err != nil {
+		return true, err
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.warmedUp = j.now()
+	return true, j.save()
+}
+
+// Transport returns a transport sending the jar's cookies with the requests
+// it passes to base, or http.DefaultTransport if nil, and storing the
+// cookies of their responses. Unlike a client's jar it sees every hop of a
+// redirect through a transport set on a colly collector.
+func (j *Jar) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{jar: j, base: base}
+}
+
+// transport applies a Jar to the requests of base
+type transport struct {
+	jar  *Jar
+	base http.RoundTripper
+}
+
+// RoundTrip sends req with the jar's cookies and stores those of the
+// response
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if cookies := t.jar.Cookies(req.URL); len(cookies) > 0 {
+		req = req.Clone(req.Context())
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.jar.SetCookies(req.URL, resp.Cookies())
+	return resp, nil
+}
+
+// store sets cookie c from u in the jar and records it for saving,
+// reporting whether the recorded cookies changed. The caller holds the
+// mutex, except while loading.
+func (j *Jar) store(u *url.URL, c *http.Cookie) bool {
+	j.jar.SetCookies(u, []*http.Cookie{c})
+
+	sc := storedCookie{
+		URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+	}
+	// Keyed like the cookie jar, which replaces a host cookie with a domain
+	// cookie of the same name
+	domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	if domain == "" {
+		domain = u.Hostname()
+	}
+	path := c.Path
+	if path == "" {
+		path = defaultPath(u.Path)
+	}
+	key := strings.Join([]string{domain, path, c.Name}, "|")
+
+	now := j.now()
+	switch {
+	case c.MaxAge < 0:
+		sc.Expires = &now
+	case c.MaxAge > 0:
+		expires := now.Add(time.Duration(c.MaxAge) * time.Second)
+		sc.Expires = &expires
+	case !c.Expires.IsZero():
+		expires := c.Expires
+		sc.Expires = &expires
+	}
+
+	if sc.Expires != nil && !sc.Expires.After(now) {
+		_, ok := j.cookies[key]
+		delete(j.cookies, key)
+		return ok
+	}
+	old, ok := j.cookies[key]
+	j.cookies[key] = sc
+	return !ok || old.Value != sc.Value || !sameExpiry(old.Expires, sc.Expires)
+}
+
+// save writes the unexpired cookies to the session file. The caller holds
+// the mutex.
+func (j *Jar) save() error {
+	if j.path == "" {
+		return nil
+	}
+
+	now := j.now()
+	keys := make([]string, 0, len(j.cookies))
+	for key, sc := range j.cookies {
+		if sc.Expires == nil || sc.Expires.After(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	f := file{Cookies: make([]storedCookie, len(keys))}
+	for i, key := range keys {
+		f.Cookies[i] = j.cookies[key]
+	}
+	if !j.warmedUp.IsZero() {
+		warmedUp := j.warmedUp
+		f.WarmedUp = &warmedUp
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	// Written to a temporary file of its own and renamed so a crash never
+	// leaves half a session and processes sharing it don't interleave.
+	// os.CreateTemp makes it readable by the owner only, as cookies are
+	// credentials.
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), j.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
+
+// cookie returns the stored cookie as set by a response
+func (sc storedCookie) cookie() *http.Cookie {
+	c := &http.Cookie{
+		Name:     sc.Name,
+		Value:    sc.Value,
+		Domain:   sc.Domain,
+		Path:     sc.Path,
+		Secure:   sc.Secure,
+		HttpOnly: sc.HttpOnly,
+	}
+	if sc.Expires != nil {
+		c.Expires = *sc.Expires
+	}
+	return c
+}
+
+// defaultPath is the path of a cookie set without one by a response to a
+// URL with urlPath, as in RFC 6265 section 5.1.4
+func defaultPath(urlPath string) string {
+	i := strings.LastIndex(urlPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return urlPath[:i]
+}
+
+// sameExpiry reports whether two optional expiry times are equal
+func sameExpiry(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
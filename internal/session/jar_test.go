@@ -0,0 +1,161 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestJarPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shop.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "region", Value: "tokyo", Path: "/", MaxAge: 3600})
+			http.SetCookie(w, &http.Cookie{Name: "gone", Value: "x", Path: "/", MaxAge: -1})
+		case "/echo":
+			for _, c := range r.Cookies() {
+				w.Header().Add("X-Cookie", c.Name+"="+c.Value)
+			}
+		}
+	}))
+	defer server.Close()
+
+	jar, err := NewJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: jar.Transport(nil)}
+	if _, err := client.Get(server.URL + "/login"); err != nil {
+		t.Fatal(err)
+	}
+	if jar.Len() != 2 {
+		t.Fatalf("Expected 2 cookies, got %d", jar.Len())
+	}
+
+	// A new jar on the same file, as in the next run, sends the cookies
+	reopened, err := NewJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: reopened.Transport(nil)}
+	resp, err := client.Get(server.URL + "/echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := resp.Header.Values("X-Cookie")
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "region=tokyo" || got[1] != "sid=abc" {
+		t.Errorf("Expected the saved cookies sent, got %v", got)
+	}
+}
+
+func TestJarDropsExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shop.json")
+	clock := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	jar, err := NewJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.now = func() time.Time { return clock }
+
+	u, _ := url.Parse("https://shop.example/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "short", Value: "1", Expires: clock.Add(time.Hour)},
+		{Name: "long", Value: "2", Expires: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	jar.SetCookies(u, []*http.Cookie{{Name: "long", Value: "2", MaxAge: -1}})
+	if jar.Len() != 1 {
+		t.Errorf("Expected the deleted cookie gone, got %d cookies", jar.Len())
+	}
+
+	// Saved cookies past their expiry aren't loaded
+	clock = clock.Add(2 * time.Hour)
+	jar.SetCookies(u, []*http.Cookie{{Name: "new", Value: "3"}})
+	reopened, err := NewJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cookies := reopened.Cookies(u); len(cookies) != 1 || cookies[0].Name != "new" {
+		t.Errorf("Expected only the unexpired cookie, got %v", cookies)
+	}
+}
+
+func TestJarSeed(t *testing.T) {
+	jar, err := NewJar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://shop.example/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "age_check", Value: "0", Path: "/"}})
+
+	if err := jar.Seed(".shop.example", []*http.Cookie{{Name: "age_check", Value: "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := jar.Seed("", []*http.Cookie{{Name: "x", Value: "1"}}); err == nil {
+		t.Error("Expected an error for a seed without a domain")
+	}
+
+	sub, _ := url.Parse("https://item.shop.example/p/1")
+	if cookies := jar.Cookies(sub); len(cookies) != 1 || cookies[0].Value != "1" {
+		t.Errorf("Expected the seeded cookie on subdomains, got %v", cookies)
+	}
+	if jar.Len() != 1 {
+		t.Errorf("Expected the seed to replace the saved cookie, got %d cookies", jar.Len())
+	}
+}
+
+func TestJarWarmUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shop.json")
+	clock := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	jar, err := NewJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.now = func() time.Time { return clock }
+
+	runs := 0
+	warm := func() error {
+		runs++
+		return nil
+	}
+
+	if _, err := jar.WarmUp(time.Hour, func() error { return errors.New("blocked") }); err == nil {
+		t.Fatal("Expected the warm-up error")
+	}
+	if !jar.WarmedUp().IsZero() {
+		t.Error("Expected a failed warm-up not recorded")
+	}
+
+	jar.WarmUp(time.Hour, warm)
+	clock = clock.Add(30 * time.Minute)
+	if ran, _ := jar.WarmUp(time.Hour, warm); ran || runs != 1 {
+		t.Errorf("Expected no warm-up within the hour, ran %d times", runs)
+	}
+
+	// The warm-up time is saved with the session
+	reopened, err := NewJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.WarmedUp().Equal(clock.Add(-30 * time.Minute)) {
+		t.Errorf("Expected the warm-up time saved, got %s", reopened.WarmedUp())
+	}
+
+	clock = clock.Add(time.Hour)
+	if ran, err := jar.WarmUp(time.Hour, warm); !ran || err != nil || runs != 2 {
+		t.Errorf("Expected a warm-up after the hour, ran %d times (%v)", runs, err)
+	}
+
+	// Without an interval only new sessions are warmed up
+	clock = clock.Add(24 * time.Hour)
+	if ran, _ := jar.WarmUp(0, warm); ran {
+		t.Error("Expected no warm-up of a warmed-up session")
+	}
+}
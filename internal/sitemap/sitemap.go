@@ -0,0 +1,282 @@
+// Package sitemap discovers page URLs from XML sitemaps, following the
+// Sitemap: lines of robots.txt, sitemap indexes and gzip-compressed sitemaps
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/crawlpolicy"
+)
+
+// maxIndexDepth bounds sitemap index recursion
+const maxIndexDepth = 5
+
+// maxSitemapSize bounds the size of a single (decompressed) sitemap; the
+// protocol allows at most 50MB
+const maxSitemapSize = 50 << 20
+
+// Entry is a page listed in a sitemap
+type Entry struct {
+	Loc     string    `json:"loc"`
+	LastMod time.Time `json:"lastmod,omitempty"` // zero when the sitemap doesn't say
+}
+
+// Options filter the discovered entries
+type Options struct {
+	Since time.Time             // skip entries (and child sitemaps) last modified before Since
+	Match func(loc string) bool // keep only matching page URLs; nil keeps all
+	Limit int                   // stop after Limit entries; 0 means no limit
+}
+
+// Config configures a Crawler
+type Config struct {
+	Transport http.RoundTripper   // nil uses the default transport
+	Policy    *crawlpolicy.Policy // checks and paces requests if set
+	UserAgent string
+}
+
+// Crawler fetches robots.txt files and sitemaps
+type Crawler struct {
+	Client    *http.Client
+	Policy    *crawlpolicy.Policy
+	UserAgent string
+}
+
+// NewCrawler creates a sitemap crawler
+func NewCrawler(cfg Config) *Crawler {
+	return &Crawler{
+		Client:    &http.Client{Transport: cfg.Transport, Timeout: 30 * time.Second},
+		Policy:    cfg.Policy,
+		UserAgent: cfg.UserAgent,
+	}
+}
+
+// document is either a <sitemapindex> or a <urlset>
+type document struct {
+	XMLName  xml.Name   `xml:""`
+	Sitemaps []entryXML `xml:"sitemap"`
+	URLs     []entryXML `xml:"url"`
+}
+
+type entryXML struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// Discover collects page entries from the sitemaps listed in robotsURLs and
+// from sitemapURLs. Sitemaps that fail to load are logged and skipped; an
+// error is returned only if nothing could be read at all.
+func (c *Crawler) Discover(robotsURLs, sitemapURLs []string, opts Options) ([]Entry, error) {
+	sitemaps := append([]string{}, sitemapURLs...)
+	var lastErr error
+	for _, robotsURL := range robotsURLs {
+		found, err := c.RobotsSitemaps(robotsURL)
+		if err != nil {
+			log.Printf("Failed to read %s: %v", robotsURL, err)
+			lastErr = err
+			continue
+		}
+		sitemaps = append(sitemaps, found...)
+	}
+
+	d := &discovery{
+		crawler: c,
+		opts:    opts,
+		visited: make(map[string]bool),
+		seen:    make(map[string]bool),
+	}
+	for _, sitemapURL := range sitemaps {
+		if d.full() {
+			break
+		}
+		if err := d.walk(sitemapURL, 0); err != nil {
+			log.Printf("Failed to read sitemap %s: %v", sitemapURL, err)
+			lastErr = err
+			continue
+		}
+		d.read++
+	}
+
+	if d.read == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return d.entries, nil
+}
+
+// RobotsSitemaps returns the sitemap URLs listed in a robots.txt file
+func (c *Crawler) RobotsSitemaps(robotsURL string) ([]string, error) {
+	body, err := c.fetch(robotsURL)
+	if err != nil {
+		return nil, err
+	}
+	return parseRobotsSitemaps(body), nil
+}
+
+// parseRobotsSitemaps extracts the Sitemap: lines of a robots.txt file
+func parseRobotsSitemaps(robots []byte) []string {
+	var sitemaps []string
+	scanner := bufio.NewScanner(bytes.NewReader(robots))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "sitemap") {
+			if loc := strings.TrimSpace(value); loc != "" {
+				sitemaps = append(sitemaps, loc)
+			}
+		}
+	}
+	return sitemaps
+}
+
+// discovery holds the state of one Discover call
+type discovery struct {
+	crawler *Crawler
+	opts    Options
+	visited map[string]bool // sitemaps
+	seen    map[string]bool // page URLs
+	entries []Entry
+	read    int
+}
+
+func (d *discovery) full() bool {
+	return d.opts.Limit > 0 && len(d.entries) >= d.opts.Limit
+}
+
+// walk reads a sitemap or sitemap index, recursing into child sitemaps
+func (d *discovery) walk(sitemapURL string, depth int) error {
+	if d.visited[sitemapURL] || d.full() {
+		return nil
+	}
+	d.visited[sitemapURL] = true
+
+	body, err := d.crawler.fetch(sitemapURL)
+	if err != nil {
+		return err
+	}
+
+	var doc document
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+
+	for _, child := range doc.Sitemaps {
+		if depth >= maxIndexDepth {
+			return fmt.Errorf("sitemap index nested deeper than %d levels", maxIndexDepth)
+		}
+		if d.before(child.LastMod) {
+			continue
+		}
+		if err := d.walk(strings.TrimSpace(child.Loc), depth+1); err != nil {
+			log.Printf("Failed to read sitemap %s: %v", child.Loc, err)
+		}
+	}
+
+	for _, u := range doc.URLs {
+		if d.full() {
+			break
+		}
+		loc := strings.TrimSpace(u.Loc)
+		if loc == "" || d.seen[loc] || d.before(u.LastMod) {
+			continue
+		}
+		if d.opts.Match != nil && !d.opts.Match(loc) {
+			continue
+		}
+		d.seen[loc] = true
+		lastMod, _ := ParseLastMod(u.LastMod)
+		d.entries = append(d.entries, Entry{Loc: loc, LastMod: lastMod})
+	}
+	return nil
+}
+
+// before reports whether a lastmod value is earlier than opts.Since. Entries
+// without a (parseable) lastmod are kept.
+func (d *discovery) before(lastMod string) bool {
+	if d.opts.Since.IsZero() {
+		return false
+	}
+	t, ok := ParseLastMod(lastMod)
+	return ok && t.Before(d.opts.Since)
+}
+
+// lastModLayouts are the W3C datetime forms allowed in sitemaps
+var lastModLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+}
+
+// ParseLastMod parses a sitemap lastmod value
+func ParseLastMod(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// fetch downloads a robots.txt or sitemap, decompressing gzip content.
+// With a policy, URLs it refuses aren't fetched and the others are paced.
+func (c *Crawler) fetch(u string) ([]byte, error) {
+	if c.Policy != nil {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Policy.Check(parsed); err != nil {
+			return nil, err
+		}
+		c.Policy.Wait(parsed)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", u, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSitemapSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", u, err)
+	}
+
+	// .xml.gz sitemaps are served as plain gzip files, not Content-Encoding
+	if len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %w", u, err)
+		}
+		defer zr.Close()
+		if body, err = io.ReadAll(io.LimitReader(zr, maxSitemapSize)); err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %w", u, err)
+		}
+	}
+	return body, nil
+}
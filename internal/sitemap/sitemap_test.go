@@ -0,0 +1,126 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/crawlpolicy"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDiscover(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "User-agent: *\nDisallow: /cart/\nSitemap: %s/sitemap_index.xml\n", server.URL)
+	})
+	mux.HandleFunc("/sitemap_index.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/items.xml.gz</loc><lastmod>2024-05-02</lastmod></sitemap>
+  <sitemap><loc>%[1]s/old.xml</loc><lastmod>2023-01-01</lastmod></sitemap>
+</sitemapindex>`, server.URL)
+	})
+	mux.HandleFunc("/items.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(gzipped(t, fmt.Sprintf(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>%[1]s/shop/item1/</loc><lastmod>2024-05-02T10:00:00+09:00</lastmod></url>
+  <url><loc>%[1]s/shop/item2/</loc><lastmod>2024-04-01</lastmod></url>
+  <url><loc>%[1]s/shop/item3/</loc></url>
+  <url><loc>%[1]s/help/</loc><lastmod>2024-05-02</lastmod></url>
+</urlset>`, server.URL)))
+	})
+	mux.HandleFunc("/old.xml", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected sitemap older than Since to be skipped")
+	})
+
+	c := NewCrawler(Config{UserAgent: "test-agent"})
+	entries, err := c.Discover([]string{server.URL + "/robots.txt"}, nil, Options{
+		Since: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Match: func(loc string) bool { return strings.Contains(loc, "/shop/") },
+	})
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	// item2 is too old, help doesn't match; item3 has no lastmod and is kept
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	if !strings.HasSuffix(entries[0].Loc, "/shop/item1/") || entries[0].LastMod.IsZero() {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if !strings.HasSuffix(entries[1].Loc, "/shop/item3/") {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+
+	limited, err := c.Discover(nil, []string{server.URL + "/items.xml.gz"}, Options{Limit: 1})
+	if err != nil || len(limited) != 1 {
+		t.Errorf("Expected 1 entry with limit, got %v, %v", limited, err)
+	}
+}
+
+func TestDiscoverFailsWhenNothingReadable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if _, err := NewCrawler(Config{}).Discover(nil, []string{server.URL + "/sitemap.xml"}, Options{}); err == nil {
+		t.Error("Expected error when no sitemap can be read")
+	}
+}
+
+func TestDiscoverHonoursPolicy(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+	})
+	mux.HandleFunc("/private/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected sitemap disallowed by robots.txt not to be fetched")
+	})
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); ua != "test-agent" {
+			t.Errorf("Expected configured user agent, got %q", ua)
+		}
+		fmt.Fprintf(w, `<urlset><url><loc>%s/item/</loc></url></urlset>`, server.URL)
+	})
+
+	policy := crawlpolicy.New(crawlpolicy.Config{UserAgent: "test-agent"})
+	c := NewCrawler(Config{Policy: policy, UserAgent: "test-agent"})
+	entries, err := c.Discover(nil, []string{server.URL + "/private/sitemap.xml", server.URL + "/sitemap.xml"}, Options{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected 1 entry from the allowed sitemap, got %v, %v", entries, err)
+	}
+	if skipped := policy.Skipped(); len(skipped) != 1 || !strings.HasSuffix(skipped[0].URL, "/private/sitemap.xml") {
+		t.Errorf("Expected the disallowed sitemap in the skip report, got %+v", skipped)
+	}
+}
+
+func TestParseLastMod(t *testing.T) {
+	for _, value := range []string{"2024-05-01", "2024-05-01T10:00:00Z", "2024-05-01T10:00+09:00"} {
+		if _, ok := ParseLastMod(value); !ok {
+			t.Errorf("Failed to parse lastmod %q", value)
+		}
+	}
+	if _, ok := ParseLastMod("yesterday"); ok {
+		t.Error("Expected invalid lastmod to fail")
+	}
+}
@@ -0,0 +1,155 @@
+// Package snapshot archives the raw HTML of scraped pages, gzip-compressed
+// and content-addressed, so extraction can be re-run without re-fetching
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/blobstore"
+)
+
+// ErrNotFound is returned for unknown or pruned snapshots
+var ErrNotFound = errors.New("snapshot not found")
+
+// pruneInterval is how often saving snapshots prunes expired ones
+const pruneInterval = time.Hour
+
+// Meta describes an archived page. Identical pages share one snapshot;
+// FirstSeen and LastSeen span the fetches that returned it.
+type Meta struct {
+	ID      string `json:"id"` // SHA-256 of the HTML
+	URL     string `json:"url"`
+	Website string `json:"website"`
+	Size    int    `json:"size"` // uncompressed bytes
+	blobstore.Seen
+}
+
+// Store keeps snapshots under a directory as <id[:2]>/<id>.html.gz with a
+// <id>.json metadata file next to each
+type Store struct {
+	files     *blobstore.Store
+	retention time.Duration // 0 keeps everything
+	pruned    time.Time     // last prune while saving
+	mutex     sync.Mutex    // guards retention and pruned
+	now       func() time.Time
+}
+
+// NewStore creates a snapshot store under dir
+func NewStore(dir string) (*Store, error) {
+	files, err := blobstore.New(dir, "snapshot", ErrNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{files: files, now: time.Now}, nil
+}
+
+// ID returns the content address of html
+func ID(html []byte) string {
+	return blobstore.ID(html)
+}
+
+// SetRetention makes saving snapshots prune those last seen longer than
+// retention ago, at most once per pruneInterval. Zero disables it.
+func (s *Store) SetRetention(retention time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.retention = retention
+}
+
+// Save archives the HTML fetched from url and returns its snapshot ID.
+// With a retention set, expired snapshots are pruned first when due; a
+// failed prune is logged and doesn't fail the save.
+func (s *Store) Save(url, website string, html []byte) (string, error) {
+	id := ID(html)
+	now := s.now()
+
+	s.mutex.Lock()
+	due := s.retention > 0 && now.Sub(s.pruned) >= pruneInterval
+	if due {
+		s.pruned = now
+	}
+	retention := s.retention
+	s.mutex.Unlock()
+
+	if due {
+		if n, err := s.prune(now.Add(-retention)); err != nil {
+			log.Printf("Warning: failed to prune snapshots: %v", err)
+		} else if n > 0 {
+			log.Printf("Pruned %d expired snapshots", n)
+		}
+	}
+
+	meta := &Meta{}
+	err := s.files.Save(id, now, meta, func() error {
+		*meta = Meta{ID: id, URL: url, Website: website, Size: len(html)}
+		return s.writeHTML(id, html)
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Load returns the metadata and HTML of a snapshot
+func (s *Store) Load(id string) (*Meta, []byte, error) {
+	var meta Meta
+	if err := s.files.ReadMeta(id, &meta); err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(s.files.Path(id, ".html.gz"))
+	if err != nil {
+		return nil, nil, ErrNotFound
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decompress snapshot %s: %w", id, err)
+	}
+	defer zr.Close()
+
+	html, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read snapshot %s: %w", id, err)
+	}
+	return &meta, html, nil
+}
+
+// Prune deletes snapshots last seen before now minus retention and returns
+// how many were removed. A zero retention keeps everything.
+func (s *Store) Prune(retention time.Duration) (int, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	return s.prune(s.now().Add(-retention))
+}
+
+// prune deletes snapshots last seen before cutoff
+func (s *Store) prune(cutoff time.Time) (int, error) {
+	return s.files.Prune(cutoff, func() blobstore.Meta { return &Meta{} }, ".html.gz")
+}
+
+func (s *Store) writeHTML(id string, html []byte) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(html); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+
+	if err := os.WriteFile(s.files.Path(id, ".html.gz"), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
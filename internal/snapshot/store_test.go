@@ -0,0 +1,92 @@
+package snapshot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStoreSaveLoadPrune(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	html := []byte("<html><h1 class=\"item-name\">Item</h1></html>")
+	id, err := store.Save("https://item.rakuten.co.jp/shop/item/", "rakuten", html)
+	if err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+	if id != ID(html) {
+		t.Errorf("Expected content address %s, got %s", ID(html), id)
+	}
+
+	// Saving the same page again keeps one snapshot and extends LastSeen
+	now = now.Add(24 * time.Hour)
+	if again, _ := store.Save("https://item.rakuten.co.jp/shop/item/", "rakuten", html); again != id {
+		t.Errorf("Expected same ID for identical HTML, got %s", again)
+	}
+
+	meta, loaded, err := store.Load(id)
+	if err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	if string(loaded) != string(html) {
+		t.Errorf("Unexpected HTML: %q", loaded)
+	}
+	if !meta.LastSeen.After(meta.FirstSeen) || meta.Size != len(html) {
+		t.Errorf("Unexpected metadata: %+v", meta)
+	}
+
+	other, _ := store.Save("https://item.rakuten.co.jp/shop/other/", "rakuten", []byte("<html>other</html>"))
+
+	// Ten days later with a 7-day retention, both are pruned
+	now = now.Add(10 * 24 * time.Hour)
+	if _, err := store.Save("https://item.rakuten.co.jp/shop/other/", "rakuten", []byte("<html>other</html>")); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := store.Prune(7 * 24 * time.Hour)
+	if err != nil || removed != 1 {
+		t.Errorf("Expected 1 snapshot pruned, got %d, %v", removed, err)
+	}
+	if _, _, err := store.Load(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected pruned snapshot to be gone, got %v", err)
+	}
+	if _, _, err := store.Load(other); err != nil {
+		t.Errorf("Expected recent snapshot to be kept, got %v", err)
+	}
+
+	if _, _, err := store.Load("../../etc/passwd"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected invalid ID to be rejected, got %v", err)
+	}
+}
+
+func TestSavePrunesExpiredSnapshots(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	store.SetRetention(7 * 24 * time.Hour)
+
+	old, err := store.Save("https://item.rakuten.co.jp/shop/old/", "rakuten", []byte("<html>old</html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving another page once the first expired prunes it
+	now = now.Add(10 * 24 * time.Hour)
+	current, err := store.Save("https://item.rakuten.co.jp/shop/new/", "rakuten", []byte("<html>new</html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Load(old); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected expired snapshot to be pruned on save, got %v", err)
+	}
+	if _, _, err := store.Load(current); err != nil {
+		t.Errorf("Expected saved snapshot to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// JSONQuarantineStorage stores quarantined products in a JSON file, keyed by
+// product ID. A newer suspicious result replaces the previous one.
+type JSONQuarantineStorage struct {
+	filePath string
+	entries  map[string]*models.QuarantinedProduct
+	mutex    sync.RWMutex
+}
+
+// NewJSONQuarantineStorage creates a new JSON file quarantine storage
+func NewJSONQuarantineStorage(filePath string) (*JSONQuarantineStorage, error) {
+	storage := &JSONQuarantineStorage{
+		filePath: filePath,
+		entries:  make(map[string]*models.QuarantinedProduct),
+	}
+
+	// Load existing data if file exists
+	if _, err := os.Stat(filePath); err == nil {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quarantine storage file: %w", err)
+		}
+
+		var entries []*models.QuarantinedProduct
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse quarantine storage file: %w", err)
+		}
+
+		for _, e := range entries {
+			storage.entries[e.Product.ID] = e
+		}
+	}
+
+	return storage, nil
+}
+
+// Save stores a quarantined product
+func (s *JSONQuarantineStorage) Save(entry *models.QuarantinedProduct) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries[entry.Product.ID] = entry
+	return s.writeToFile()
+}
+
+// Get retrieves a quarantined product by product ID, returning nil if not found
+func (s *JSONQuarantineStorage) Get(id string) (*models.QuarantinedProduct, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.entries[id], nil
+}
+
+// GetAll returns all quarantined products, most recent first
+func (s *JSONQuarantineStorage) GetAll() ([]*models.QuarantinedProduct, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entries := make([]*models.QuarantinedProduct, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].QuarantinedAt.Equal(entries[j].QuarantinedAt) {
+			return entries[i].QuarantinedAt.After(entries[j].QuarantinedAt)
+		}
+		return entries[i].Product.ID < entries[j].Product.ID
+	})
+	return entries, nil
+}
+
+// Delete removes a quarantined product
+func (s *JSONQuarantineStorage) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.entries[id]; !exists {
+		return fmt.Errorf("quarantined product with ID %s not found", id)
+	}
+
+	delete(s.entries, id)
+	return s.writeToFile()
+}
+
+// writeToFile persists the quarantined products to the JSON file
+func (s *JSONQuarantineStorage) writeToFile() error {
+	entries := make([]*models.QuarantinedProduct, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantined products: %w", err)
+	}
+
+	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write to quarantine storage file: %w", err)
+	}
+
+	return nil
+}
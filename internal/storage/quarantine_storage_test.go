@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestJSONQuarantineStorage(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "quarantine.json")
+
+	store, err := NewJSONQuarantineStorage(filePath)
+	if err != nil {
+		t.Fatalf("Failed to create quarantine storage: %v", err)
+	}
+
+	previous := models.NewMoney(1980, "JPY")
+	entry := &models.QuarantinedProduct{
+		Product:       models.NewProduct("item-1", "Item", "https://item.rakuten.co.jp/shop/item-1/", "rakuten", models.NewMoney(19, "JPY")),
+		Issues:        []models.ValidationIssue{{Field: "price", Rule: "price_jump", Message: "price fell from 1980 to 19"}},
+		PreviousPrice: &previous,
+		QuarantinedAt: time.Now(),
+	}
+	if err := store.Save(entry); err != nil {
+		t.Fatalf("Failed to save entry: %v", err)
+	}
+
+	// Reload from disk
+	store, err = NewJSONQuarantineStorage(filePath)
+	if err != nil {
+		t.Fatalf("Failed to reload quarantine storage: %v", err)
+	}
+
+	got, err := store.Get("item-1")
+	if err != nil || got == nil {
+		t.Fatalf("Expected stored entry, got %v, %v", got, err)
+	}
+	if got.Product.CurrentPrice != models.NewMoney(19, "JPY") || *got.PreviousPrice != previous || len(got.Issues) != 1 {
+		t.Errorf("Unexpected entry: %+v", got)
+	}
+
+	if err := store.Delete("item-1"); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+	if all, _ := store.GetAll(); len(all) != 0 {
+		t.Errorf("Expected empty quarantine, got %d entries", len(all))
+	}
+	if err := store.Delete("item-1"); err == nil {
+		t.Error("Expected error deleting a missing entry")
+	}
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+// JSONShopStorage stores shops in a JSON file, keyed by website and shop code
+type JSONShopStorage struct {
+	filePath string
+	shops    map[string]*models.Shop
+	mutex    sync.RWMutex
+}
+
+// NewJSONShopStorage creates a new JSON file shop storage
+func NewJSONShopStorage(filePath string) (*JSONShopStorage, error) {
+	storage := &JSONShopStorage{
+		filePath: filePath,
+		shops:    make(map[string]*models.Shop),
+	}
+
+	// Load existing data if file exists
+	if _, err := os.Stat(filePath); err == nil {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read shop storage file: %w", err)
+		}
+
+		var shops []*models.Shop
+		if err := json.Unmarshal(data, &shops); err != nil {
+			return nil, fmt.Errorf("failed to parse shop storage file: %w", err)
+		}
+
+		for _, s := range shops {
+			storage.shops[shopKey(s.Website, s.Code)] = s
+		}
+	}
+
+	return storage, nil
+}
+
+// Save stores a shop in the storage
+func (s *JSONShopStorage) Save(shop *models.Shop) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.shops[shopKey(shop.Website, shop.Code)] = shop
+	return s.writeToFile()
+}
+
+// Get retrieves a shop by website and code, returning nil if not found
+func (s *JSONShopStorage) Get(website, code string) (*models.Shop, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.shops[shopKey(website, code)], nil
+}
+
+// GetAll returns all shops sorted by website and code
+func (s *JSONShopStorage) GetAll() ([]*models.Shop, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	shops := make([]*models.Shop, 0, len(s.shops))
+	for _, shop := range s.shops {
+		shops = append(shops, shop)
+	}
+	sort.Slice(shops, func(i, j int) bool {
+		return shopKey(shops[i].Website, shops[i].Code) < shopKey(shops[j].Website, shops[j].Code)
+	})
+	return shops, nil
+}
+
+// writeToFile persists the shops to the JSON file
+func (s *JSONShopStorage) writeToFile() error {
+	shops := make([]*models.Shop, 0, len(s.shops))
+	for _, shop := range s.shops {
+		shops = append(shops, shop)
+	}
+
+	data, err := json.MarshalIndent(shops, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal shops: %w", err)
+	}
+
+	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write to shop storage file: %w", err)
+	}
+
+	return nil
+}
+
+// shopKey builds the map key for a shop
+func shopKey(website, code string) string {
+	return website + "/" + code
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/tedjuang/go-scrapy/internal/models"
+)
+
+func TestJSONShopStorage(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "shops.json")
+
+	store, err := NewJSONShopStorage(filePath)
+	if err != nil {
+		t.Fatalf("Failed to create shop storage: %v", err)
+	}
+
+	shop := models.NewShop("book", "楽天ブックス", "https://www.rakuten.co.jp/book/", "rakuten")
+	shop.ProductCount = 3
+	if err := store.Save(shop); err != nil {
+		t.Fatalf("Failed to save shop: %v", err)
+	}
+
+	// Reload from disk
+	store, err = NewJSONShopStorage(filePath)
+	if err != nil {
+		t.Fatalf("Failed to reload shop storage: %v", err)
+	}
+
+	got, err := store.Get("rakuten", "book")
+	if err != nil || got == nil {
+		t.Fatalf("Expected stored shop, got %v, %v", got, err)
+	}
+	if got.Name != shop.Name || got.ProductCount != 3 {
+		t.Errorf("Unexpected shop: %+v", got)
+	}
+
+	if missing, _ := store.Get("rakuten", "missing"); missing != nil {
+		t.Errorf("Expected nil for missing shop, got %+v", missing)
+	}
+
+	all, _ := store.GetAll()
+	if len(all) != 1 {
+		t.Errorf("Expected 1 shop, got %d", len(all))
+	}
+}
@@ -45,6 +45,11 @@ func NewJSONFileStorage(filePath string) (*JSONFileStorage, error) {
 			return nil, fmt.Errorf("failed to read storage file: %w", err)
 		}
 
+		// Files written before prices were encoded as Money
+		data, err = models.UpgradeLegacyJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse storage file: %w", err)
+		}
 		var products []*models.Product
 		if err := json.Unmarshal(data, &products); err != nil {
 			return nil, fmt.Errorf("failed to parse storage file: %w", err)
@@ -31,8 +31,7 @@ func TestJSONFileStorage(t *testing.T) {
 		"Test Product",
 		"https://example.com/product",
 		"rakuten",
-		99.99,
-		"JPY",
+		models.NewMoney(9999, "JPY"),
 	)
 
 	if err := storage.Save(testProduct); err != nil {
@@ -63,8 +62,7 @@ func TestJSONFileStorage(t *testing.T) {
 		"Another Product",
 		"https://example.com/another",
 		"rakuten",
-		199.99,
-		"JPY",
+		models.NewMoney(19999, "JPY"),
 	)
 
 	if err := storage.Save(testProduct2); err != nil {